@@ -81,19 +81,90 @@ func (x *VideoChunk) GetVideoId() string {
 	return ""
 }
 
+type TranscriptSegment struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Start         float64                `protobuf:"fixed64,2,opt,name=start,proto3" json:"start,omitempty"`
+	End           float64                `protobuf:"fixed64,3,opt,name=end,proto3" json:"end,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TranscriptSegment) Reset() {
+	*x = TranscriptSegment{}
+	mi := &file_videoproc_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TranscriptSegment) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TranscriptSegment) ProtoMessage() {}
+
+func (x *TranscriptSegment) ProtoReflect() protoreflect.Message {
+	mi := &file_videoproc_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TranscriptSegment.ProtoReflect.Descriptor instead.
+func (*TranscriptSegment) Descriptor() ([]byte, []int) {
+	return file_videoproc_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TranscriptSegment) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *TranscriptSegment) GetStart() float64 {
+	if x != nil {
+		return x.Start
+	}
+	return 0
+}
+
+func (x *TranscriptSegment) GetEnd() float64 {
+	if x != nil {
+		return x.End
+	}
+	return 0
+}
+
+func (x *TranscriptSegment) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
 type ProcessResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	VideoId       string                 `protobuf:"bytes,1,opt,name=video_id,json=videoId,proto3" json:"video_id,omitempty"`
 	Summary       string                 `protobuf:"bytes,2,opt,name=summary,proto3" json:"summary,omitempty"`
 	Error         string                 `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
 	Status        string                 `protobuf:"bytes,4,opt,name=status,proto3" json:"status,omitempty"`
+	Transcript    string                 `protobuf:"bytes,5,opt,name=transcript,proto3" json:"transcript,omitempty"`
+	Segments      []*TranscriptSegment   `protobuf:"bytes,6,rep,name=segments,proto3" json:"segments,omitempty"`
+	Language      string                 `protobuf:"bytes,7,opt,name=language,proto3" json:"language,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ProcessResponse) Reset() {
 	*x = ProcessResponse{}
-	mi := &file_videoproc_proto_msgTypes[1]
+	mi := &file_videoproc_proto_msgTypes[2]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -105,7 +176,7 @@ func (x *ProcessResponse) String() string {
 func (*ProcessResponse) ProtoMessage() {}
 
 func (x *ProcessResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_videoproc_proto_msgTypes[1]
+	mi := &file_videoproc_proto_msgTypes[2]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -118,7 +189,7 @@ func (x *ProcessResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ProcessResponse.ProtoReflect.Descriptor instead.
 func (*ProcessResponse) Descriptor() ([]byte, []int) {
-	return file_videoproc_proto_rawDescGZIP(), []int{1}
+	return file_videoproc_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *ProcessResponse) GetVideoId() string {
@@ -149,6 +220,27 @@ func (x *ProcessResponse) GetStatus() string {
 	return ""
 }
 
+func (x *ProcessResponse) GetTranscript() string {
+	if x != nil {
+		return x.Transcript
+	}
+	return ""
+}
+
+func (x *ProcessResponse) GetSegments() []*TranscriptSegment {
+	if x != nil {
+		return x.Segments
+	}
+	return nil
+}
+
+func (x *ProcessResponse) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
 var File_videoproc_proto protoreflect.FileDescriptor
 
 const file_videoproc_proto_rawDesc = "" +
@@ -158,12 +250,22 @@ const file_videoproc_proto_rawDesc = "" +
 	"VideoChunk\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
 	"\x04data\x18\x02 \x01(\fR\x04data\x12\x19\n" +
-	"\bvideo_id\x18\x03 \x01(\tR\avideoId\"t\n" +
+	"\bvideo_id\x18\x03 \x01(\tR\avideoId\"_\n" +
+	"\x11TranscriptSegment\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x14\n" +
+	"\x05start\x18\x02 \x01(\x01R\x05start\x12\x10\n" +
+	"\x03end\x18\x03 \x01(\x01R\x03end\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\"\xea\x01\n" +
 	"\x0fProcessResponse\x12\x19\n" +
 	"\bvideo_id\x18\x01 \x01(\tR\avideoId\x12\x18\n" +
 	"\asummary\x18\x02 \x01(\tR\asummary\x12\x14\n" +
 	"\x05error\x18\x03 \x01(\tR\x05error\x12\x16\n" +
-	"\x06status\x18\x04 \x01(\tR\x06status2U\n" +
+	"\x06status\x18\x04 \x01(\tR\x06status\x12\x1e\n" +
+	"\n" +
+	"transcript\x18\x05 \x01(\tR\n" +
+	"transcript\x128\n" +
+	"\bsegments\x18\x06 \x03(\v2\x1c.videoproc.TranscriptSegmentR\bsegments\x12\x1a\n" +
+	"\blanguage\x18\a \x01(\tR\blanguage2U\n" +
 	"\x0eVideoProcessor\x12C\n" +
 	"\fProcessVideo\x12\x15.videoproc.VideoChunk\x1a\x1a.videoproc.ProcessResponse(\x01B\x1bZ\x19proto/videoproc;videoprocb\x06proto3"
 
@@ -179,19 +281,21 @@ func file_videoproc_proto_rawDescGZIP() []byte {
 	return file_videoproc_proto_rawDescData
 }
 
-var file_videoproc_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_videoproc_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
 var file_videoproc_proto_goTypes = []any{
-	(*VideoChunk)(nil),      // 0: videoproc.VideoChunk
-	(*ProcessResponse)(nil), // 1: videoproc.ProcessResponse
+	(*VideoChunk)(nil),        // 0: videoproc.VideoChunk
+	(*TranscriptSegment)(nil), // 1: videoproc.TranscriptSegment
+	(*ProcessResponse)(nil),   // 2: videoproc.ProcessResponse
 }
 var file_videoproc_proto_depIdxs = []int32{
-	0, // 0: videoproc.VideoProcessor.ProcessVideo:input_type -> videoproc.VideoChunk
-	1, // 1: videoproc.VideoProcessor.ProcessVideo:output_type -> videoproc.ProcessResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	1, // 0: videoproc.ProcessResponse.segments:type_name -> videoproc.TranscriptSegment
+	0, // 1: videoproc.VideoProcessor.ProcessVideo:input_type -> videoproc.VideoChunk
+	2, // 2: videoproc.VideoProcessor.ProcessVideo:output_type -> videoproc.ProcessResponse
+	2, // [2:3] is the sub-list for method output_type
+	1, // [1:2] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_videoproc_proto_init() }
@@ -205,7 +309,7 @@ func file_videoproc_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_videoproc_proto_rawDesc), len(file_videoproc_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   3,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/code-zt/vidnotes/config"
 	"github.com/code-zt/vidnotes/internal/handlers"
+	"github.com/code-zt/vidnotes/internal/prompts"
 	"github.com/code-zt/vidnotes/internal/repository"
 	"github.com/code-zt/vidnotes/internal/routes"
 	"github.com/code-zt/vidnotes/internal/services"
@@ -76,14 +80,105 @@ func main() {
 	userRepo := repository.NewUserRepository(mongoClient.DB)
 	videoRepo := repository.NewVideoRepository(mongoClient.DB)
 	sessionRepo := repository.NewAISessionRepository(mongoClient.DB)
+	chunkRepo := repository.NewChunkRepository(mongoClient.DB)
+	collectionRepo := repository.NewCollectionRepository(mongoClient.DB)
+	shareRepo := repository.NewShareRepository(mongoClient.DB)
+	workspaceRepo := repository.NewWorkspaceRepository(mongoClient.DB)
+	summaryVersionRepo := repository.NewSummaryVersionRepository(mongoClient.DB)
+	aiUsageRepo := repository.NewAIUsageRepository(mongoClient.DB)
+	quizRepo := repository.NewQuizRepository(mongoClient.DB)
+	translationRepo := repository.NewTranslationRepository(mongoClient.DB)
+
+	// Создание индексов
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := videoRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create video indexes: %v", err)
+	}
+	if err := sessionRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create session indexes: %v", err)
+	}
+	if err := chunkRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create chunk indexes: %v", err)
+	}
+	if err := collectionRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create collection indexes: %v", err)
+	}
+	if err := shareRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create share indexes: %v", err)
+	}
+	if err := workspaceRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create workspace indexes: %v", err)
+	}
+	if err := summaryVersionRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create summary version indexes: %v", err)
+	}
+	if err := aiUsageRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create AI usage indexes: %v", err)
+	}
+	if err := quizRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create quiz indexes: %v", err)
+	}
+	if err := translationRepo.EnsureIndexes(indexCtx); err != nil {
+		log.Printf("Failed to create translation indexes: %v", err)
+	}
+	cancelIndexes()
 
 	// Инициализация сервисов
-	userService := services.NewUserService(userRepo)
-	videoService := services.NewVideoService(videoRepo, userService, grpcConn)
+	userService := services.NewUserService(userRepo, aiUsageRepo)
+	// Письма: приглашения в воркспейсы приходят со ссылкой на APP_URL
+	smtpPort, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	if err != nil {
+		smtpPort = 587
+	}
+	appURL := os.Getenv("APP_URL")
+	if appURL == "" {
+		appURL = "http://localhost:3000"
+	}
+	emailService := services.NewEmailService(services.SMTPConfig{
+		Host:     os.Getenv("SMTP_HOST"),
+		Port:     smtpPort,
+		Username: os.Getenv("SMTP_USERNAME"),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     os.Getenv("SMTP_FROM"),
+		AppURL:   appURL,
+	})
+	// Удаление персональных данных из запросов к LLM и эмбеддингам; воркспейс может переопределить политику
+	redactor, err := services.NewPIIRedactor(config.GetRedactionConfig(), workspaceRepo)
+	if err != nil {
+		log.Fatal("Failed to load PII redaction dictionaries:", err)
+	}
+
+	workspaceService := services.NewWorkspaceService(workspaceRepo, userRepo, videoRepo, sessionRepo, emailService, redactor)
+
+	// Инициализация эмбеддингов для семантического поиска
+	embeddingProvider, err := services.NewEmbeddingProvider(config.GetEmbeddingConfig())
+	if err != nil {
+		log.Fatal("Failed to init embeddings provider:", err)
+	}
+	embeddingService := services.NewEmbeddingService(redactor.Embeddings(embeddingProvider), chunkRepo, videoRepo)
+
+	// Инициализация AI сервиса: провайдер LLM выбирается конфигом, в том числе по операциям
+	llmConfig := config.GetLLMConfig()
+	llmRouter, err := services.NewLLMRouter(llmConfig)
+	if err != nil {
+		log.Fatal("Failed to init LLM provider:", err)
+	}
+	// Шаблоны промптов: встроенные, поверх них — из PROMPTS_DIR
+	promptRegistry, err := prompts.NewRegistry(llmConfig.PromptsDir)
+	if err != nil {
+		log.Fatal("Failed to load prompt templates:", err)
+	}
+	aiService := services.NewAIService(llmRouter, promptRegistry, userService, redactor)
 
-	// Инициализация AI сервиса
-	openRouterConfig := config.GetOpenRouterConfig()
-	aiService := services.NewOpenRouterService(openRouterConfig)
+	sessionMemoryService := services.NewSessionMemoryService(aiService, sessionRepo)
+	summaryService := services.NewSummaryService(summaryVersionRepo, videoRepo, sessionRepo, aiService, embeddingService, userService, workspaceService)
+	libraryService := services.NewLibraryService(videoRepo, collectionRepo, summaryService, aiService)
+	quizService := services.NewQuizService(quizRepo, summaryService, aiService)
+	insightsService := services.NewInsightsService(videoRepo, summaryService, aiService)
+	translationService := services.NewTranslationService(translationRepo, summaryService, userService, aiService)
+	shareService := services.NewShareService(shareRepo, videoRepo, summaryService)
+	videoService := services.NewVideoService(videoRepo, userService, embeddingService, libraryService, workspaceService, summaryService, quizService, insightsService, translationService, shareService, grpcConn)
+	searchService := services.NewSearchService(videoRepo)
 
 	// Инициализация JWT
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -105,21 +200,43 @@ func main() {
 
 	// Инициализация handlers
 	userHandlers := handlers.NewUserHandlers(userService, jwtManager)
-	videoHandlers := handlers.NewVideoHandlers(videoService)
-	aiHandlers := handlers.NewAIHandlers(aiService, sessionRepo, videoRepo)
+	videoHandlers := handlers.NewVideoHandlers(videoService, libraryService, workspaceService)
+	aiHandlers := handlers.NewAIHandlers(aiService, sessionRepo, videoRepo, workspaceService, sessionMemoryService, embeddingService, libraryService, translationService)
+	searchHandlers := handlers.NewSearchHandlers(searchService, embeddingService, libraryService, workspaceService)
+	libraryHandlers := handlers.NewLibraryHandlers(libraryService, workspaceService)
+	shareHandlers := handlers.NewShareHandlers(shareService)
+	workspaceHandlers := handlers.NewWorkspaceHandlers(workspaceService)
+	summaryHandlers := handlers.NewSummaryHandlers(summaryService)
+	adminHandlers := handlers.NewAdminHandlers(userService, promptRegistry)
+	quizHandlers := handlers.NewQuizHandlers(quizService)
+	insightsHandlers := handlers.NewInsightsHandlers(insightsService)
+	translationHandlers := handlers.NewTranslationHandlers(translationService)
 
 	// Создание Fiber приложения
+	// IP клиента берётся из X-Forwarded-For только от nginx (TRUSTED_PROXIES — адреса
+	// или подсети через запятую); от остальных — адрес соединения
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+
 	app := fiber.New(fiber.Config{
-		BodyLimit: 500 * 1024 * 1024, // 500MB для загрузки видео
-		AppName:   "VidNotes API",
+		BodyLimit:               500 * 1024 * 1024, // 500MB для загрузки видео
+		AppName:                 "VidNotes API",
+		ProxyHeader:             fiber.HeaderXForwardedFor,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxies,
+		EnableIPValidation:      true,
 	})
 
 	// CORS только для разработки
 	if os.Getenv("DOCKER_ENV") != "true" {
 		app.Use(cors.New(cors.Config{
 			AllowOrigins:     "http://localhost:3000,http://127.0.0.1:3000,http://localhost:80",
-			AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Content-Length",
-			AllowMethods:     "GET, POST, PUT, DELETE, OPTIONS",
+			AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Content-Length, X-Share-Password, X-Workspace-ID",
+			AllowMethods:     "GET, POST, PUT, PATCH, DELETE, OPTIONS",
 			AllowCredentials: true,
 		}))
 	}
@@ -142,7 +259,7 @@ func main() {
 	routes.SetupDocs(app)
 
 	// Настройка маршрутов
-	routes.SetupRoutes(app, jwtManager, userHandlers, videoHandlers, aiHandlers)
+	routes.SetupRoutes(app, jwtManager, userHandlers, videoHandlers, aiHandlers, searchHandlers, libraryHandlers, shareHandlers, workspaceHandlers, summaryHandlers, adminHandlers, quizHandlers, insightsHandlers, translationHandlers)
 
 	// Запуск сервера
 	port := os.Getenv("PORT")
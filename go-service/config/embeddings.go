@@ -0,0 +1,24 @@
+// config/embeddings.go
+package config
+
+type EmbeddingConfig struct {
+	Provider   string `json:"provider"` // "openai" (любой OpenAI-совместимый endpoint) или "fake"
+	APIKey     string `json:"api_key"`
+	BaseURL    string `json:"base_url"`
+	Model      string `json:"model"`
+	Dimensions int    `json:"dimensions"`
+	BatchSize  int    `json:"batch_size"`
+	Timeout    int    `json:"timeout"`
+}
+
+func GetEmbeddingConfig() *EmbeddingConfig {
+	return &EmbeddingConfig{
+		Provider:   getEnv("EMBEDDINGS_PROVIDER", "openai"),
+		APIKey:     getEnv("EMBEDDINGS_API_KEY", ""),
+		BaseURL:    getEnv("EMBEDDINGS_BASE_URL", "https://api.openai.com/v1"),
+		Model:      getEnv("EMBEDDINGS_MODEL", "text-embedding-3-small"),
+		Dimensions: getEnvInt("EMBEDDINGS_DIMENSIONS", 256),
+		BatchSize:  getEnvInt("EMBEDDINGS_BATCH_SIZE", 64),
+		Timeout:    getEnvInt("EMBEDDINGS_TIMEOUT", 60),
+	}
+}
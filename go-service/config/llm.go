@@ -0,0 +1,105 @@
+// config/llm.go
+package config
+
+import "strings"
+
+// Операции, для которых можно выбрать отдельного провайдера и модель
+const (
+	LLMOperationChat      = "chat"      // диалог в AI-сессиях
+	LLMOperationSummary   = "summary"   // доработка саммари и саммари по диалогу
+	LLMOperationTags      = "tags"      // подсказки тегов
+	LLMOperationTitle     = "title"     // названия AI-сессий
+	LLMOperationQuiz      = "quiz"      // тесты и карточки по видео
+	LLMOperationInsights  = "insights"  // тезисы, задачи и решения по видео
+	LLMOperationTranslate = "translate" // переводы саммари и транскриптов
+)
+
+var LLMOperations = []string{LLMOperationChat, LLMOperationSummary, LLMOperationTags, LLMOperationTitle, LLMOperationQuiz, LLMOperationInsights, LLMOperationTranslate}
+
+// LLMRoute — провайдер и модель для операции; пустые поля берутся по умолчанию.
+// Fallbacks — запасные модели по порядку: "model" у того же провайдера
+// или "provider:model" у другого (ollama:llama3.1)
+type LLMRoute struct {
+	Provider  string   `json:"provider"`
+	Model     string   `json:"model"`
+	Fallbacks []string `json:"fallbacks"`
+}
+
+// LLMRetryConfig — повторы запроса к модели при 429 и сбоях апстрима
+type LLMRetryConfig struct {
+	MaxRetries  int `json:"max_retries"`   // повторов на каждую модель цепочки
+	BaseDelayMs int `json:"base_delay_ms"` // первая пауза, дальше растёт вдвое, со случайным разбросом
+	MaxDelayMs  int `json:"max_delay_ms"`  // если Retry-After дольше, переходим к следующей модели
+}
+
+type LLMConfig struct {
+	Provider   string              `json:"provider"` // "openrouter", "openai", "ollama" или "fake"
+	Operations map[string]LLMRoute `json:"operations"`
+	OpenRouter *OpenRouterConfig   `json:"openrouter"`
+	OpenAI     *OpenAIConfig       `json:"openai"`
+	Ollama     *OllamaConfig       `json:"ollama"`
+
+	// Запасные модели для операций без собственного списка
+	FallbackModels []string       `json:"fallback_models"`
+	Retry          LLMRetryConfig `json:"retry"`
+
+	ContextWindow  int `json:"context_window"`   // 0 — по таблице известных моделей
+	MaxInputTokens int `json:"max_input_tokens"` // потолок на запрос, чтобы не тратить большие окна целиком
+	ResponseTokens int `json:"response_tokens"`  // резерв окна под ответ
+
+	// Каталог с шаблонами промптов, заменяющими встроенные
+	PromptsDir string `json:"prompts_dir"`
+}
+
+// OpenAIConfig — любой OpenAI-совместимый /chat/completions (OpenAI, vLLM, LM Studio)
+type OpenAIConfig struct {
+	APIKey  string `json:"api_key"`
+	BaseURL string `json:"base_url"`
+	Model   string `json:"model"`
+	Timeout int    `json:"timeout"`
+}
+
+type OllamaConfig struct {
+	BaseURL string `json:"base_url"`
+	Model   string `json:"model"`
+	Timeout int    `json:"timeout"`
+}
+
+func GetLLMConfig() *LLMConfig {
+	operations := make(map[string]LLMRoute, len(LLMOperations))
+	for _, op := range LLMOperations {
+		key := strings.ToUpper(op)
+		operations[op] = LLMRoute{
+			Provider:  getEnv("LLM_"+key+"_PROVIDER", ""),
+			Model:     getEnv("LLM_"+key+"_MODEL", ""),
+			Fallbacks: getEnvList("LLM_" + key + "_FALLBACK_MODELS"),
+		}
+	}
+
+	return &LLMConfig{
+		Provider:   getEnv("LLM_PROVIDER", "openrouter"),
+		Operations: operations,
+		OpenRouter: GetOpenRouterConfig(),
+		OpenAI: &OpenAIConfig{
+			APIKey:  getEnv("OPENAI_API_KEY", ""),
+			BaseURL: getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+			Model:   getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+			Timeout: getEnvInt("OPENAI_TIMEOUT", 120),
+		},
+		Ollama: &OllamaConfig{
+			BaseURL: getEnv("OLLAMA_BASE_URL", "http://localhost:11434"),
+			Model:   getEnv("OLLAMA_MODEL", "llama3.1"),
+			Timeout: getEnvInt("OLLAMA_TIMEOUT", 300),
+		},
+		FallbackModels: getEnvList("LLM_FALLBACK_MODELS"),
+		Retry: LLMRetryConfig{
+			MaxRetries:  getEnvInt("LLM_MAX_RETRIES", 2),
+			BaseDelayMs: getEnvInt("LLM_RETRY_BASE_DELAY_MS", 500),
+			MaxDelayMs:  getEnvInt("LLM_RETRY_MAX_DELAY_MS", 10000),
+		},
+		ContextWindow:  getEnvInt("LLM_CONTEXT_WINDOW", 0),
+		MaxInputTokens: getEnvInt("LLM_MAX_INPUT_TOKENS", 16000),
+		ResponseTokens: getEnvInt("LLM_RESPONSE_TOKENS", 1024),
+		PromptsDir:     getEnv("PROMPTS_DIR", ""),
+	}
+}
@@ -0,0 +1,21 @@
+// config/redaction.go
+package config
+
+// RedactionConfig — удаление персональных данных из текста перед отправкой в LLM
+type RedactionConfig struct {
+	// Политика по умолчанию: для личных видео и воркспейсов без своей настройки
+	Enabled bool `json:"enabled"`
+	// Файлы словарей: термин на строку, # — комментарий; категория плейсхолдера —
+	// имя файла без расширения (names.txt → [NAMES_1])
+	Dictionaries []string `json:"dictionaries"`
+	// Дополнительные термины из окружения, категория TERM
+	Terms []string `json:"terms"`
+}
+
+func GetRedactionConfig() *RedactionConfig {
+	return &RedactionConfig{
+		Enabled:      getEnvBool("PII_REDACTION_ENABLED", true),
+		Dictionaries: getEnvList("PII_REDACTION_DICTIONARIES"),
+		Terms:        getEnvList("PII_REDACTION_TERMS"),
+	}
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type AdminHandlers struct {
+	userService services.UserService
+	prompts     *prompts.Registry
+}
+
+func NewAdminHandlers(userService services.UserService, registry *prompts.Registry) *AdminHandlers {
+	return &AdminHandlers{
+		userService: userService,
+		prompts:     registry,
+	}
+}
+
+// RequireAdmin пропускает дальше только пользователей с ролью admin;
+// роль читается из базы, чтобы её снятие действовало сразу
+func (h *AdminHandlers) RequireAdmin(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	user, err := h.userService.GetProfile(c.Context(), userObjectID)
+	if err != nil || user.Role != models.UserRoleAdmin {
+		return utils.Error(c, fiber.StatusForbidden, "Admin access required")
+	}
+
+	return c.Next()
+}
+
+// ListPrompts возвращает активные версии шаблонов промптов
+func (h *AdminHandlers) ListPrompts(c *fiber.Ctx) error {
+	return utils.Success(c, fiber.StatusOK, h.prompts.Active())
+}
@@ -1,9 +1,18 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
 	"github.com/code-zt/vidnotes/internal/repository"
 	"github.com/code-zt/vidnotes/internal/services"
 	"github.com/code-zt/vidnotes/pkg/utils"
@@ -12,22 +21,36 @@ import (
 )
 
 type AIHandlers struct {
-	aiService   services.AIService
-	sessionRepo repository.AISessionRepository
-	videoRepo   repository.VideoRepository
+	aiService          services.AIService
+	sessionRepo        repository.AISessionRepository
+	videoRepo          repository.VideoRepository
+	workspaceService   services.WorkspaceService
+	sessionMemory      services.SessionMemoryService
+	embeddingService   services.EmbeddingService
+	libraryService     services.LibraryService
+	translationService services.TranslationService
 }
 
-func NewAIHandlers(aiService services.AIService, sessionRepo repository.AISessionRepository, videoRepo repository.VideoRepository) *AIHandlers {
+func NewAIHandlers(aiService services.AIService, sessionRepo repository.AISessionRepository, videoRepo repository.VideoRepository, workspaceService services.WorkspaceService, sessionMemory services.SessionMemoryService, embeddingService services.EmbeddingService, libraryService services.LibraryService, translationService services.TranslationService) *AIHandlers {
 	return &AIHandlers{
-		aiService:   aiService,
-		sessionRepo: sessionRepo,
-		videoRepo:   videoRepo,
+		aiService:          aiService,
+		sessionRepo:        sessionRepo,
+		videoRepo:          videoRepo,
+		workspaceService:   workspaceService,
+		sessionMemory:      sessionMemory,
+		embeddingService:   embeddingService,
+		libraryService:     libraryService,
+		translationService: translationService,
 	}
 }
 
+// CreateSessionRequest задаёт видео сессии одним из способов: video_id,
+// список video_ids или collection_id (с вложенными коллекциями)
 type CreateSessionRequest struct {
-	VideoID string `json:"video_id"`
-	Title   string `json:"title"`
+	VideoID      string   `json:"video_id"`
+	VideoIDs     []string `json:"video_ids"`
+	CollectionID string   `json:"collection_id"`
+	Title        string   `json:"title"`
 }
 
 func (h *AIHandlers) CreateSession(c *fiber.Ctx) error {
@@ -42,29 +65,40 @@ func (h *AIHandlers) CreateSession(c *fiber.Ctx) error {
 		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
 	}
 
-	videoID, err := primitive.ObjectIDFromHex(req.VideoID)
+	videoIDs, collectionID, err := h.sessionVideoIDs(c.Context(), userObjectID, req)
 	if err != nil {
-		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID format")
+		return sessionError(c, err, "Failed to create session")
 	}
 
-	video, err := h.videoRepo.GetByID(c.Context(), videoID)
-	if err != nil {
-		return utils.Error(c, fiber.StatusNotFound, "Video not found")
-	}
+	videos := make([]*models.Video, 0, len(videoIDs))
+	for _, videoID := range videoIDs {
+		video, err := h.videoRepo.GetByID(c.Context(), videoID)
+		if err != nil {
+			return utils.Error(c, fiber.StatusNotFound, "Video not found")
+		}
 
-	if video.UserID != userObjectID {
-		return utils.Error(c, fiber.StatusForbidden, "Access denied")
-	}
-	processedSummary, err := h.aiService.ImproveSummary(c.Context(), video.Summary, []string{"Delete all non-essential content: irrelevant text, personal info, or details that don’t support the main context. Keep only key facts, direct context, and critical info. Return filtered content concisely."})
-	if err != nil {
-		processedSummary = video.Summary
+		// По видео воркспейса сессии создают его текущие участники, включая загрузившего видео
+		if video.WorkspaceID != nil {
+			if _, err := h.workspaceService.Authorize(c.Context(), *video.WorkspaceID, userObjectID, models.WorkspaceRoleViewer); err != nil {
+				return utils.Error(c, fiber.StatusForbidden, "Access denied")
+			}
+		} else if video.UserID != userObjectID {
+			return utils.Error(c, fiber.StatusForbidden, "Access denied")
+		}
+
+		// Сессия видна участникам воркспейса, поэтому смешивать видео разных пространств нельзя
+		if len(videos) > 0 && !sameWorkspace(videos[0].WorkspaceID, video.WorkspaceID) {
+			return sessionError(c, models.ErrMixedSessionVideos, "Failed to create session")
+		}
+		videos = append(videos, video)
 	}
 
 	session := &models.AISession{
-		UserID:  userObjectID,
-		VideoID: videoID,
-		Title:   req.Title,
-		Summary: processedSummary,
+		UserID:       userObjectID,
+		VideoID:      videos[0].ID,
+		WorkspaceID:  videos[0].WorkspaceID,
+		CollectionID: collectionID,
+		Title:        req.Title,
 		Messages: []models.AIMessage{
 			{
 				Role:    "system",
@@ -74,24 +108,138 @@ func (h *AIHandlers) CreateSession(c *fiber.Ctx) error {
 		},
 	}
 
+	if len(videos) == 1 {
+		video := videos[0]
+		ctx := services.WithAIUsageScope(prompts.WithLocale(c.Context(), video.Language), userObjectID, nil)
+		ctx = services.WithAIWorkspace(ctx, video.WorkspaceID)
+		processedSummary, err := h.aiService.CondenseSummary(ctx, video.Summary)
+		if err != nil {
+			processedSummary = video.Summary
+		}
+		session.Summary = processedSummary
+	} else {
+		// Саммари нескольких видео берутся из самих видео при каждом запросе
+		session.VideoIDs = videoIDs
+	}
+
 	sessionID, err := h.sessionRepo.Create(c.Context(), session)
 	if err != nil {
 		return utils.Error(c, fiber.StatusInternalServerError, err.Error())
 	}
 
 	session.ID = sessionID
-	return utils.Success(c, fiber.StatusCreated, session)
+	return utils.Success(c, fiber.StatusCreated, session.View())
+}
+
+// sessionVideoIDs разбирает способ выбора видео сессии; коллекция раскрывается
+// в видео её и вложенных коллекций
+func (h *AIHandlers) sessionVideoIDs(ctx context.Context, userID primitive.ObjectID, req CreateSessionRequest) ([]primitive.ObjectID, *primitive.ObjectID, error) {
+	modes := 0
+	for _, set := range []bool{req.VideoID != "", len(req.VideoIDs) > 0, req.CollectionID != ""} {
+		if set {
+			modes++
+		}
+	}
+	if modes != 1 {
+		return nil, nil, models.ErrInvalidSessionVideos
+	}
+
+	var videoIDs []primitive.ObjectID
+	var collectionID *primitive.ObjectID
+	switch {
+	case req.VideoID != "":
+		videoID, err := primitive.ObjectIDFromHex(req.VideoID)
+		if err != nil {
+			return nil, nil, errInvalidVideoIDs
+		}
+		videoIDs = []primitive.ObjectID{videoID}
+
+	case len(req.VideoIDs) > 0:
+		ids, err := parseObjectIDs(req.VideoIDs)
+		if err != nil {
+			return nil, nil, err
+		}
+		seen := make(map[primitive.ObjectID]bool, len(ids))
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				videoIDs = append(videoIDs, id)
+			}
+		}
+
+	default:
+		id, err := primitive.ObjectIDFromHex(req.CollectionID)
+		if err != nil {
+			return nil, nil, models.ErrCollectionNotFound
+		}
+		collectionIDs, err := h.libraryService.ResolveCollection(ctx, userID, id, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		videoIDs, err = h.videoRepo.ListIDs(ctx, userID, models.VideoFilter{CollectionIDs: collectionIDs})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(videoIDs) == 0 {
+			return nil, nil, models.ErrInvalidSessionVideos
+		}
+		collectionID = &id
+	}
+
+	if len(videoIDs) > models.MaxSessionVideos {
+		return nil, nil, models.ErrTooManySessionVideos
+	}
+	return videoIDs, collectionID, nil
+}
+
+func sameWorkspace(a, b *primitive.ObjectID) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// sessionError сопоставляет ошибки выбора видео и сообщений сессии с HTTP-статусами
+func sessionError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrCollectionNotFound),
+		errors.Is(err, models.ErrMessageNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, errInvalidVideoIDs),
+		errors.Is(err, models.ErrInvalidSessionVideos),
+		errors.Is(err, models.ErrTooManySessionVideos),
+		errors.Is(err, models.ErrMixedSessionVideos),
+		errors.Is(err, models.ErrMessageNotEditable),
+		errors.Is(err, models.ErrMessageNotRegenerable),
+		errors.Is(err, models.ErrInvalidSessionTitle),
+		errors.Is(err, models.ErrInvalidExportFormat):
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
+
+// messageError сопоставляет ошибки проверки сообщения перед запросом к модели
+func messageError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, models.ErrContextTooLong):
+		return utils.Error(c, fiber.StatusRequestEntityTooLarge, "Message is too long for the model context")
+	case errors.Is(err, models.ErrMonthlyAITokensExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, "Failed to check message")
 }
 
+const aiErrorReply = "Извините, произошла ошибка при обработке запроса. Пожалуйста, попробуйте позже."
+
 type SendMessageRequest struct {
 	Message string `json:"message"`
 }
 
 func (h *AIHandlers) SendMessage(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
-	sessionID, err := h.getValidSessionID(c)
-	if err != nil {
-		return err
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
 	}
 
 	var req SendMessageRequest
@@ -103,64 +251,454 @@ func (h *AIHandlers) SendMessage(c *fiber.Ctx) error {
 		return utils.Error(c, fiber.StatusBadRequest, "Message cannot be empty")
 	}
 
-	session, err := h.validateSessionAccess(c, sessionID, userID)
-	if err != nil {
-		return err
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
 	}
 
 	userMessage := models.AIMessage{
-		Role:    "user",
-		Content: req.Message,
-		Time:    time.Now(),
+		ParentID: session.ActiveLeafID,
+		Role:     "user",
+		Content:  req.Message,
 	}
+	return h.answer(c, session, userID, &userMessage, true)
+}
 
-	if err := h.sessionRepo.AddMessage(c.Context(), sessionID, userMessage); err != nil {
-		return utils.Error(c, fiber.StatusInternalServerError, "Failed to save user message")
+// EditMessage задаёт вопрос заново с другим текстом: правка становится новой
+// веткой рядом с исходным сообщением, ответ на неё — концом активной ветки
+func (h *AIHandlers) EditMessage(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	var req SendMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Message == "" {
+		return utils.Error(c, fiber.StatusBadRequest, "Message cannot be empty")
+	}
+
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
+	}
+
+	original, ok := sessionMessage(c, session)
+	if !ok {
+		return nil
+	}
+	if original.Role != "user" {
+		return sessionError(c, models.ErrMessageNotEditable, "Failed to edit message")
 	}
 
-	aiResponse, err := h.aiService.SendMessage(c.Context(), session, req.Message)
+	userMessage := models.AIMessage{
+		ParentID: original.ParentID,
+		Role:     "user",
+		Content:  req.Message,
+	}
+	return h.answer(c, session, userID, &userMessage, true)
+}
+
+// RegenerateMessage отвечает на тот же вопрос заново; прежний ответ остаётся альтернативой
+func (h *AIHandlers) RegenerateMessage(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
+	}
+
+	reply, ok := sessionMessage(c, session)
+	if !ok {
+		return nil
+	}
+	if reply.Role != "assistant" || reply.ParentID == nil {
+		return sessionError(c, models.ErrMessageNotRegenerable, "Failed to regenerate message")
+	}
+	question, ok := session.Message(*reply.ParentID)
+	if !ok || question.Role != "user" {
+		return sessionError(c, models.ErrMessageNotRegenerable, "Failed to regenerate message")
+	}
+
+	return h.answer(c, session, userID, question, false)
+}
+
+// ActivateMessage делает активной ветку с сообщением; она продолжается
+// по самым новым ответам до конца
+func (h *AIHandlers) ActivateMessage(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
+	}
+
+	message, ok := sessionMessage(c, session)
+	if !ok {
+		return nil
+	}
+
+	leafID := session.LatestLeaf(message.ID)
+	if err := h.sessionRepo.SetActiveLeaf(c.Context(), sessionID, leafID); err != nil {
+		return sessionError(c, err, "Failed to switch branch")
+	}
+	session.ActiveLeafID = &leafID
+
+	return utils.Success(c, fiber.StatusOK, session.View())
+}
+
+// sessionMessage находит сообщение сессии из параметра :messageId. Если сообщения
+// нет, ответ с ошибкой уже записан и ok == false.
+func sessionMessage(c *fiber.Ctx, session *models.AISession) (*models.AIMessage, bool) {
+	messageID, err := primitive.ObjectIDFromHex(c.Params("messageId"))
 	if err != nil {
-		errorMessage := models.AIMessage{
-			Role:    "assistant",
-			Content: "Извините, произошла ошибка при обработке запроса. Пожалуйста, попробуйте позже.",
-			Time:    time.Now(),
+		utils.Error(c, fiber.StatusBadRequest, "Invalid message ID")
+		return nil, false
+	}
+	message, ok := session.Message(messageID)
+	if !ok {
+		sessionError(c, models.ErrMessageNotFound, "Message not found")
+		return nil, false
+	}
+	return message, true
+}
+
+// answer получает ответ модели на вопрос и сохраняет его в ветке после вопроса.
+// Новый вопрос (save) сохраняется перед запросом к модели; в историю идёт ветка
+// до вопроса, даже если активна другая.
+func (h *AIHandlers) answer(c *fiber.Ctx, session *models.AISession, userID string, question *models.AIMessage, save bool) error {
+	sessionID := session.ID
+
+	// Расход запроса записывается на отправителя сообщения; ID проверен в validateSessionAccess
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	if err := h.aiService.CheckMessage(services.WithAIUsageScope(c.Context(), userObjectID, &sessionID), question.Content); err != nil {
+		return messageError(c, err)
+	}
+	retrieved := h.retrieveContext(c.Context(), session, question.Content)
+
+	if save {
+		if err := h.sessionRepo.AddMessage(c.Context(), sessionID, question); err != nil {
+			return utils.Error(c, fiber.StatusInternalServerError, "Failed to save user message")
 		}
-		h.sessionRepo.AddMessage(c.Context(), sessionID, errorMessage)
-		return utils.Error(c, fiber.StatusInternalServerError, "AI service error: "+err.Error())
 	}
 
-	aiMessage := models.AIMessage{
-		Role:    "assistant",
-		Content: aiResponse,
-		Time:    time.Now(),
+	history := *session
+	history.Messages = session.BranchTo(question.ParentID)
+	history.ActiveLeafID = nil
+
+	ctx := services.WithAIUsageScope(prompts.WithLocale(c.Context(), retrieved.locale), userObjectID, &sessionID)
+	ctx = services.WithAIWorkspace(ctx, session.WorkspaceID)
+	aiResponse, err := h.aiService.SendMessage(ctx, &history, retrieved.sources, question.Content)
+	if err != nil {
+		errorMessage := models.AIMessage{
+			ParentID: &question.ID,
+			Role:     "assistant",
+			Content:  aiErrorReply,
+		}
+		h.sessionRepo.AddMessage(c.Context(), sessionID, &errorMessage)
+		if errors.Is(err, models.ErrMonthlyAITokensExceeded) {
+			return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "AI service error: "+err.Error())
 	}
 
-	if err := h.sessionRepo.AddMessage(c.Context(), sessionID, aiMessage); err != nil {
+	aiMessage := retrieved.assistantMessage(question.ID, aiResponse)
+	if err := h.sessionRepo.AddMessage(c.Context(), sessionID, &aiMessage); err != nil {
 		return utils.Error(c, fiber.StatusInternalServerError, "Failed to save AI response")
 	}
+	h.afterAnswer(session, userObjectID, retrieved.locale, question.Content, aiMessage.Content)
 
 	response := models.AIResponse{
-		Message:   aiResponse,
+		MessageID: aiMessage.ID.Hex(),
+		Message:   aiMessage.Content,
 		SessionID: sessionID.Hex(),
 		Time:      aiMessage.Time,
+		Citations: aiMessage.Citations,
+		Model:     aiMessage.Model,
 	}
 
 	return utils.Success(c, fiber.StatusOK, response)
 }
 
-func (h *AIHandlers) GetSession(c *fiber.Ctx) error {
+// StreamMessage отвечает потоком SSE: события delta с фрагментами ответа,
+// затем done с итоговым сообщением или error. При отключении клиента
+// запрос к модели отменяется, а полученная часть ответа сохраняется и
+// обрабатывается как обычный ответ.
+func (h *AIHandlers) StreamMessage(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
-	sessionID, err := h.getValidSessionID(c)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	var req SendMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Message == "" {
+		return utils.Error(c, fiber.StatusBadRequest, "Message cannot be empty")
+	}
+
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
+	}
+
+	// Расход запроса записывается на отправителя сообщения; ID проверен в validateSessionAccess
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	if err := h.aiService.CheckMessage(services.WithAIUsageScope(c.Context(), userObjectID, &sessionID), req.Message); err != nil {
+		return messageError(c, err)
+	}
+	retrieved := h.retrieveContext(c.Context(), session, req.Message)
+
+	userMessage := models.AIMessage{
+		ParentID: session.ActiveLeafID,
+		Role:     "user",
+		Content:  req.Message,
+	}
+
+	if err := h.sessionRepo.AddMessage(c.Context(), sessionID, &userMessage); err != nil {
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to save user message")
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	// Отключаем буферизацию ответа в nginx
+	c.Set("X-Accel-Buffering", "no")
+
+	message := req.Message
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		// Writer вызывается после выхода из обработчика, контекст fiber уже недоступен
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = services.WithAIUsageScope(prompts.WithLocale(ctx, retrieved.locale), userObjectID, &sessionID)
+		ctx = services.WithAIWorkspace(ctx, session.WorkspaceID)
+
+		disconnected := false
+		aiResponse, err := h.aiService.StreamMessage(ctx, session, retrieved.sources, message, func(delta string) error {
+			if err := writeSSE(w, "delta", fiber.Map{"content": delta}); err != nil {
+				disconnected = true
+				return err
+			}
+			return nil
+		})
+
+		saveCtx, saveCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer saveCancel()
+
+		if disconnected {
+			if aiResponse.Content != "" {
+				partial := retrieved.assistantMessage(userMessage.ID, aiResponse)
+				if err := h.sessionRepo.AddMessage(saveCtx, sessionID, &partial); err != nil {
+					fmt.Printf("Failed to save partial AI response for session %s: %v\n", sessionID.Hex(), err)
+					return
+				}
+				h.afterAnswer(session, userObjectID, retrieved.locale, message, partial.Content)
+			}
+			return
+		}
+
+		if err != nil {
+			fmt.Printf("AI stream for session %s failed: %v\n", sessionID.Hex(), err)
+			h.sessionRepo.AddMessage(saveCtx, sessionID, &models.AIMessage{
+				ParentID: &userMessage.ID,
+				Role:     "assistant",
+				Content:  aiErrorReply,
+			})
+			writeSSE(w, "error", fiber.Map{"message": "AI service error: " + err.Error()})
+			return
+		}
+
+		aiMessage := retrieved.assistantMessage(userMessage.ID, aiResponse)
+		if err := h.sessionRepo.AddMessage(saveCtx, sessionID, &aiMessage); err != nil {
+			writeSSE(w, "error", fiber.Map{"message": "Failed to save AI response"})
+			return
+		}
+		h.afterAnswer(session, userObjectID, retrieved.locale, message, aiMessage.Content)
+
+		// Итоговый текст может отличаться от суммы delta: неверные ссылки из него удалены
+		writeSSE(w, "done", models.AIResponse{
+			MessageID: aiMessage.ID.Hex(),
+			Message:   aiMessage.Content,
+			SessionID: sessionID.Hex(),
+			Time:      aiMessage.Time,
+			Citations: aiMessage.Citations,
+			Model:     aiMessage.Model,
+		})
+	})
+
+	return nil
+}
+
+// afterAnswer запускает фоновые шаги после сохранения ответа, в том числе
+// частичного при отключении клиента: память сессии и название новой сессии
+func (h *AIHandlers) afterAnswer(session *models.AISession, userID primitive.ObjectID, locale, question, answer string) {
+	go h.refreshMemory(session.ID, session.WorkspaceID, userID)
+	if session.Title == "" {
+		go h.autoTitle(session.ID, session.WorkspaceID, userID, locale, question, answer)
+	}
+}
+
+// refreshMemory в фоне сворачивает ранние реплики длинной сессии в память;
+// расход идёт на пользователя, чьё сообщение удлинило сессию
+func (h *AIHandlers) refreshMemory(sessionID primitive.ObjectID, workspaceID *primitive.ObjectID, userID primitive.ObjectID) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	ctx = services.WithAIWorkspace(services.WithAIUsageScope(ctx, userID, &sessionID), workspaceID)
+
+	if err := h.sessionMemory.Refresh(ctx, sessionID); err != nil {
+		fmt.Printf("Failed to refresh memory for session %s: %v\n", sessionID.Hex(), err)
+	}
+}
+
+// autoTitle в фоне называет сессию без названия по вопросу и ответу;
+// если пользователь успел задать название сам, оно не меняется
+func (h *AIHandlers) autoTitle(sessionID primitive.ObjectID, workspaceID *primitive.ObjectID, userID primitive.ObjectID, locale, question, answer string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	ctx = services.WithAIWorkspace(services.WithAIUsageScope(prompts.WithLocale(ctx, locale), userID, &sessionID), workspaceID)
+
+	title, err := h.aiService.GenerateTitle(ctx, question, answer)
 	if err != nil {
-		return err
+		fmt.Printf("Failed to generate title for session %s: %v\n", sessionID.Hex(), err)
+		return
+	}
+	if title == "" {
+		return
+	}
+
+	if err := h.sessionRepo.SetTitleIfEmpty(ctx, sessionID, title); err != nil {
+		fmt.Printf("Failed to save title for session %s: %v\n", sessionID.Hex(), err)
+	}
+}
+
+// Сколько фрагментов транскрипта подбирается под вопрос; в сессиях по нескольким
+// видео они делятся между видео, но не меньше minChunksPerVideo на каждое
+const (
+	retrievedChunks   = 6
+	minChunksPerVideo = 2
+)
+
+// retrieval — контекст для ответа: материалы видео сессии, ID найденных чанков
+// и сегменты, по которым проверяются ссылки ответа
+type retrieval struct {
+	// Язык первого видео — по нему выбираются шаблоны промптов
+	locale   string
+	sources  []services.ChatSource
+	chunkIDs []primitive.ObjectID
+	videos   []services.CitedVideo
+}
+
+// retrieveContext подбирает под вопрос релевантные фрагменты транскрипта каждого
+// видео сессии с номерами сегментов и таймкодами. Если поиск не дал результатов,
+// в контекст уходит весь транскрипт — его обрежет бюджет токенов. В сессиях по
+// нескольким видео материалы помечаются V1, V2… по порядку видео в сессии.
+func (h *AIHandlers) retrieveContext(ctx context.Context, session *models.AISession, question string) retrieval {
+	videoIDs := session.Videos()
+	multi := len(videoIDs) > 1
+	limit := retrievedChunks
+	if multi {
+		limit = max(minChunksPerVideo, retrievedChunks/len(videoIDs))
+	}
+
+	var r retrieval
+	for i, videoID := range videoIDs {
+		video, err := h.videoRepo.GetByID(ctx, videoID)
+		if err != nil {
+			// Удалённое видео из сессии по нескольким видео просто пропускается
+			if !multi {
+				r.sources = append(r.sources, services.ChatSource{Summary: session.Summary})
+			}
+			continue
+		}
+
+		if r.locale == "" {
+			r.locale = video.Language
+		}
+
+		source := services.ChatSource{Title: video.Title, Summary: session.Summary}
+		if multi {
+			source.Label = fmt.Sprintf("V%d", i+1)
+			source.Summary = video.Summary
+		}
+
+		if video.Transcript != "" {
+			chunks, err := h.embeddingService.RetrieveChunks(ctx, video, question, limit)
+			if err != nil {
+				fmt.Printf("Failed to retrieve chunks for session %s: %v\n", session.ID.Hex(), err)
+			}
+			if len(chunks) == 0 {
+				source.Excerpts = []string{services.TranscriptExcerpt(video, source.Label)}
+			} else {
+				source.Excerpts = services.CitableExcerpts(video, source.Label, chunks)
+				for _, chunk := range chunks {
+					if !chunk.ID.IsZero() {
+						r.chunkIDs = append(r.chunkIDs, chunk.ID)
+					}
+				}
+			}
+		}
+
+		r.sources = append(r.sources, source)
+		r.videos = append(r.videos, services.CitedVideo{ID: video.ID, Label: source.Label, Segments: video.Segments})
+	}
+	return r
+}
+
+// assistantMessage собирает ответ ассистента на вопрос questionID со ссылками,
+// сверенными с транскриптом
+func (r retrieval) assistantMessage(questionID primitive.ObjectID, reply services.ChatResult) models.AIMessage {
+	content, citations := services.ResolveCitations(reply.Content, r.videos)
+	return models.AIMessage{
+		ParentID:  &questionID,
+		Role:      "assistant",
+		Content:   content,
+		Time:      time.Now(),
+		ChunkIDs:  r.chunkIDs,
+		Citations: citations,
+		Model:     reply.Model,
 	}
+}
 
-	session, err := h.validateSessionAccess(c, sessionID, userID)
+// writeSSE отправляет событие и сразу сбрасывает буфер; ошибка означает, что клиент отключился
+func writeSSE(w *bufio.Writer, event string, data interface{}) error {
+	payload, err := json.Marshal(data)
 	if err != nil {
 		return err
 	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+func (h *AIHandlers) GetSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
 
-	return utils.Success(c, fiber.StatusOK, session)
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleViewer)
+	if !ok {
+		return nil
+	}
+
+	return utils.Success(c, fiber.StatusOK, session.View())
 }
 
 func (h *AIHandlers) GetUserSessions(c *fiber.Ctx) error {
@@ -170,24 +708,182 @@ func (h *AIHandlers) GetUserSessions(c *fiber.Ctx) error {
 		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	sessions, err := h.sessionRepo.GetByUserID(c.Context(), userObjectID)
+	page, err := parsePageOptions(c)
 	if err != nil {
-		return utils.Error(c, fiber.StatusInternalServerError, "Failed to get sessions")
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get sessions")
+	}
+
+	filter := models.SessionFilter{From: from, To: to, WorkspaceID: workspaceID}
+	if v := c.Query("video_id"); v != "" {
+		if filter.VideoID, err = primitive.ObjectIDFromHex(v); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID format")
+		}
+	}
+
+	// Архивные сессии по умолчанию скрыты; archived=all показывает все
+	archived := false
+	filter.Archived = &archived
+	switch v := c.Query("archived"); v {
+	case "":
+	case "all":
+		filter.Archived = nil
+	default:
+		if archived, err = strconv.ParseBool(v); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid archived filter")
+		}
+	}
+	if v := c.Query("pinned"); v != "" {
+		pinned, err := strconv.ParseBool(v)
+		if err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid pinned filter")
+		}
+		filter.Pinned = &pinned
+	}
+
+	sessions, err := h.sessionRepo.List(c.Context(), userObjectID, filter, page)
+	if err != nil {
+		return pageError(c, err, "Failed to get sessions")
 	}
 
 	return utils.Success(c, fiber.StatusOK, sessions)
 }
 
-func (h *AIHandlers) DeleteSession(c *fiber.Ctx) error {
+// UpdateSession меняет название, закрепление и архивацию сессии
+func (h *AIHandlers) UpdateSession(c *fiber.Ctx) error {
 	userID := c.Locals("userID").(string)
-	sessionID, err := h.getValidSessionID(c)
-	if err != nil {
-		return err
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	var req models.UpdateSessionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" || utf8.RuneCountInString(title) > models.MaxSessionTitleLength {
+			return sessionError(c, models.ErrInvalidSessionTitle, "Failed to update session")
+		}
+		req.Title = &title
+	}
+
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
+	}
+
+	if err := h.sessionRepo.Update(c.Context(), sessionID, req); err != nil {
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to update session")
+	}
+
+	if req.Title != nil {
+		session.Title = *req.Title
+	}
+	if req.Pinned != nil {
+		session.Pinned = *req.Pinned
+	}
+	if req.Archived != nil {
+		session.Archived = *req.Archived
 	}
 
-	_, err = h.validateSessionAccess(c, sessionID, userID)
+	return utils.Success(c, fiber.StatusOK, session.View())
+}
+
+// ExportSession отдаёт стенограмму активной ветки файлом в формате md, json или html
+func (h *AIHandlers) ExportSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	format := c.Query("format", services.ExportFormatMarkdown)
+	switch format {
+	case services.ExportFormatMarkdown, services.ExportFormatJSON, services.ExportFormatHTML:
+	default:
+		return sessionError(c, models.ErrInvalidExportFormat, "Failed to export session")
+	}
+	language := c.Query("lang")
+	if language != "" {
+		var err error
+		if language, err = services.NormalizeLanguage(language); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleViewer)
+	if !ok {
+		return nil
+	}
+
+	// Удалённые видео остаются nil, чтобы метки V1, V2… совпадали со ссылками в ответах
+	videoIDs := session.Videos()
+	videos := make([]*models.Video, len(videoIDs))
+	for i, videoID := range videoIDs {
+		if video, err := h.videoRepo.GetByID(c.Context(), videoID); err == nil {
+			videos[i] = video
+		}
+	}
+
+	export := services.BuildSessionExport(session, videos)
+	// Переводы берутся только готовые; недостающие не запускаются
+	if language != "" {
+		summaries := make(map[string]string)
+		for _, video := range videos {
+			if video == nil {
+				continue
+			}
+			translation, err := h.translationService.Completed(c.Context(), video.ID, language)
+			if err != nil {
+				return utils.Error(c, fiber.StatusInternalServerError, "Failed to export session")
+			}
+			if translation != nil {
+				summaries[video.ID.Hex()] = translation.Summary
+			}
+		}
+		export.UseTranslations(language, summaries)
+	}
+
+	body, contentType, err := export.Render(format)
 	if err != nil {
-		return err
+		return sessionError(c, err, "Failed to export session")
+	}
+
+	c.Attachment(fmt.Sprintf("session-%s.%s", sessionID.Hex(), format))
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+func (h *AIHandlers) DeleteSession(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	sessionID, ok := h.getValidSessionID(c)
+	if !ok {
+		return nil
+	}
+
+	// Свою сессию в воркспейсе удаляет редактор, чужие — только владелец
+	session, ok := h.validateSessionAccess(c, sessionID, userID, models.WorkspaceRoleEditor)
+	if !ok {
+		return nil
+	}
+	// userID уже проверен в validateSessionAccess
+	userObjectID, _ := primitive.ObjectIDFromHex(userID)
+	if session.WorkspaceID != nil && session.UserID != userObjectID {
+		if _, err := h.workspaceService.Authorize(c.Context(), *session.WorkspaceID, userObjectID, models.WorkspaceRoleOwner); err != nil {
+			return utils.Error(c, fiber.StatusForbidden, "Access denied")
+		}
 	}
 
 	if err := h.sessionRepo.Delete(c.Context(), sessionID); err != nil {
@@ -199,28 +895,42 @@ func (h *AIHandlers) DeleteSession(c *fiber.Ctx) error {
 	})
 }
 
-func (h *AIHandlers) getValidSessionID(c *fiber.Ctx) (primitive.ObjectID, error) {
+// getValidSessionID разбирает :id; при ошибке ответ 400 уже записан и ok == false
+func (h *AIHandlers) getValidSessionID(c *fiber.Ctx) (primitive.ObjectID, bool) {
 	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
-		return primitive.NilObjectID, utils.Error(c, fiber.StatusBadRequest, "Invalid session ID")
+		utils.Error(c, fiber.StatusBadRequest, "Invalid session ID")
+		return primitive.NilObjectID, false
 	}
-	return sessionID, nil
+	return sessionID, true
 }
 
-func (h *AIHandlers) validateSessionAccess(c *fiber.Ctx, sessionID primitive.ObjectID, userID string) (*models.AISession, error) {
+// validateSessionAccess пускает к личной сессии её автора, а к сессии воркспейса —
+// участников с ролью не ниже minRole, автора тоже: исключённый участник теряет
+// доступ и к своим сессиям. Без доступа ответ с ошибкой уже записан
+// и ok == false: обработчик должен сразу вернуть nil.
+func (h *AIHandlers) validateSessionAccess(c *fiber.Ctx, sessionID primitive.ObjectID, userID string, minRole string) (*models.AISession, bool) {
 	userObjectID, err := primitive.ObjectIDFromHex(userID)
 	if err != nil {
-		return nil, utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+		utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+		return nil, false
 	}
 
 	session, err := h.sessionRepo.GetByID(c.Context(), sessionID)
 	if err != nil {
-		return nil, utils.Error(c, fiber.StatusNotFound, "Session not found")
+		utils.Error(c, fiber.StatusNotFound, "Session not found")
+		return nil, false
 	}
 
-	if session.UserID != userObjectID {
-		return nil, utils.Error(c, fiber.StatusForbidden, "Access denied")
+	if session.WorkspaceID != nil {
+		if _, err := h.workspaceService.Authorize(c.Context(), *session.WorkspaceID, userObjectID, minRole); err != nil {
+			utils.Error(c, fiber.StatusForbidden, "Access denied")
+			return nil, false
+		}
+	} else if session.UserID != userObjectID {
+		utils.Error(c, fiber.StatusForbidden, "Access denied")
+		return nil, false
 	}
 
-	return session, nil
+	return session, true
 }
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type InsightsHandlers struct {
+	insightsService services.InsightsService
+}
+
+func NewInsightsHandlers(insightsService services.InsightsService) *InsightsHandlers {
+	return &InsightsHandlers{
+		insightsService: insightsService,
+	}
+}
+
+func (h *InsightsHandlers) GetInsights(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	insights, err := h.insightsService.Get(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return insightsError(c, err, "Failed to get insights")
+	}
+
+	return utils.Success(c, fiber.StatusOK, insights)
+}
+
+// RefreshInsights синхронно пересобирает выводы, например после правки саммари
+func (h *InsightsHandlers) RefreshInsights(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	insights, err := h.insightsService.Refresh(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return insightsError(c, err, "Failed to extract insights")
+	}
+
+	return utils.Success(c, fiber.StatusOK, insights)
+}
+
+// insightsError сопоставляет ошибки выводов с HTTP-статусами
+func insightsError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrVideoNotFound),
+		errors.Is(err, models.ErrWorkspaceNotFound),
+		errors.Is(err, models.ErrInsightsNotReady):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrNoVideoContent):
+		return utils.Error(c, fiber.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrMonthlyAITokensExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	case errors.Is(err, models.ErrInvalidAIOutput):
+		return utils.Error(c, fiber.StatusBadGateway, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errInvalidVideoIDs = errors.New("invalid video_ids")
+
+type LibraryHandlers struct {
+	libraryService   services.LibraryService
+	workspaceService services.WorkspaceService
+}
+
+func NewLibraryHandlers(libraryService services.LibraryService, workspaceService services.WorkspaceService) *LibraryHandlers {
+	return &LibraryHandlers{
+		libraryService:   libraryService,
+		workspaceService: workspaceService,
+	}
+}
+
+func (h *LibraryHandlers) ListTags(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get tags")
+	}
+
+	tags, err := h.libraryService.ListTags(c.Context(), userObjectID, workspaceID)
+	if err != nil {
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to get tags")
+	}
+
+	return utils.Success(c, fiber.StatusOK, tags)
+}
+
+func (h *LibraryHandlers) AssignTags(c *fiber.Ctx) error {
+	return h.bulkTags(c, h.libraryService.AssignTags)
+}
+
+func (h *LibraryHandlers) UnassignTags(c *fiber.Ctx) error {
+	return h.bulkTags(c, h.libraryService.UnassignTags)
+}
+
+type bulkTagsFunc func(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error)
+
+func (h *LibraryHandlers) bulkTags(c *fiber.Ctx, apply bulkTagsFunc) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	// Видео воркспейса меняют редакторы и владелец
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return workspaceError(c, err, "Failed to update tags")
+	}
+
+	var req models.BulkTagsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	videoIDs, err := parseObjectIDs(req.VideoIDs)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := apply(c.Context(), userObjectID, workspaceID, videoIDs, req.Tags)
+	if err != nil {
+		return libraryError(c, err, "Failed to update tags")
+	}
+
+	return utils.Success(c, fiber.StatusOK, result)
+}
+
+func (h *LibraryHandlers) SuggestTags(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	videoID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID")
+	}
+
+	tags, err := h.libraryService.SuggestTags(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return libraryError(c, err, "Failed to suggest tags")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"suggested_tags": tags,
+	})
+}
+
+func (h *LibraryHandlers) ListCollections(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	// Число видео в коллекциях считается по библиотеке воркспейса, если он указан
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get collections")
+	}
+
+	tree, err := h.libraryService.ListCollections(c.Context(), userObjectID, workspaceID)
+	if err != nil {
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to get collections")
+	}
+
+	return utils.Success(c, fiber.StatusOK, tree)
+}
+
+func (h *LibraryHandlers) CreateCollection(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req models.CreateCollectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	collection, err := h.libraryService.CreateCollection(c.Context(), userObjectID, req)
+	if err != nil {
+		return libraryError(c, err, "Failed to create collection")
+	}
+
+	return utils.Success(c, fiber.StatusCreated, collection)
+}
+
+func (h *LibraryHandlers) UpdateCollection(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	collectionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid collection ID")
+	}
+
+	var req models.UpdateCollectionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	collection, err := h.libraryService.UpdateCollection(c.Context(), userObjectID, collectionID, req)
+	if err != nil {
+		return libraryError(c, err, "Failed to update collection")
+	}
+
+	return utils.Success(c, fiber.StatusOK, collection)
+}
+
+func (h *LibraryHandlers) DeleteCollection(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	collectionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid collection ID")
+	}
+
+	if err := h.libraryService.DeleteCollection(c.Context(), userObjectID, collectionID); err != nil {
+		return libraryError(c, err, "Failed to delete collection")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"message": "Collection deleted successfully",
+	})
+}
+
+func (h *LibraryHandlers) AddVideos(c *fiber.Ctx) error {
+	return h.bulkCollection(c, h.libraryService.AddVideosToCollection)
+}
+
+func (h *LibraryHandlers) RemoveVideos(c *fiber.Ctx) error {
+	return h.bulkCollection(c, h.libraryService.RemoveVideosFromCollection)
+}
+
+type bulkCollectionFunc func(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, collectionID primitive.ObjectID, videoIDs []primitive.ObjectID) (*models.BulkResult, error)
+
+func (h *LibraryHandlers) bulkCollection(c *fiber.Ctx, apply bulkCollectionFunc) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	collectionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid collection ID")
+	}
+
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return workspaceError(c, err, "Failed to update collection")
+	}
+
+	var req models.BulkVideosRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	videoIDs, err := parseObjectIDs(req.VideoIDs)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	result, err := apply(c.Context(), userObjectID, workspaceID, collectionID, videoIDs)
+	if err != nil {
+		return libraryError(c, err, "Failed to update collection")
+	}
+
+	return utils.Success(c, fiber.StatusOK, result)
+}
+
+// parseVideoLibraryFilter читает tag, collection_id и include_nested (по умолчанию true)
+// и раскрывает коллекцию в список ID с учётом вложенных
+func parseVideoLibraryFilter(c *fiber.Ctx, library services.LibraryService, userID primitive.ObjectID, filter *models.VideoFilter) error {
+	if tag := c.Query("tag"); tag != "" {
+		filter.Tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	}
+
+	raw := c.Query("collection_id")
+	if raw == "" {
+		return nil
+	}
+
+	collectionID, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return models.ErrCollectionNotFound
+	}
+
+	ids, err := library.ResolveCollection(c.Context(), userID, collectionID, c.QueryBool("include_nested", true))
+	if err != nil {
+		return err
+	}
+	filter.CollectionIDs = ids
+	return nil
+}
+
+func parseObjectIDs(values []string) ([]primitive.ObjectID, error) {
+	if len(values) == 0 {
+		return nil, errInvalidVideoIDs
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, errInvalidVideoIDs
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// libraryError сопоставляет ошибки тегов и коллекций с HTTP-статусами
+func libraryError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrVideoNotFound),
+		errors.Is(err, models.ErrWorkspaceNotFound),
+		errors.Is(err, models.ErrCollectionNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrInvalidTag),
+		errors.Is(err, models.ErrTooManyTags),
+		errors.Is(err, models.ErrTooManyVideos),
+		errors.Is(err, models.ErrInvalidCollectionName),
+		errors.Is(err, models.ErrCollectionTooDeep):
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrCollectionCycle):
+		return utils.Error(c, fiber.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrMonthlyAITokensExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
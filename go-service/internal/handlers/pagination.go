@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	errInvalidLimit     = errors.New("invalid limit")
+	errInvalidSortOrder = errors.New("invalid sort order, expected asc or desc")
+	errInvalidFromDate  = errors.New("invalid 'from' date")
+	errInvalidToDate    = errors.New("invalid 'to' date")
+)
+
+// parsePageOptions читает cursor, limit, sort и order из query-параметров
+func parsePageOptions(c *fiber.Ctx) (models.PageOptions, error) {
+	page := models.PageOptions{
+		Cursor: c.Query("cursor"),
+		Limit:  c.QueryInt("limit", models.DefaultPageLimit),
+		SortBy: c.Query("sort"),
+	}
+
+	if page.Limit <= 0 {
+		return page, errInvalidLimit
+	}
+
+	switch order := c.Query("order", models.SortDesc); order {
+	case models.SortAsc, models.SortDesc:
+		page.SortOrder = order
+	default:
+		return page, errInvalidSortOrder
+	}
+
+	return page, nil
+}
+
+// parseDateRange читает from/to в формате RFC3339 или YYYY-MM-DD.
+// Дата без времени в "to" включает весь указанный день.
+func parseDateRange(c *fiber.Ctx) (from, to *time.Time, err error) {
+	if v := c.Query("from"); v != "" {
+		t, _, err := parseQueryTime(v)
+		if err != nil {
+			return nil, nil, errInvalidFromDate
+		}
+		from = &t
+	}
+
+	if v := c.Query("to"); v != "" {
+		t, dateOnly, err := parseQueryTime(v)
+		if err != nil {
+			return nil, nil, errInvalidToDate
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		}
+		to = &t
+	}
+
+	return from, to, nil
+}
+
+func parseQueryTime(value string) (time.Time, bool, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.DateOnly, value)
+	return t, true, err
+}
+
+// pageError переводит ошибки пагинации репозитория в HTTP-ответ
+func pageError(c *fiber.Ctx, err error, message string) error {
+	if errors.Is(err, models.ErrInvalidCursor) ||
+		errors.Is(err, models.ErrCursorMismatch) ||
+		errors.Is(err, models.ErrInvalidSortField) {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, message)
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type QuizHandlers struct {
+	quizService services.QuizService
+}
+
+func NewQuizHandlers(quizService services.QuizService) *QuizHandlers {
+	return &QuizHandlers{
+		quizService: quizService,
+	}
+}
+
+// GenerateQuiz синхронно генерирует тест и карточки по видео
+func (h *QuizHandlers) GenerateQuiz(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Тело необязательно
+	var req models.GenerateQuizRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	quiz, err := h.quizService.Generate(c.Context(), userObjectID, videoID, req)
+	if err != nil {
+		return quizError(c, err, "Failed to generate quiz")
+	}
+
+	return utils.Success(c, fiber.StatusCreated, quiz)
+}
+
+func (h *QuizHandlers) ListQuizzes(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	quizzes, err := h.quizService.List(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return quizError(c, err, "Failed to get quizzes")
+	}
+
+	return utils.Success(c, fiber.StatusOK, quizzes)
+}
+
+func (h *QuizHandlers) GetQuiz(c *fiber.Ctx) error {
+	userObjectID, videoID, quizID, err := quizParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	quiz, err := h.quizService.Get(c.Context(), userObjectID, videoID, quizID)
+	if err != nil {
+		return quizError(c, err, "Failed to get quiz")
+	}
+
+	return utils.Success(c, fiber.StatusOK, quiz)
+}
+
+// ExportQuiz отдаёт тест файлом CSV для импорта в Anki
+func (h *QuizHandlers) ExportQuiz(c *fiber.Ctx) error {
+	userObjectID, videoID, quizID, err := quizParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	body, err := h.quizService.ExportAnki(c.Context(), userObjectID, videoID, quizID)
+	if err != nil {
+		return quizError(c, err, "Failed to export quiz")
+	}
+
+	c.Attachment(fmt.Sprintf("quiz-%s.csv", quizID.Hex()))
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+func quizParams(c *fiber.Ctx) (userID, videoID, quizID primitive.ObjectID, err error) {
+	if userID, videoID, err = summaryParams(c); err != nil {
+		return userID, videoID, quizID, err
+	}
+	if quizID, err = primitive.ObjectIDFromHex(c.Params("quizId")); err != nil {
+		return userID, videoID, quizID, errors.New("invalid quiz ID")
+	}
+	return userID, videoID, quizID, nil
+}
+
+// quizError сопоставляет ошибки тестов с HTTP-статусами
+func quizError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrVideoNotFound),
+		errors.Is(err, models.ErrWorkspaceNotFound),
+		errors.Is(err, models.ErrQuizNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrInvalidQuizRequest):
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrNoVideoContent):
+		return utils.Error(c, fiber.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrMonthlyAITokensExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	case errors.Is(err, models.ErrInvalidAIOutput):
+		return utils.Error(c, fiber.StatusBadGateway, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
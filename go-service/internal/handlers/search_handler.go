@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type SearchHandlers struct {
+	searchService    services.SearchService
+	embeddingService services.EmbeddingService
+	libraryService   services.LibraryService
+	workspaceService services.WorkspaceService
+}
+
+func NewSearchHandlers(searchService services.SearchService, embeddingService services.EmbeddingService, libraryService services.LibraryService, workspaceService services.WorkspaceService) *SearchHandlers {
+	return &SearchHandlers{
+		searchService:    searchService,
+		embeddingService: embeddingService,
+		libraryService:   libraryService,
+		workspaceService: workspaceService,
+	}
+}
+
+func (h *SearchHandlers) Search(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	language := c.Query("lang")
+	if language != "" && language != "ru" && language != "en" {
+		return utils.Error(c, fiber.StatusBadRequest, "Unsupported language, expected ru or en")
+	}
+
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return workspaceError(c, err, "Search failed")
+	}
+
+	filter := models.VideoFilter{WorkspaceID: workspaceID}
+	if err := parseVideoLibraryFilter(c, h.libraryService, userObjectID, &filter); err != nil {
+		return libraryError(c, err, "Search failed")
+	}
+
+	result, err := h.searchService.Search(c.Context(), userObjectID, c.Query("q"), language, filter, c.QueryInt("limit", models.DefaultSearchLimit))
+	if err != nil {
+		if errors.Is(err, models.ErrEmptySearchQuery) {
+			return utils.Error(c, fiber.StatusBadRequest, "Search query cannot be empty")
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Search failed")
+	}
+
+	return utils.Success(c, fiber.StatusOK, result)
+}
+
+func (h *SearchHandlers) SemanticSearch(c *fiber.Ctx) error {
+	userID := c.Locals("userID").(string)
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return workspaceError(c, err, "Semantic search failed")
+	}
+
+	filter := models.VideoFilter{
+		Status:      c.Query("status"),
+		Language:    c.Query("language"),
+		From:        from,
+		To:          to,
+		WorkspaceID: workspaceID,
+	}
+	if err := parseVideoLibraryFilter(c, h.libraryService, userObjectID, &filter); err != nil {
+		return libraryError(c, err, "Semantic search failed")
+	}
+
+	result, err := h.embeddingService.SemanticSearch(c.Context(), userObjectID, c.Query("q"), filter, c.QueryInt("limit", models.DefaultSearchLimit))
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEmptySearchQuery):
+			return utils.Error(c, fiber.StatusBadRequest, "Search query cannot be empty")
+		case errors.Is(err, models.ErrEmbeddingsUnavailable):
+			return utils.Error(c, fiber.StatusServiceUnavailable, "Semantic search is not configured")
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Semantic search failed")
+	}
+
+	return utils.Success(c, fiber.StatusOK, result)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Пароль ссылки передаётся заголовком, чтобы не попадать в логи запросов
+const sharePasswordHeader = "X-Share-Password"
+
+type ShareHandlers struct {
+	shareService services.ShareService
+}
+
+func NewShareHandlers(shareService services.ShareService) *ShareHandlers {
+	return &ShareHandlers{
+		shareService: shareService,
+	}
+}
+
+func (h *ShareHandlers) CreateShare(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	videoID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID")
+	}
+
+	var req models.CreateShareRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	share, err := h.shareService.CreateShare(c.Context(), userObjectID, videoID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrVideoNotFound), errors.Is(err, models.ErrWorkspaceNotFound):
+			return utils.Error(c, fiber.StatusNotFound, "Video not found")
+		case errors.Is(err, models.ErrWorkspaceAccessDenied):
+			return utils.Error(c, fiber.StatusForbidden, err.Error())
+		case errors.Is(err, models.ErrInvalidShareTTL):
+			return utils.Error(c, fiber.StatusBadRequest, "expires_in must be between 0 and 8760 hours")
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to create share link")
+	}
+
+	return utils.Success(c, fiber.StatusCreated, share)
+}
+
+func (h *ShareHandlers) ListShares(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	videoID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID")
+	}
+
+	shares, err := h.shareService.ListShares(c.Context(), userObjectID, videoID)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrVideoNotFound), errors.Is(err, models.ErrWorkspaceNotFound):
+			return utils.Error(c, fiber.StatusNotFound, "Video not found")
+		case errors.Is(err, models.ErrWorkspaceAccessDenied):
+			return utils.Error(c, fiber.StatusForbidden, err.Error())
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to get share links")
+	}
+
+	return utils.Success(c, fiber.StatusOK, shares)
+}
+
+func (h *ShareHandlers) RevokeShare(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	shareID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid share ID")
+	}
+
+	if err := h.shareService.RevokeShare(c.Context(), userObjectID, shareID); err != nil {
+		if errors.Is(err, models.ErrShareNotFound) {
+			return utils.Error(c, fiber.StatusNotFound, "Share link not found")
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to revoke share link")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"message": "Share link revoked",
+	})
+}
+
+// OpenShare — публичный эндпоинт, доступный без JWT
+func (h *ShareHandlers) OpenShare(c *fiber.Ctx) error {
+	shared, err := h.shareService.OpenShare(c.Context(), c.Params("token"), c.Get(sharePasswordHeader), c.IP())
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrShareNotFound):
+			return utils.Error(c, fiber.StatusNotFound, "Share link not found")
+		case errors.Is(err, models.ErrShareExpired):
+			return utils.Error(c, fiber.StatusGone, "Share link expired")
+		case errors.Is(err, models.ErrSharePasswordRequired):
+			return utils.Error(c, fiber.StatusUnauthorized, "Password required")
+		case errors.Is(err, models.ErrInvalidSharePassword):
+			return utils.Error(c, fiber.StatusForbidden, "Invalid password")
+		case errors.Is(err, models.ErrTooManyShareAttempts):
+			return utils.Error(c, fiber.StatusTooManyRequests, "Too many attempts, try again later")
+		}
+		return utils.Error(c, fiber.StatusInternalServerError, "Failed to open share link")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return utils.Success(c, fiber.StatusOK, shared)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var errInvalidVersion = errors.New("invalid summary version")
+
+type SummaryHandlers struct {
+	summaryService services.SummaryService
+}
+
+func NewSummaryHandlers(summaryService services.SummaryService) *SummaryHandlers {
+	return &SummaryHandlers{
+		summaryService: summaryService,
+	}
+}
+
+func (h *SummaryHandlers) UpdateSummary(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	var req models.UpdateSummaryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	version, err := h.summaryService.UpdateSummary(c.Context(), userObjectID, videoID, req.Summary)
+	if err != nil {
+		return summaryError(c, err, "Failed to update summary")
+	}
+
+	return utils.Success(c, fiber.StatusOK, version)
+}
+
+func (h *SummaryHandlers) ListVersions(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	versions, err := h.summaryService.ListVersions(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return summaryError(c, err, "Failed to get summary versions")
+	}
+
+	return utils.Success(c, fiber.StatusOK, versions)
+}
+
+func (h *SummaryHandlers) GetVersion(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	number, err := parseVersion(c.Params("version"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	version, err := h.summaryService.GetVersion(c.Context(), userObjectID, videoID, number)
+	if err != nil {
+		return summaryError(c, err, "Failed to get summary version")
+	}
+
+	return utils.Success(c, fiber.StatusOK, version)
+}
+
+func (h *SummaryHandlers) Diff(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	from, err := parseVersion(c.Query("from"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+	to, err := parseVersion(c.Query("to"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	diff, err := h.summaryService.Diff(c.Context(), userObjectID, videoID, from, to)
+	if err != nil {
+		return summaryError(c, err, "Failed to diff summary versions")
+	}
+
+	return utils.Success(c, fiber.StatusOK, diff)
+}
+
+func (h *SummaryHandlers) RestoreVersion(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	number, err := parseVersion(c.Params("version"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	version, err := h.summaryService.Restore(c.Context(), userObjectID, videoID, number)
+	if err != nil {
+		return summaryError(c, err, "Failed to restore summary version")
+	}
+
+	return utils.Success(c, fiber.StatusOK, version)
+}
+
+func (h *SummaryHandlers) ImproveSummary(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Тело необязательно
+	var req models.ImproveSummaryRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+
+	job, err := h.summaryService.ImproveSummary(c.Context(), userObjectID, videoID, req.Focus)
+	if err != nil {
+		return summaryError(c, err, "Failed to start summary improvement")
+	}
+
+	return utils.Success(c, fiber.StatusAccepted, job)
+}
+
+func (h *SummaryHandlers) FixSummary(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	job, err := h.summaryService.FixSummary(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return summaryError(c, err, "Failed to start summary fix")
+	}
+
+	return utils.Success(c, fiber.StatusAccepted, job)
+}
+
+func (h *SummaryHandlers) SummarizeSession(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	job, err := h.summaryService.SummarizeSession(c.Context(), userObjectID, sessionID)
+	if err != nil {
+		return summaryError(c, err, "Failed to start dialogue summary")
+	}
+
+	return utils.Success(c, fiber.StatusAccepted, job)
+}
+
+func summaryParams(c *fiber.Ctx) (userID, videoID primitive.ObjectID, err error) {
+	if userID, err = primitive.ObjectIDFromHex(c.Locals("userID").(string)); err != nil {
+		return userID, videoID, errors.New("invalid user ID")
+	}
+	if videoID, err = primitive.ObjectIDFromHex(c.Params("id")); err != nil {
+		return userID, videoID, errors.New("invalid video ID")
+	}
+	return userID, videoID, nil
+}
+
+func parseVersion(raw string) (int, error) {
+	version, err := strconv.Atoi(raw)
+	if err != nil || version < 1 {
+		return 0, errInvalidVersion
+	}
+	return version, nil
+}
+
+// summaryError сопоставляет ошибки версий саммари с HTTP-статусами
+func summaryError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrVideoNotFound),
+		errors.Is(err, models.ErrWorkspaceNotFound),
+		errors.Is(err, models.ErrSummaryVersionNotFound),
+		errors.Is(err, models.ErrSessionNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrInvalidSummary),
+		errors.Is(err, models.ErrInvalidImproveFocus),
+		errors.Is(err, models.ErrEmptyDialogue):
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrSummaryVersionConflict),
+		errors.Is(err, models.ErrMultiVideoSession),
+		errors.Is(err, models.ErrSummaryJobInProgress),
+		errors.Is(err, models.ErrSummaryNotReady):
+		return utils.Error(c, fiber.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrMonthlyAnalysesLimitExceeded),
+		errors.Is(err, models.ErrMonthlyAITokensExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TranslationHandlers struct {
+	translationService services.TranslationService
+}
+
+func NewTranslationHandlers(translationService services.TranslationService) *TranslationHandlers {
+	return &TranslationHandlers{
+		translationService: translationService,
+	}
+}
+
+// TranslateVideo запускает перевод и отвечает 202; готовый актуальный перевод
+// возвращается сразу с 200
+func (h *TranslationHandlers) TranslateVideo(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	var req models.TranslateVideoRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	translation, started, err := h.translationService.Translate(c.Context(), userObjectID, videoID, req.Language)
+	if err != nil {
+		return translationError(c, err, "Failed to start translation")
+	}
+
+	status := fiber.StatusOK
+	if started {
+		status = fiber.StatusAccepted
+	}
+	return utils.Success(c, status, translation)
+}
+
+func (h *TranslationHandlers) ListTranslations(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	translations, err := h.translationService.List(c.Context(), userObjectID, videoID)
+	if err != nil {
+		return translationError(c, err, "Failed to get translations")
+	}
+
+	return utils.Success(c, fiber.StatusOK, translations)
+}
+
+func (h *TranslationHandlers) GetTranslation(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	translation, err := h.translationService.Get(c.Context(), userObjectID, videoID, c.Params("lang"))
+	if err != nil {
+		return translationError(c, err, "Failed to get translation")
+	}
+
+	return utils.Success(c, fiber.StatusOK, translation)
+}
+
+// GetSubtitles отдаёт субтитры файлом; lang выбирает готовый перевод
+func (h *TranslationHandlers) GetSubtitles(c *fiber.Ctx) error {
+	userObjectID, videoID, err := summaryParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	format := c.Query("format", services.SubtitleFormatSRT)
+	language := c.Query("lang")
+	body, contentType, err := h.translationService.Subtitles(c.Context(), userObjectID, videoID, language, format)
+	if err != nil {
+		return translationError(c, err, "Failed to get subtitles")
+	}
+
+	name := "subtitles-" + videoID.Hex()
+	if language != "" {
+		name += "." + language
+	}
+	c.Attachment(fmt.Sprintf("%s.%s", name, format))
+	c.Set(fiber.HeaderContentType, contentType)
+	return c.Status(fiber.StatusOK).Send(body)
+}
+
+// translationError сопоставляет ошибки перевода с HTTP-статусами
+func translationError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrInvalidTranslationLanguage),
+		errors.Is(err, models.ErrInvalidSubtitleFormat):
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrVideoNotFound),
+		errors.Is(err, models.ErrWorkspaceNotFound),
+		errors.Is(err, models.ErrTranslationNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrNoVideoContent),
+		errors.Is(err, models.ErrNoTranscriptSegments),
+		errors.Is(err, models.ErrTranslationNotReady):
+		return utils.Error(c, fiber.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrMonthlyAITokensExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
@@ -1,6 +1,9 @@
 package handlers
 
 import (
+	"errors"
+
+	"github.com/code-zt/vidnotes/internal/models"
 	"github.com/code-zt/vidnotes/internal/services"
 	"github.com/code-zt/vidnotes/pkg/utils"
 	"github.com/gofiber/fiber/v2"
@@ -8,12 +11,16 @@ import (
 )
 
 type VideoHandlers struct {
-	videoService services.VideoService
+	videoService     services.VideoService
+	libraryService   services.LibraryService
+	workspaceService services.WorkspaceService
 }
 
-func NewVideoHandlers(videoService services.VideoService) *VideoHandlers {
+func NewVideoHandlers(videoService services.VideoService, libraryService services.LibraryService, workspaceService services.WorkspaceService) *VideoHandlers {
 	return &VideoHandlers{
-		videoService: videoService,
+		videoService:     videoService,
+		libraryService:   libraryService,
+		workspaceService: workspaceService,
 	}
 }
 
@@ -28,6 +35,12 @@ func (h *VideoHandlers) UploadVideo(c *fiber.Ctx) error {
 		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
+	// Загружать в воркспейс могут редакторы и владелец
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return workspaceError(c, err, "Failed to upload video")
+	}
+
 	form, err := c.MultipartForm()
 	if err != nil {
 		return utils.Error(c, fiber.StatusBadRequest, "Invalid form data")
@@ -51,7 +64,7 @@ func (h *VideoHandlers) UploadVideo(c *fiber.Ctx) error {
 		return utils.Error(c, fiber.StatusInternalServerError, "Failed to read file")
 	}
 
-	video, err := h.videoService.UploadVideo(c.Context(), userObjectID, fileBytes, fileHeader.Filename)
+	video, err := h.videoService.UploadVideo(c.Context(), userObjectID, workspaceID, fileBytes, fileHeader.Filename)
 	if err != nil {
 		return utils.Error(c, fiber.StatusInternalServerError, err.Error())
 	}
@@ -63,14 +76,14 @@ func (h *VideoHandlers) UploadVideo(c *fiber.Ctx) error {
 }
 
 func (h *VideoHandlers) GetVideoStatus(c *fiber.Ctx) error {
-	videoID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	userObjectID, videoID, err := summaryParams(c)
 	if err != nil {
-		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID")
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
 	}
 
-	video, err := h.videoService.GetVideoStatus(c.Context(), videoID)
+	video, err := h.videoService.GetVideoStatus(c.Context(), userObjectID, videoID)
 	if err != nil {
-		return utils.Error(c, fiber.StatusNotFound, "Video not found")
+		return videoError(c, err, "Failed to get video")
 	}
 
 	return utils.Success(c, fiber.StatusOK, video)
@@ -83,39 +96,78 @@ func (h *VideoHandlers) GetUserVideos(c *fiber.Ctx) error {
 		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
 	}
 
-	videos, err := h.videoService.GetUserVideos(c.Context(), userObjectID)
+	page, err := parsePageOptions(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	workspaceID, err := workspaceScope(c, h.workspaceService, userObjectID, models.WorkspaceRoleViewer)
 	if err != nil {
-		return utils.Error(c, fiber.StatusInternalServerError, "Failed to get videos")
+		return workspaceError(c, err, "Failed to get videos")
+	}
+
+	filter := models.VideoFilter{
+		Status:      c.Query("status"),
+		Language:    c.Query("language"),
+		From:        from,
+		To:          to,
+		WorkspaceID: workspaceID,
+	}
+	if err := parseVideoLibraryFilter(c, h.libraryService, userObjectID, &filter); err != nil {
+		return libraryError(c, err, "Failed to get videos")
+	}
+
+	videos, err := h.videoService.GetUserVideos(c.Context(), userObjectID, filter, page)
+	if err != nil {
+		return pageError(c, err, "Failed to get videos")
 	}
 
 	return utils.Success(c, fiber.StatusOK, videos)
 }
 
 func (h *VideoHandlers) GetVideoResult(c *fiber.Ctx) error {
-	videoID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	userObjectID, videoID, err := summaryParams(c)
 	if err != nil {
-		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID")
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
 	}
 
-	result, err := h.videoService.GetVideoResult(c.Context(), videoID)
+	result, err := h.videoService.GetVideoResult(c.Context(), userObjectID, videoID)
 	if err != nil {
-		return utils.Error(c, fiber.StatusNotFound, "Video result not found")
+		return videoError(c, err, "Failed to get video result")
 	}
 
 	return utils.Success(c, fiber.StatusOK, result)
 }
 
 func (h *VideoHandlers) DeleteVideo(c *fiber.Ctx) error {
-	videoID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	userObjectID, videoID, err := summaryParams(c)
 	if err != nil {
-		return utils.Error(c, fiber.StatusBadRequest, "Invalid video ID")
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
 	}
 
-	if err := h.videoService.DeleteVideo(c.Context(), videoID); err != nil {
-		return utils.Error(c, fiber.StatusInternalServerError, "Failed to delete video")
+	if err := h.videoService.DeleteVideo(c.Context(), userObjectID, videoID); err != nil {
+		return videoError(c, err, "Failed to delete video")
 	}
 
 	return utils.Success(c, fiber.StatusOK, fiber.Map{
 		"message": "Video deleted successfully",
 	})
 }
+
+// videoError сопоставляет ошибки доступа к видео с HTTP-статусами
+func videoError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, models.ErrVideoNotFound),
+		errors.Is(err, models.ErrVideoResultNotFound),
+		errors.Is(err, models.ErrWorkspaceNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
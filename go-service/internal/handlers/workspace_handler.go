@@ -0,0 +1,326 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/services"
+	"github.com/code-zt/vidnotes/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Заголовок, переключающий запросы к видео и сессиям на библиотеку воркспейса
+const workspaceHeader = "X-Workspace-ID"
+
+var errInvalidWorkspaceID = errors.New("invalid workspace ID")
+
+type WorkspaceHandlers struct {
+	workspaceService services.WorkspaceService
+}
+
+func NewWorkspaceHandlers(workspaceService services.WorkspaceService) *WorkspaceHandlers {
+	return &WorkspaceHandlers{
+		workspaceService: workspaceService,
+	}
+}
+
+func (h *WorkspaceHandlers) CreateWorkspace(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req models.CreateWorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	workspace, err := h.workspaceService.CreateWorkspace(c.Context(), userObjectID, req)
+	if err != nil {
+		return workspaceError(c, err, "Failed to create workspace")
+	}
+
+	return utils.Success(c, fiber.StatusCreated, workspace)
+}
+
+func (h *WorkspaceHandlers) ListWorkspaces(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	workspaces, err := h.workspaceService.ListWorkspaces(c.Context(), userObjectID)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get workspaces")
+	}
+
+	return utils.Success(c, fiber.StatusOK, workspaces)
+}
+
+func (h *WorkspaceHandlers) GetWorkspace(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	workspace, err := h.workspaceService.GetWorkspace(c.Context(), userObjectID, workspaceID)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get workspace")
+	}
+
+	return utils.Success(c, fiber.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandlers) UpdateWorkspace(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	var req models.UpdateWorkspaceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	workspace, err := h.workspaceService.UpdateWorkspace(c.Context(), userObjectID, workspaceID, req)
+	if err != nil {
+		return workspaceError(c, err, "Failed to update workspace")
+	}
+
+	return utils.Success(c, fiber.StatusOK, workspace)
+}
+
+func (h *WorkspaceHandlers) DeleteWorkspace(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	if err := h.workspaceService.DeleteWorkspace(c.Context(), userObjectID, workspaceID); err != nil {
+		return workspaceError(c, err, "Failed to delete workspace")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"message": "Workspace deleted successfully",
+	})
+}
+
+func (h *WorkspaceHandlers) GetAnalytics(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	analytics, err := h.workspaceService.GetAnalyticsInfo(c.Context(), userObjectID, workspaceID)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get workspace analytics")
+	}
+
+	return utils.Success(c, fiber.StatusOK, analytics)
+}
+
+func (h *WorkspaceHandlers) ListMembers(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	members, err := h.workspaceService.ListMembers(c.Context(), userObjectID, workspaceID)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get members")
+	}
+
+	return utils.Success(c, fiber.StatusOK, members)
+}
+
+func (h *WorkspaceHandlers) UpdateMemberRole(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	memberID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid member ID")
+	}
+
+	var req models.UpdateMemberRoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := h.workspaceService.UpdateMemberRole(c.Context(), userObjectID, workspaceID, memberID, req.Role); err != nil {
+		return workspaceError(c, err, "Failed to update member")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"message": "Member role updated",
+	})
+}
+
+func (h *WorkspaceHandlers) RemoveMember(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	memberID, err := primitive.ObjectIDFromHex(c.Params("userId"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid member ID")
+	}
+
+	if err := h.workspaceService.RemoveMember(c.Context(), userObjectID, workspaceID, memberID); err != nil {
+		return workspaceError(c, err, "Failed to remove member")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"message": "Member removed",
+	})
+}
+
+func (h *WorkspaceHandlers) InviteMember(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	var req models.InviteMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	invite, err := h.workspaceService.InviteMember(c.Context(), userObjectID, workspaceID, req)
+	if err != nil {
+		return workspaceError(c, err, "Failed to create invite")
+	}
+
+	return utils.Success(c, fiber.StatusCreated, invite)
+}
+
+func (h *WorkspaceHandlers) ListInvites(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	invites, err := h.workspaceService.ListInvites(c.Context(), userObjectID, workspaceID)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get invites")
+	}
+
+	return utils.Success(c, fiber.StatusOK, invites)
+}
+
+func (h *WorkspaceHandlers) RevokeInvite(c *fiber.Ctx) error {
+	userObjectID, workspaceID, err := workspaceParams(c)
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	inviteID, err := primitive.ObjectIDFromHex(c.Params("inviteId"))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid invite ID")
+	}
+
+	if err := h.workspaceService.RevokeInvite(c.Context(), userObjectID, workspaceID, inviteID); err != nil {
+		return workspaceError(c, err, "Failed to revoke invite")
+	}
+
+	return utils.Success(c, fiber.StatusOK, fiber.Map{
+		"message": "Invite revoked",
+	})
+}
+
+func (h *WorkspaceHandlers) ListMyInvites(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	invites, err := h.workspaceService.ListMyInvites(c.Context(), userObjectID)
+	if err != nil {
+		return workspaceError(c, err, "Failed to get invites")
+	}
+
+	return utils.Success(c, fiber.StatusOK, invites)
+}
+
+func (h *WorkspaceHandlers) AcceptInvite(c *fiber.Ctx) error {
+	userObjectID, err := primitive.ObjectIDFromHex(c.Locals("userID").(string))
+	if err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid user ID")
+	}
+
+	var req models.AcceptInviteRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.Error(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if req.Token == "" {
+		return utils.Error(c, fiber.StatusBadRequest, "Invite token is required")
+	}
+
+	workspace, err := h.workspaceService.AcceptInvite(c.Context(), userObjectID, req.Token)
+	if err != nil {
+		return workspaceError(c, err, "Failed to accept invite")
+	}
+
+	return utils.Success(c, fiber.StatusOK, workspace)
+}
+
+func workspaceParams(c *fiber.Ctx) (userID, workspaceID primitive.ObjectID, err error) {
+	if userID, err = primitive.ObjectIDFromHex(c.Locals("userID").(string)); err != nil {
+		return userID, workspaceID, errors.New("invalid user ID")
+	}
+	if workspaceID, err = primitive.ObjectIDFromHex(c.Params("id")); err != nil {
+		return userID, workspaceID, errInvalidWorkspaceID
+	}
+	return userID, workspaceID, nil
+}
+
+// workspaceScope читает X-Workspace-ID и проверяет роль пользователя.
+// Без заголовка возвращает nil — запрос относится к личной библиотеке.
+func workspaceScope(c *fiber.Ctx, workspaceService services.WorkspaceService, userID primitive.ObjectID, minRole string) (*primitive.ObjectID, error) {
+	raw := c.Get(workspaceHeader)
+	if raw == "" {
+		return nil, nil
+	}
+
+	workspaceID, err := primitive.ObjectIDFromHex(raw)
+	if err != nil {
+		return nil, errInvalidWorkspaceID
+	}
+
+	if _, err := workspaceService.Authorize(c.Context(), workspaceID, userID, minRole); err != nil {
+		return nil, err
+	}
+	return &workspaceID, nil
+}
+
+// workspaceError сопоставляет ошибки воркспейсов с HTTP-статусами
+func workspaceError(c *fiber.Ctx, err error, fallback string) error {
+	switch {
+	case errors.Is(err, errInvalidWorkspaceID),
+		errors.Is(err, models.ErrInvalidWorkspaceName),
+		errors.Is(err, models.ErrInvalidWorkspaceRole),
+		errors.Is(err, models.ErrInvalidInviteEmail):
+		return utils.Error(c, fiber.StatusBadRequest, err.Error())
+	case errors.Is(err, models.ErrWorkspaceAccessDenied):
+		return utils.Error(c, fiber.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrWorkspaceNotFound),
+		errors.Is(err, models.ErrMemberNotFound),
+		errors.Is(err, models.ErrInviteNotFound):
+		return utils.Error(c, fiber.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrAlreadyMember),
+		errors.Is(err, models.ErrOwnerCannotLeave),
+		errors.Is(err, models.ErrWorkspaceNotEmpty):
+		return utils.Error(c, fiber.StatusConflict, err.Error())
+	case errors.Is(err, models.ErrInviteExpired):
+		return utils.Error(c, fiber.StatusGone, err.Error())
+	case errors.Is(err, models.ErrMonthlyAnalysesLimitExceeded):
+		return utils.Error(c, fiber.StatusTooManyRequests, err.Error())
+	case errors.Is(err, models.ErrInviteSendFailed):
+		return utils.Error(c, fiber.StatusBadGateway, models.ErrInviteSendFailed.Error())
+	}
+	return utils.Error(c, fiber.StatusInternalServerError, fallback)
+}
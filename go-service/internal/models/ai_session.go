@@ -1,25 +1,188 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type AISession struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	VideoID   primitive.ObjectID `bson:"video_id" json:"video_id"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	Messages  []AIMessage        `bson:"messages" json:"messages"`
-	Title     string             `bson:"title" json:"title"`
-	Summary   string             `bson:"summary,omitempty" json:"summary,omitempty"`
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID  primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VideoID primitive.ObjectID `bson:"video_id" json:"video_id"`
+	// Для сессий по нескольким видео — все видео по порядку; VideoID совпадает с первым
+	VideoIDs []primitive.ObjectID `bson:"video_ids,omitempty" json:"video_ids,omitempty"`
+	// Коллекция, из которой набраны видео сессии
+	CollectionID *primitive.ObjectID `bson:"collection_id,omitempty" json:"collection_id,omitempty"`
+	// Сессии по видео воркспейса видны его участникам
+	WorkspaceID *primitive.ObjectID `bson:"workspace_id,omitempty" json:"workspace_id,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	Messages    []AIMessage         `bson:"messages" json:"messages,omitempty"` // в списках сессий не загружается
+	Title       string              `bson:"title" json:"title"`
+	Summary     string              `bson:"summary,omitempty" json:"summary,omitempty"`
+	Pinned      bool                `bson:"pinned,omitempty" json:"pinned"`
+	Archived    bool                `bson:"archived,omitempty" json:"archived"`
+
+	// Messages хранит сообщения всех веток по порядку добавления, ветки связаны
+	// через ParentID; ActiveLeafID — последнее сообщение активной ветки
+	ActiveLeafID *primitive.ObjectID `bson:"active_leaf_id,omitempty" json:"active_leaf_id,omitempty"`
+
+	// Сжатая память о ранних репликах; MemoryUpTo — сколько первых сообщений ветки
+	// в неё свёрнуто, MemoryAnchorID — последнее из них: в другой ветке память не действует
+	Memory         string              `bson:"memory,omitempty" json:"memory,omitempty"`
+	MemoryUpTo     int                 `bson:"memory_up_to,omitempty" json:"memory_up_to,omitempty"`
+	MemoryAnchorID *primitive.ObjectID `bson:"memory_anchor_id,omitempty" json:"memory_anchor_id,omitempty"`
+}
+
+// Videos возвращает видео сессии по порядку
+func (s *AISession) Videos() []primitive.ObjectID {
+	if len(s.VideoIDs) > 0 {
+		return s.VideoIDs
+	}
+	return []primitive.ObjectID{s.VideoID}
+}
+
+const (
+	// MaxSessionVideos — сколько видео можно обсуждать в одной сессии
+	MaxSessionVideos = 10
+	// MaxSessionTitleLength — длина названия сессии в символах
+	MaxSessionTitleLength = 100
+)
+
+// Message возвращает сообщение сессии по ID
+func (s *AISession) Message(id primitive.ObjectID) (*AIMessage, bool) {
+	for i := range s.Messages {
+		if s.Messages[i].ID == id {
+			return &s.Messages[i], true
+		}
+	}
+	return nil, false
+}
+
+// BranchTo возвращает ветку от первого сообщения до leafID включительно;
+// nil — пустая ветка
+func (s *AISession) BranchTo(leafID *primitive.ObjectID) []AIMessage {
+	index := make(map[primitive.ObjectID]int, len(s.Messages))
+	for i, msg := range s.Messages {
+		index[msg.ID] = i
+	}
+
+	var branch []AIMessage
+	// Ограничение по числу сообщений защищает от циклов в повреждённых данных
+	for id := leafID; id != nil && len(branch) < len(s.Messages); {
+		i, ok := index[*id]
+		if !ok {
+			break
+		}
+		branch = append(branch, s.Messages[i])
+		id = s.Messages[i].ParentID
+	}
+	slices.Reverse(branch)
+	return branch
+}
+
+// Branch возвращает активную ветку диалога; сессии без ветвления — все сообщения по порядку
+func (s *AISession) Branch() []AIMessage {
+	if s.ActiveLeafID == nil {
+		return s.Messages
+	}
+	return s.BranchTo(s.ActiveLeafID)
+}
+
+// BranchMemory возвращает память и число свёрнутых в неё сообщений, если она
+// собрана из начала этой ветки; иначе память не действует
+func (s *AISession) BranchMemory(branch []AIMessage) (string, int) {
+	if s.MemoryUpTo == 0 || s.MemoryUpTo > len(branch) {
+		return "", 0
+	}
+	if s.MemoryAnchorID != nil && branch[s.MemoryUpTo-1].ID != *s.MemoryAnchorID {
+		return "", 0
+	}
+	return s.Memory, s.MemoryUpTo
+}
+
+// LatestLeaf спускается от сообщения по самым новым ответам до конца ветки
+func (s *AISession) LatestLeaf(id primitive.ObjectID) primitive.ObjectID {
+	for range s.Messages {
+		next := id
+		for _, msg := range s.Messages {
+			if msg.ParentID != nil && *msg.ParentID == id {
+				next = msg.ID
+			}
+		}
+		if next == id {
+			break
+		}
+		id = next
+	}
+	return id
+}
+
+// View возвращает сессию с активной веткой вместо всего дерева сообщений
+func (s *AISession) View() *AISessionView {
+	siblings := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, msg := range s.Messages {
+		siblings[msg.parent()] = append(siblings[msg.parent()], msg.ID)
+	}
+
+	branch := s.Branch()
+	messages := make([]AIBranchMessage, len(branch))
+	for i, msg := range branch {
+		messages[i].AIMessage = msg
+		if alternatives := siblings[msg.parent()]; len(alternatives) > 1 && !msg.ID.IsZero() {
+			messages[i].Alternatives = alternatives
+		}
+	}
+
+	return &AISessionView{AISession: s, Messages: messages}
+}
+
+// AISessionView — сессия с активной веткой диалога
+type AISessionView struct {
+	*AISession
+	Messages []AIBranchMessage `json:"messages"`
+}
+
+// AIBranchMessage — сообщение активной ветки; Alternatives — все версии этого
+// места диалога (правки вопроса или перегенерированные ответы) по порядку
+// создания, включая само сообщение
+type AIBranchMessage struct {
+	AIMessage
+	Alternatives []primitive.ObjectID `json:"alternatives,omitempty"`
 }
 
 type AIMessage struct {
+	ID primitive.ObjectID `bson:"id,omitempty" json:"id,omitempty"`
+	// Предыдущее сообщение ветки; nil — начало диалога
+	ParentID *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+
 	Role    string    `bson:"role" json:"role"`
 	Content string    `bson:"content" json:"content"`
 	Time    time.Time `bson:"time" json:"time"`
+	// Чанки транскрипта, подставленные в контекст ответа ассистента
+	ChunkIDs []primitive.ObjectID `bson:"chunk_ids,omitempty" json:"chunk_ids,omitempty"`
+	// Проверенные ссылки ответа на сегменты транскрипта
+	Citations []AICitation `bson:"citations,omitempty" json:"citations,omitempty"`
+	// Модель, которая фактически ответила, с учётом запасных
+	Model string `bson:"model,omitempty" json:"model,omitempty"`
+}
+
+// parent — ID предыдущего сообщения; для начала диалога нулевой
+func (m *AIMessage) parent() primitive.ObjectID {
+	if m.ParentID == nil {
+		return primitive.NilObjectID
+	}
+	return *m.ParentID
+}
+
+// AICitation — ссылка ответа ассистента на сегмент транскрипта
+type AICitation struct {
+	VideoID   primitive.ObjectID `bson:"video_id" json:"video_id"`
+	SegmentID int                `bson:"segment_id" json:"segment_id"`
+	Start     float64            `bson:"start" json:"start"`
+	End       float64            `bson:"end" json:"end"`
+	Quote     string             `bson:"quote" json:"quote"`
 }
 
 type AIRequest struct {
@@ -29,7 +192,10 @@ type AIRequest struct {
 }
 
 type AIResponse struct {
-	Message   string    `json:"message"`
-	SessionID string    `json:"session_id"`
-	Time      time.Time `json:"time"`
+	MessageID string       `json:"message_id"`
+	Message   string       `json:"message"`
+	SessionID string       `json:"session_id"`
+	Time      time.Time    `json:"time"`
+	Citations []AICitation `json:"citations,omitempty"`
+	Model     string       `json:"model,omitempty"`
 }
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// testTree — диалог с перегенерированным ответом и отредактированным вопросом:
+//
+//	q1 ─┬─ a1 ─┬─ q2 ── a2
+//	    │      └─ q2b ─ a2b
+//	    └─ a1b
+func testTree() (*AISession, map[string]primitive.ObjectID) {
+	ids := make(map[string]primitive.ObjectID)
+	for _, name := range []string{"q1", "a1", "q2", "a2", "a1b", "q2b", "a2b"} {
+		ids[name] = primitive.NewObjectID()
+	}
+	msg := func(name, parent, role string) AIMessage {
+		m := AIMessage{ID: ids[name], Role: role, Content: name}
+		if parent != "" {
+			p := ids[parent]
+			m.ParentID = &p
+		}
+		return m
+	}
+
+	session := &AISession{Messages: []AIMessage{
+		msg("q1", "", "user"),
+		msg("a1", "q1", "assistant"),
+		msg("q2", "a1", "user"),
+		msg("a2", "q2", "assistant"),
+		msg("a1b", "q1", "assistant"),
+		msg("q2b", "a1", "user"),
+		msg("a2b", "q2b", "assistant"),
+	}}
+	return session, ids
+}
+
+func contents(messages []AIMessage) []string {
+	out := make([]string, len(messages))
+	for i, m := range messages {
+		out[i] = m.Content
+	}
+	return out
+}
+
+func TestAISessionBranch(t *testing.T) {
+	session, ids := testTree()
+	ptr := func(name string) *primitive.ObjectID {
+		id := ids[name]
+		return &id
+	}
+	unknown := primitive.NewObjectID()
+
+	tests := []struct {
+		name string
+		leaf *primitive.ObjectID
+		want []string
+	}{
+		{name: "no active leaf", leaf: nil, want: []string{"q1", "a1", "q2", "a2", "a1b", "q2b", "a2b"}},
+		{name: "original branch", leaf: ptr("a2"), want: []string{"q1", "a1", "q2", "a2"}},
+		{name: "edited question", leaf: ptr("a2b"), want: []string{"q1", "a1", "q2b", "a2b"}},
+		{name: "regenerated answer", leaf: ptr("a1b"), want: []string{"q1", "a1b"}},
+		{name: "unknown leaf", leaf: &unknown, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session.ActiveLeafID = tt.leaf
+			if got := contents(session.Branch()); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Branch() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAISessionBranchCycle(t *testing.T) {
+	a, b := primitive.NewObjectID(), primitive.NewObjectID()
+	session := &AISession{
+		Messages: []AIMessage{
+			{ID: a, ParentID: &b, Content: "a"},
+			{ID: b, ParentID: &a, Content: "b"},
+		},
+		ActiveLeafID: &a,
+	}
+
+	if got := session.Branch(); len(got) > len(session.Messages) {
+		t.Errorf("Branch() on a cycle returned %d messages", len(got))
+	}
+}
+
+func TestAISessionLatestLeaf(t *testing.T) {
+	session, ids := testTree()
+
+	tests := []struct {
+		from string
+		want string
+	}{
+		{from: "q1", want: "a1b"},
+		{from: "a1", want: "a2b"},
+		{from: "q2", want: "a2"},
+		{from: "a2b", want: "a2b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			if got := session.LatestLeaf(ids[tt.from]); got != ids[tt.want] {
+				t.Errorf("LatestLeaf(%s) = %s, want %s", tt.from, got.Hex(), ids[tt.want].Hex())
+			}
+		})
+	}
+}
+
+func TestAISessionView(t *testing.T) {
+	session, ids := testTree()
+	leaf := ids["a2b"]
+	session.ActiveLeafID = &leaf
+
+	view := session.View()
+
+	want := map[string][]primitive.ObjectID{
+		"q1":  nil,
+		"a1":  {ids["a1"], ids["a1b"]},
+		"q2b": {ids["q2"], ids["q2b"]},
+		"a2b": nil,
+	}
+	if len(view.Messages) != len(want) {
+		t.Fatalf("view has %d messages, want %d", len(view.Messages), len(want))
+	}
+	for _, msg := range view.Messages {
+		if !reflect.DeepEqual(msg.Alternatives, want[msg.Content]) {
+			t.Errorf("alternatives of %s = %v, want %v", msg.Content, msg.Alternatives, want[msg.Content])
+		}
+	}
+}
+
+func TestAISessionBranchMemory(t *testing.T) {
+	session, ids := testTree()
+	anchor := ids["a1"]
+	session.Memory = "память"
+	session.MemoryUpTo = 2
+	session.MemoryAnchorID = &anchor
+
+	tests := []struct {
+		name     string
+		leaf     string
+		wantUpTo int
+	}{
+		{name: "same prefix", leaf: "a2b", wantUpTo: 2},
+		{name: "other branch", leaf: "a1b", wantUpTo: 0},
+		{name: "branch too short", leaf: "q1", wantUpTo: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaf := ids[tt.leaf]
+			memory, upTo := session.BranchMemory(session.BranchTo(&leaf))
+			if upTo != tt.wantUpTo || (upTo > 0) != (memory != "") {
+				t.Errorf("BranchMemory() = (%q, %d), want up to %d", memory, upTo, tt.wantUpTo)
+			}
+		})
+	}
+}
+
+// Правка первого вопроса и перегенерация ответа на него строят историю до
+// родителя вопроса — она пустая, а память длинной ветки к ней не относится
+func TestAISessionBranchToRoot(t *testing.T) {
+	session, ids := testTree()
+	leaf, anchor := ids["a2"], ids["q2"]
+	session.ActiveLeafID = &leaf
+	session.Memory = "память"
+	session.MemoryUpTo = 3
+	session.MemoryAnchorID = &anchor
+
+	history := *session
+	history.Messages = session.BranchTo(nil)
+	history.ActiveLeafID = nil
+
+	branch := history.Branch()
+	if len(branch) != 0 {
+		t.Fatalf("Branch() of an empty history = %q", contents(branch))
+	}
+	if memory, upTo := history.BranchMemory(branch); memory != "" || upTo != 0 {
+		t.Errorf("BranchMemory() of an empty history = (%q, %d)", memory, upTo)
+	}
+	if got := history.View().Messages; len(got) != 0 {
+		t.Errorf("View() of an empty history has %d messages", len(got))
+	}
+}
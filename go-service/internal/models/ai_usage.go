@@ -0,0 +1,45 @@
+// models/ai_usage.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AIUsageRecord — расход одного запроса к LLM
+type AIUsageRecord struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	SessionID *primitive.ObjectID `bson:"session_id,omitempty" json:"session_id,omitempty"`
+	Operation string              `bson:"operation" json:"operation"`
+	Model     string              `bson:"model" json:"model"`
+
+	PromptTokens     int `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int `bson:"total_tokens" json:"total_tokens"`
+	// Токены посчитаны локально: провайдер не сообщил расход
+	Estimated bool `bson:"estimated,omitempty" json:"estimated,omitempty"`
+	// Стоимость в USD: от провайдера или по таблице цен моделей
+	Cost float64 `bson:"cost" json:"cost"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+type AIUsageTotals struct {
+	Requests         int     `bson:"requests" json:"requests"`
+	PromptTokens     int     `bson:"prompt_tokens" json:"prompt_tokens"`
+	CompletionTokens int     `bson:"completion_tokens" json:"completion_tokens"`
+	TotalTokens      int     `bson:"total_tokens" json:"total_tokens"`
+	Cost             float64 `bson:"cost" json:"cost"`
+}
+
+// AIUsageInfo — расход AI за текущий месяц в аналитике пользователя
+type AIUsageInfo struct {
+	MonthlyTokenLimit      int                      `json:"monthly_token_limit"`
+	MonthlyTokensUsed      int                      `json:"monthly_tokens_used"`
+	MonthlyTokensRemaining int                      `json:"monthly_tokens_remaining"`
+	MonthlyCost            float64                  `json:"monthly_cost"` // USD
+	Requests               int                      `json:"requests"`
+	ByOperation            map[string]AIUsageTotals `json:"by_operation"`
+}
@@ -0,0 +1,45 @@
+// models/collection.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxTagsPerVideo    = 20
+	MaxTagLength       = 50
+	MaxCollectionDepth = 10
+	MaxBulkVideos      = 200
+)
+
+// Collection — пользовательская папка для видео; ParentID == nil у корневых
+type Collection struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID      primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	Name        string              `bson:"name" json:"name"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CollectionNode — коллекция с вложенными коллекциями для отдачи дерева
+type CollectionNode struct {
+	*Collection
+	VideoCount int               `json:"video_count"`
+	Children   []*CollectionNode `json:"children"`
+}
+
+type TagCount struct {
+	Tag   string `bson:"_id" json:"tag"`
+	Count int    `bson:"count" json:"count"`
+}
+
+type BulkResult struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+	// Видео библиотеки, которые не изменены из-за лимита (тегов на видео)
+	Failed []primitive.ObjectID `json:"failed,omitempty"`
+}
@@ -0,0 +1,44 @@
+// models/embedding.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ChunkSourceSummary    = "summary"
+	ChunkSourceTranscript = "transcript"
+)
+
+// ContentChunk — фрагмент саммари или транскрипта видео с вектором эмбеддинга
+type ContentChunk struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VideoID   primitive.ObjectID `bson:"video_id" json:"video_id"`
+	Source    string             `bson:"source" json:"source"`
+	Index     int                `bson:"index" json:"index"`
+	Text      string             `bson:"text" json:"text"`
+	Start     float64            `bson:"start,omitempty" json:"start,omitempty"`
+	End       float64            `bson:"end,omitempty" json:"end,omitempty"`
+	Vector    []float32          `bson:"vector" json:"-"`
+	Model     string             `bson:"model" json:"model"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type SemanticHit struct {
+	VideoID primitive.ObjectID `json:"video_id"`
+	Title   string             `json:"title"`
+	ChunkID primitive.ObjectID `json:"chunk_id"`
+	Source  string             `json:"source"`
+	Text    string             `json:"text"`
+	Start   float64            `json:"start,omitempty"`
+	End     float64            `json:"end,omitempty"`
+	Score   float64            `json:"score"`
+}
+
+type SemanticSearchResult struct {
+	Query string        `json:"query"`
+	Items []SemanticHit `json:"items"`
+}
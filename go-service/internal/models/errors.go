@@ -4,11 +4,21 @@ package models
 import "errors"
 
 var (
-	ErrSessionCreateFailed = errors.New("session create failed")
-	ErrSessionNotFound     = errors.New("session not found")
-	ErrSessionUpdateFailed = errors.New("session update failed")
-	ErrSessionDeleteFailed = errors.New("session delete failed")
-	ErrInvalidMessageRole  = errors.New("invalid message role")
+	ErrSessionCreateFailed   = errors.New("session create failed")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrSessionUpdateFailed   = errors.New("session update failed")
+	ErrSessionDeleteFailed   = errors.New("session delete failed")
+	ErrSessionUpdateConflict = errors.New("session was modified concurrently")
+	ErrInvalidMessageRole    = errors.New("invalid message role")
+	ErrInvalidSessionVideos  = errors.New("invalid session videos")
+	ErrTooManySessionVideos  = errors.New("too many videos in session")
+	ErrMixedSessionVideos    = errors.New("session videos belong to different workspaces")
+	ErrMultiVideoSession     = errors.New("operation is not available for multi-video sessions")
+	ErrMessageNotFound       = errors.New("message not found")
+	ErrMessageNotEditable    = errors.New("only user messages can be edited")
+	ErrMessageNotRegenerable = errors.New("only assistant replies can be regenerated")
+	ErrInvalidSessionTitle   = errors.New("session title must be 1-100 characters")
+	ErrInvalidExportFormat   = errors.New("export format must be md, json or html")
 
 	ErrUserAlreadyExists            = errors.New("user already exists")
 	ErrUserCreateFailed             = errors.New("user create failed")
@@ -17,12 +27,14 @@ var (
 	ErrUserDeleteFailed             = errors.New("user delete failed")
 	ErrInvalidPassword              = errors.New("invalid password")
 	ErrMonthlyAnalysesLimitExceeded = errors.New("monthly analyses limit exceeded")
+	ErrMonthlyAITokensExceeded      = errors.New("monthly AI token limit exceeded")
 	ErrInvalidSubscription          = errors.New("invalid subscription")
 
 	ErrVideoCreateFailed = errors.New("video create failed")
 	ErrVideoNotFound     = errors.New("video not found")
 	ErrVideoUpdateFailed = errors.New("video update failed")
 	ErrVideoDeleteFailed = errors.New("video delete failed")
+	ErrNoVideoContent    = errors.New("video has no transcript or summary yet")
 
 	ErrVideoResultCreateFailed = errors.New("video result create failed")
 	ErrVideoResultNotFound     = errors.New("video result not found")
@@ -31,4 +43,69 @@ var (
 	ErrAIServiceUnavailable = errors.New("AI service unavailable")
 	ErrInvalidAIRequest     = errors.New("invalid AI request")
 	ErrContextTooLong       = errors.New("context too long")
+	ErrPromptNotFound       = errors.New("prompt template not found")
+	ErrPromptTemplate       = errors.New("invalid prompt template")
+	ErrInvalidAIOutput      = errors.New("model returned invalid structured output")
+
+	ErrInvalidCursor    = errors.New("invalid cursor")
+	ErrInvalidSortField = errors.New("invalid sort field")
+	ErrCursorMismatch   = errors.New("cursor was issued for a different sort or order")
+
+	ErrEmptySearchQuery = errors.New("empty search query")
+
+	ErrCollectionCreateFailed = errors.New("collection create failed")
+	ErrCollectionNotFound     = errors.New("collection not found")
+	ErrCollectionUpdateFailed = errors.New("collection update failed")
+	ErrCollectionDeleteFailed = errors.New("collection delete failed")
+	ErrCollectionCycle        = errors.New("collection cannot be moved into itself or its descendant")
+	ErrCollectionTooDeep      = errors.New("collection nesting too deep")
+	ErrInvalidCollectionName  = errors.New("invalid collection name")
+	ErrInvalidTag             = errors.New("invalid tag")
+	ErrTooManyTags            = errors.New("too many tags")
+	ErrTooManyVideos          = errors.New("too many videos in bulk request")
+
+	ErrShareCreateFailed     = errors.New("share create failed")
+	ErrShareNotFound         = errors.New("share not found")
+	ErrShareExpired          = errors.New("share link expired")
+	ErrSharePasswordRequired = errors.New("share password required")
+	ErrInvalidSharePassword  = errors.New("invalid share password")
+	ErrTooManyShareAttempts  = errors.New("too many share password attempts")
+	ErrInvalidShareTTL       = errors.New("invalid share expiry")
+
+	ErrWorkspaceCreateFailed = errors.New("workspace create failed")
+	ErrWorkspaceNotFound     = errors.New("workspace not found")
+	ErrWorkspaceUpdateFailed = errors.New("workspace update failed")
+	ErrWorkspaceAccessDenied = errors.New("workspace access denied")
+	ErrWorkspaceNotEmpty     = errors.New("workspace still has videos")
+	ErrInvalidWorkspaceName  = errors.New("invalid workspace name")
+	ErrInvalidWorkspaceRole  = errors.New("invalid workspace role")
+	ErrMemberNotFound        = errors.New("workspace member not found")
+	ErrAlreadyMember         = errors.New("user is already a workspace member")
+	ErrOwnerCannotLeave      = errors.New("workspace owner cannot leave or change own role")
+	ErrInviteNotFound        = errors.New("invite not found")
+	ErrInviteExpired         = errors.New("invite expired")
+	ErrInvalidInviteEmail    = errors.New("invalid invite email")
+	ErrInviteSendFailed      = errors.New("failed to send invite email")
+
+	ErrSummaryVersionNotFound = errors.New("summary version not found")
+	ErrSummaryVersionConflict = errors.New("summary version conflict")
+	ErrInvalidSummary         = errors.New("invalid summary")
+	ErrSummaryNotReady        = errors.New("video has no summary yet")
+	ErrSummaryJobInProgress   = errors.New("summary refinement already in progress")
+	ErrInvalidImproveFocus    = errors.New("invalid improvement focus")
+	ErrEmptyDialogue          = errors.New("session has no dialogue to summarize")
+
+	ErrQuizNotFound       = errors.New("quiz not found")
+	ErrInvalidQuizRequest = errors.New("invalid quiz size")
+	ErrInsightsNotReady   = errors.New("video has no insights yet")
+
+	ErrTranslationNotFound        = errors.New("translation not found")
+	ErrTranslationInProgress      = errors.New("translation already in progress")
+	ErrTranslationNotReady        = errors.New("translation is not ready yet")
+	ErrInvalidTranslationLanguage = errors.New("invalid translation language")
+	ErrNoTranscriptSegments       = errors.New("video has no timed transcript")
+	ErrInvalidSubtitleFormat      = errors.New("subtitle format must be srt or vtt")
+
+	ErrEmbeddingsUnavailable = errors.New("embeddings provider unavailable")
+	ErrChunkSaveFailed       = errors.New("chunk save failed")
 )
@@ -0,0 +1,43 @@
+// models/insights.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VideoInsights — структурированные выводы по видео, например по записи встречи.
+// Хранятся в видео и пересобираются целиком.
+type VideoInsights struct {
+	KeyPoints     []InsightItem `bson:"key_points" json:"key_points"`
+	ActionItems   []ActionItem  `bson:"action_items" json:"action_items"`
+	Decisions     []InsightItem `bson:"decisions" json:"decisions"`
+	OpenQuestions []InsightItem `bson:"open_questions" json:"open_questions"`
+
+	// Модель, которая фактически выделила выводы
+	Model string `bson:"model,omitempty" json:"model,omitempty"`
+	// Кто запустил извлечение; после обработки видео не задан
+	AuthorID    *primitive.ObjectID `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	GeneratedAt time.Time           `bson:"generated_at" json:"generated_at"`
+}
+
+// InsightItem — тезис, решение или вопрос со ссылкой на сегмент транскрипта
+type InsightItem struct {
+	Text      string   `bson:"text" json:"text"`
+	SegmentID *int     `bson:"segment_id,omitempty" json:"segment_id,omitempty"`
+	Start     *float64 `bson:"start,omitempty" json:"start,omitempty"`
+}
+
+// ActionItem — задача; исполнитель и срок — только если прозвучали в видео
+type ActionItem struct {
+	Task  string `bson:"task" json:"task"`
+	Owner string `bson:"owner,omitempty" json:"owner,omitempty"`
+	// Срок в формате YYYY-MM-DD
+	DueDate   string   `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	SegmentID *int     `bson:"segment_id,omitempty" json:"segment_id,omitempty"`
+	Start     *float64 `bson:"start,omitempty" json:"start,omitempty"`
+}
+
+// Формат срока задачи
+const DueDateLayout = "2006-01-02"
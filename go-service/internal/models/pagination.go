@@ -0,0 +1,60 @@
+// models/pagination.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// PageOptions описывает курсорную пагинацию и сортировку списков
+type PageOptions struct {
+	Cursor    string
+	Limit     int
+	SortBy    string
+	SortOrder string
+}
+
+type VideoFilter struct {
+	Status   string
+	Tag      string
+	Language string
+	From     *time.Time
+	To       *time.Time
+	// Видео, входящие хотя бы в одну из коллекций
+	CollectionIDs []primitive.ObjectID
+	// Если задан, выбираются видео воркспейса вместо личных видео пользователя
+	WorkspaceID *primitive.ObjectID
+}
+
+// SessionFilter: Archived и Pinned — nil означает любые сессии
+type SessionFilter struct {
+	VideoID     primitive.ObjectID
+	From        *time.Time
+	To          *time.Time
+	WorkspaceID *primitive.ObjectID
+	Archived    *bool
+	Pinned      *bool
+}
+
+type VideoPage struct {
+	Items      []*Video `json:"items"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+	// Только на первой странице
+	Total *int64 `json:"total,omitempty"`
+}
+
+type SessionPage struct {
+	Items      []*AISession `json:"items"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+	// Только на первой странице
+	Total *int64 `json:"total,omitempty"`
+}
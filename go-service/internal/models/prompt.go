@@ -0,0 +1,12 @@
+// models/prompt.go
+package models
+
+// PromptTemplateInfo — активная версия шаблона промпта
+type PromptTemplateInfo struct {
+	Name string `json:"name"`
+	// Пустой язык — шаблон по умолчанию
+	Locale  string `json:"locale,omitempty"`
+	Version int    `json:"version"`
+	// builtin — встроенный, override — из каталога PROMPTS_DIR
+	Source string `json:"source"`
+}
@@ -0,0 +1,49 @@
+// models/quiz.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Размер теста по умолчанию и потолок: больше не помещается в ответ модели
+const (
+	DefaultQuizQuestions  = 8
+	MaxQuizQuestions      = 20
+	DefaultQuizFlashcards = 12
+	MaxQuizFlashcards     = 30
+
+	MinQuizOptions = 3
+	MaxQuizOptions = 5
+)
+
+// Quiz — вопросы с вариантами ответа и карточки для повторения, сгенерированные по видео
+type Quiz struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	VideoID  primitive.ObjectID `bson:"video_id" json:"video_id"`
+	AuthorID primitive.ObjectID `bson:"author_id" json:"author_id"`
+	// Модель, которая фактически сгенерировала тест
+	Model      string         `bson:"model,omitempty" json:"model,omitempty"`
+	Questions  []QuizQuestion `bson:"questions" json:"questions"`
+	Flashcards []Flashcard    `bson:"flashcards" json:"flashcards"`
+	CreatedAt  time.Time      `bson:"created_at" json:"created_at"`
+}
+
+// QuizQuestion — вопрос с одним верным вариантом; Answer — его индекс в Options
+type QuizQuestion struct {
+	Question    string   `bson:"question" json:"question"`
+	Options     []string `bson:"options" json:"options"`
+	Answer      int      `bson:"answer" json:"answer"`
+	Explanation string   `bson:"explanation,omitempty" json:"explanation,omitempty"`
+	// Сегмент транскрипта, на котором основан вопрос
+	SegmentID *int     `bson:"segment_id,omitempty" json:"segment_id,omitempty"`
+	Start     *float64 `bson:"start,omitempty" json:"start,omitempty"`
+}
+
+type Flashcard struct {
+	Front     string   `bson:"front" json:"front"`
+	Back      string   `bson:"back" json:"back"`
+	SegmentID *int     `bson:"segment_id,omitempty" json:"segment_id,omitempty"`
+	Start     *float64 `bson:"start,omitempty" json:"start,omitempty"`
+}
@@ -25,3 +25,81 @@ type ChangePasswordRequest struct {
 type ChangeSubscriptionRequest struct {
 	Subscription string `json:"subscription" binding:"required,oneof=free premium business"`
 }
+
+type BulkTagsRequest struct {
+	VideoIDs []string `json:"video_ids"`
+	Tags     []string `json:"tags"`
+}
+
+type BulkVideosRequest struct {
+	VideoIDs []string `json:"video_ids"`
+}
+
+type CreateCollectionRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+}
+
+// UpdateCollectionRequest: пустой ParentID не меняет родителя, "root" делает коллекцию корневой
+type UpdateCollectionRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ParentID    *string `json:"parent_id,omitempty"`
+}
+
+// UpdateSessionRequest — частичное обновление сессии; nil-поля не меняются
+type UpdateSessionRequest struct {
+	Title    *string `json:"title,omitempty"`
+	Pinned   *bool   `json:"pinned,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+}
+
+// CreateShareRequest: ExpiresIn в часах, 0 — без срока действия
+type CreateShareRequest struct {
+	ExpiresIn         int    `json:"expires_in,omitempty"`
+	Password          string `json:"password,omitempty"`
+	IncludeTranscript bool   `json:"include_transcript"`
+}
+
+type CreateWorkspaceRequest struct {
+	Name string `json:"name"`
+}
+
+type UpdateWorkspaceRequest struct {
+	Name      string `json:"name,omitempty"`
+	RedactPII *bool  `json:"redact_pii,omitempty"`
+}
+
+type InviteMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+type AcceptInviteRequest struct {
+	Token string `json:"token"`
+}
+
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type UpdateSummaryRequest struct {
+	Summary string `json:"summary"`
+}
+
+// TranslateVideoRequest — язык перевода: код ISO 639-1, при необходимости с регионом (pt-BR)
+type TranslateVideoRequest struct {
+	Language string `json:"language"`
+}
+
+// GenerateQuizRequest — размер теста; 0 — по умолчанию
+type GenerateQuizRequest struct {
+	Questions  int `json:"questions"`
+	Flashcards int `json:"flashcards"`
+}
+
+type ImproveSummaryRequest struct {
+	// Необязательные акценты доработки
+	Focus []string `json:"focus"`
+}
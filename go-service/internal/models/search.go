@@ -0,0 +1,56 @@
+// models/search.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 50
+)
+
+// ScoredVideo — видео, найденное полнотекстовым поиском, с релевантностью
+type ScoredVideo struct {
+	Video `bson:",inline"`
+	Score float64 `bson:"score"`
+}
+
+// TextRange — позиция подсветки в тексте сниппета (в рунах, end не включается)
+type TextRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+type SearchSnippet struct {
+	Field      string      `json:"field"`
+	Text       string      `json:"text"`
+	Highlights []TextRange `json:"highlights"`
+}
+
+type SegmentMatch struct {
+	SegmentID  int         `json:"segment_id"`
+	Start      float64     `json:"start"`
+	End        float64     `json:"end"`
+	Text       string      `json:"text"`
+	Highlights []TextRange `json:"highlights"`
+}
+
+type SearchHit struct {
+	VideoID   primitive.ObjectID `json:"video_id"`
+	Title     string             `json:"title"`
+	Status    string             `json:"status"`
+	Language  string             `json:"language,omitempty"`
+	Score     float64            `json:"score"`
+	Snippets  []SearchSnippet    `json:"snippets"`
+	Segments  []SegmentMatch     `json:"segments,omitempty"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type SearchResult struct {
+	Query string      `json:"query"`
+	Items []SearchHit `json:"items"`
+	Total int         `json:"total"`
+}
@@ -0,0 +1,42 @@
+// models/share.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Максимальный срок жизни публичной ссылки
+	MaxShareTTL = 365 * 24 * time.Hour
+)
+
+// Share — публичная ссылка на саммари видео для пользователей без аккаунта.
+// В базе хранится только SHA-256 токена; сам токен отдаётся один раз при создании.
+type Share struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Token             string             `bson:"-" json:"token,omitempty"`
+	TokenHash         string             `bson:"token_hash" json:"-"`
+	UserID            primitive.ObjectID `bson:"user_id" json:"user_id"`
+	VideoID           primitive.ObjectID `bson:"video_id" json:"video_id"`
+	PasswordHash      string             `bson:"password_hash,omitempty" json:"-"`
+	HasPassword       bool               `bson:"has_password" json:"has_password"`
+	IncludeTranscript bool               `bson:"include_transcript" json:"include_transcript"`
+	ExpiresAt         *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt         *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ViewCount         int64              `bson:"view_count" json:"view_count"`
+	LastViewedAt      *time.Time         `bson:"last_viewed_at,omitempty" json:"last_viewed_at,omitempty"`
+	CreatedAt         time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// SharedVideo — то, что видит получатель ссылки
+type SharedVideo struct {
+	Title      string              `json:"title"`
+	Summary    string              `json:"summary"`
+	Transcript string              `json:"transcript,omitempty"`
+	Segments   []TranscriptSegment `json:"segments,omitempty"`
+	Language   string              `json:"language,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	ExpiresAt  *time.Time          `json:"expires_at,omitempty"`
+}
@@ -4,26 +4,30 @@ package models
 import "time"
 
 type SubscriptionConfig struct {
-	MonthlyAnalyses int `json:"monthly_analyses"` // Месячный лимит анализов
+	MonthlyAnalyses int `json:"monthly_analyses"`  // Месячный лимит анализов
+	MonthlyAITokens int `json:"monthly_ai_tokens"` // Месячный лимит токенов LLM
 }
 
 type AnalyticsInfo struct {
-	Subscription       string    `json:"subscription"`         // "free" или "premium"
-	MonthlyLimit       int       `json:"monthly_limit"`        // Месячный лимит
-	MonthlyUsed        int       `json:"monthly_used"`         // Использовано в этом месяце
-	MonthlyRemaining   int       `json:"monthly_remaining"`    // Осталось в этом месяце
-	TotalAnalyses      int       `json:"total_analyses"`       // Всего анализов за все время
-	CanPerformAnalysis bool      `json:"can_perform_analysis"` // Может ли выполнить анализ
-	NextReset          time.Time `json:"next_reset"`           // Время следующего сброса
-	UsagePercentage    float64   `json:"usage_percentage"`     // Процент использования
-	CurrentMonth       string    `json:"current_month"`        // Текущий месяц
+	Subscription       string       `json:"subscription"`         // "free" или "premium"
+	MonthlyLimit       int          `json:"monthly_limit"`        // Месячный лимит
+	MonthlyUsed        int          `json:"monthly_used"`         // Использовано в этом месяце
+	MonthlyRemaining   int          `json:"monthly_remaining"`    // Осталось в этом месяце
+	TotalAnalyses      int          `json:"total_analyses"`       // Всего анализов за все время
+	CanPerformAnalysis bool         `json:"can_perform_analysis"` // Может ли выполнить анализ
+	NextReset          time.Time    `json:"next_reset"`           // Время следующего сброса
+	UsagePercentage    float64      `json:"usage_percentage"`     // Процент использования
+	CurrentMonth       string       `json:"current_month"`        // Текущий месяц
+	AIUsage            *AIUsageInfo `json:"ai_usage"`             // Расход токенов LLM за месяц
 }
 
 var SubscriptionLimits = map[string]SubscriptionConfig{
 	"free": {
-		MonthlyAnalyses: 50, // 50 анализов в месяц
+		MonthlyAnalyses: 50,      // 50 анализов в месяц
+		MonthlyAITokens: 200_000, // 200 тыс. токенов в месяц
 	},
 	"premium": {
-		MonthlyAnalyses: 500, // 500 анализов в месяц
+		MonthlyAnalyses: 500,       // 500 анализов в месяц
+		MonthlyAITokens: 5_000_000, // 5 млн токенов в месяц
 	},
 }
@@ -0,0 +1,79 @@
+// models/summary_version.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Источники версий саммари
+const (
+	SummarySourceProcessing = "processing"
+	SummarySourceManual     = "manual"
+	SummarySourceRestore    = "restore"
+	SummarySourceImprove    = "ai_improve"
+	SummarySourceFix        = "ai_fix"
+	SummarySourceDialogue   = "ai_dialogue"
+
+	MaxSummaryLength = 100000
+)
+
+// SummaryVersion — снимок саммари видео; текущая версия дублируется в Video.Summary
+type SummaryVersion struct {
+	ID       primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	VideoID  primitive.ObjectID  `bson:"video_id" json:"video_id"`
+	Version  int                 `bson:"version" json:"version"`
+	Content  string              `bson:"content,omitempty" json:"content,omitempty"`
+	Source   string              `bson:"source" json:"source"`
+	AuthorID *primitive.ObjectID `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	// Для восстановления — номер исходной версии
+	RestoredFrom *int `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+	// Для саммари по диалогу — сессия-источник
+	SessionID *primitive.ObjectID `bson:"session_id,omitempty" json:"session_id,omitempty"`
+	Length    int                 `bson:"length" json:"length"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// Статусы фоновой AI-доработки саммари
+const (
+	SummaryJobProcessing = "processing"
+	SummaryJobCompleted  = "completed"
+	SummaryJobFailed     = "failed"
+
+	MaxImproveFocusItems  = 10
+	MaxImproveFocusLength = 200
+)
+
+// SummaryJob — последняя фоновая AI-операция над саммари; хранится в видео
+type SummaryJob struct {
+	ID        primitive.ObjectID  `bson:"id" json:"id"`
+	VideoID   primitive.ObjectID  `bson:"video_id" json:"video_id"`
+	Source    string              `bson:"source" json:"source"`
+	Status    string              `bson:"status" json:"status"`
+	SessionID *primitive.ObjectID `bson:"session_id,omitempty" json:"session_id,omitempty"`
+	// Номер созданной версии после успешного завершения
+	Version    int        `bson:"version,omitempty" json:"version,omitempty"`
+	Error      string     `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt  time.Time  `bson:"started_at" json:"started_at"`
+	FinishedAt *time.Time `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+type SummaryDiff struct {
+	From       int      `json:"from"`
+	To         int      `json:"to"`
+	Ops        []DiffOp `json:"ops"`
+	Insertions int      `json:"insertions"`
+	Deletions  int      `json:"deletions"`
+}
@@ -0,0 +1,41 @@
+// models/translation.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Статусы перевода
+const (
+	TranslationProcessing = "processing"
+	TranslationCompleted  = "completed"
+	TranslationFailed     = "failed"
+)
+
+// Translation — перевод саммари и транскрипта видео на один язык. Сегменты
+// сохраняют номера и таймкоды оригинала. Хранится по одному на язык и
+// переиспользуется, пока не изменились саммари или транскрипт.
+type Translation struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	VideoID  primitive.ObjectID `bson:"video_id" json:"video_id"`
+	Language string             `bson:"language" json:"language"`
+	// Язык оригинала на момент перевода
+	SourceLanguage string `bson:"source_language,omitempty" json:"source_language,omitempty"`
+	Status         string `bson:"status" json:"status"`
+	Error          string `bson:"error,omitempty" json:"error,omitempty"`
+
+	Summary    string              `bson:"summary,omitempty" json:"summary,omitempty"`
+	Transcript string              `bson:"transcript,omitempty" json:"transcript,omitempty"`
+	Segments   []TranscriptSegment `bson:"segments,omitempty" json:"segments,omitempty"`
+
+	// Хэш саммари и транскрипта, с которых сделан перевод
+	SourceHash string `bson:"source_hash" json:"-"`
+	// Оригинал изменился после перевода; вычисляется при чтении
+	Outdated bool `bson:"-" json:"outdated"`
+
+	RequestedBy primitive.ObjectID `bson:"requested_by" json:"requested_by"`
+	StartedAt   time.Time          `bson:"started_at" json:"started_at"`
+	FinishedAt  *time.Time         `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
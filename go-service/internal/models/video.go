@@ -6,13 +6,51 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// LibraryID — библиотека, в которой лежат видео: воркспейс или личная библиотека пользователя
+func LibraryID(userID primitive.ObjectID, workspaceID *primitive.ObjectID) primitive.ObjectID {
+	if workspaceID != nil {
+		return *workspaceID
+	}
+	return userID
+}
+
 type Video struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
-	Title     string             `bson:"title" json:"title"`
-	URL       string             `bson:"url" json:"url"`
-	Status    string             `bson:"status" json:"status"`
-	Summary   string             `bson:"summary,omitempty" json:"summary,omitempty"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	Title      string              `bson:"title" json:"title"`
+	URL        string              `bson:"url" json:"url"`
+	Status     string              `bson:"status" json:"status"`
+	Summary    string              `bson:"summary,omitempty" json:"summary,omitempty"`
+	Transcript string              `bson:"transcript,omitempty" json:"transcript,omitempty"`
+	Segments   []TranscriptSegment `bson:"segments,omitempty" json:"segments,omitempty"`
+	Language   string              `bson:"language,omitempty" json:"language,omitempty"`
+	Tags       []string            `bson:"tags,omitempty" json:"tags,omitempty"`
+
+	SuggestedTags []string             `bson:"suggested_tags,omitempty" json:"suggested_tags,omitempty"`
+	CollectionIDs []primitive.ObjectID `bson:"collection_ids,omitempty" json:"collection_ids,omitempty"`
+
+	// Воркспейс-владелец; у личных видео не задан, UserID — загрузивший
+	WorkspaceID *primitive.ObjectID `bson:"workspace_id,omitempty" json:"workspace_id,omitempty"`
+	// Библиотека видео — воркспейс или автор личного видео; ключ индексов и поиска
+	LibraryID primitive.ObjectID `bson:"library_id" json:"-"`
+
+	// Последняя AI-доработка саммари (improve, fix, по диалогу)
+	SummaryJob *SummaryJob `bson:"summary_job,omitempty" json:"summary_job,omitempty"`
+
+	// Ключевые тезисы, задачи, решения и открытые вопросы
+	Insights *VideoInsights `bson:"insights,omitempty" json:"insights,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+
+	// Язык стемминга для полнотекстового индекса (language_override)
+	SearchLanguage string `bson:"search_language,omitempty" json:"-"`
+}
+
+// TranscriptSegment — фрагмент транскрипции с таймкодами в секундах
+type TranscriptSegment struct {
+	ID    int     `bson:"id" json:"id"`
+	Start float64 `bson:"start" json:"start"`
+	End   float64 `bson:"end" json:"end"`
+	Text  string  `bson:"text" json:"text"`
 }
@@ -0,0 +1,78 @@
+// models/workspace.go
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Роли участников воркспейса, по возрастанию прав
+const (
+	WorkspaceRoleViewer = "viewer"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleOwner  = "owner"
+
+	WorkspaceInviteTTL = 7 * 24 * time.Hour
+)
+
+// Workspace — общая библиотека команды со своим тарифом и общей квотой анализов
+type Workspace struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name    string             `bson:"name" json:"name"`
+	OwnerID primitive.ObjectID `bson:"owner_id" json:"owner_id"`
+
+	// Общая квота на всех участников; тариф меняется только через биллинг
+	Subscription        string    `bson:"subscription" json:"subscription"`
+	AnalysesCount       int       `bson:"analyses_count" json:"analyses_count"`
+	MonthlyAnalysesUsed int       `bson:"monthly_analyses_used" json:"monthly_analyses_used"`
+	LastResetMonth      int       `bson:"last_reset_month" json:"-"`
+	LastResetYear       int       `bson:"last_reset_year" json:"-"`
+	LastAnalysisDate    time.Time `bson:"last_analysis_date,omitempty" json:"-"`
+
+	// Удалять персональные данные перед отправкой в LLM; nil — по настройке сервера
+	RedactPII *bool `bson:"redact_pii,omitempty" json:"redact_pii,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+type WorkspaceMember struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role        string             `bson:"role" json:"role"`
+	JoinedAt    time.Time          `bson:"joined_at" json:"joined_at"`
+}
+
+// WorkspaceInvite — приглашение на email; принимается одноразовым токеном из письма
+// и только пользователем с этим email
+type WorkspaceInvite struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	WorkspaceID primitive.ObjectID `bson:"workspace_id" json:"workspace_id"`
+	Email       string             `bson:"email" json:"email"`
+	// SHA-256 токена; сам токен есть только в письме
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	Role       string             `bson:"role" json:"role"`
+	InvitedBy  primitive.ObjectID `bson:"invited_by" json:"invited_by"`
+	ExpiresAt  time.Time          `bson:"expires_at" json:"expires_at"`
+	AcceptedAt *time.Time         `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+
+	// Заполняется при выдаче приглашений пользователю
+	WorkspaceName string `bson:"-" json:"workspace_name,omitempty"`
+}
+
+// WorkspaceSummary — воркспейс вместе с ролью текущего пользователя
+type WorkspaceSummary struct {
+	*Workspace
+	Role string `json:"role"`
+}
+
+type WorkspaceMemberInfo struct {
+	UserID   primitive.ObjectID `json:"user_id"`
+	Name     string             `json:"name"`
+	Email    string             `json:"email"`
+	Role     string             `json:"role"`
+	JoinedAt time.Time          `json:"joined_at"`
+}
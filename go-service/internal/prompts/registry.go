@@ -0,0 +1,245 @@
+// prompts/registry.go
+package prompts
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+// Встроенные шаблоны: templates/<name>.v<N>.tmpl — для всех языков,
+// templates/<locale>/<name>.v<N>.tmpl — для конкретного языка
+//
+//go:embed templates
+var builtin embed.FS
+
+const (
+	SourceBuiltin  = "builtin"
+	SourceOverride = "override"
+)
+
+var fileNamePattern = regexp.MustCompile(`^([a-z0-9_]+)\.v(\d+)\.tmpl$`)
+
+var funcs = template.FuncMap{
+	"join": func(elems []string, sep string) string {
+		return strings.Join(elems, sep)
+	},
+}
+
+// Template — разобранный шаблон промпта. Файл определяет блоки
+// {{define "system"}} и {{define "user"}}; отсутствующий блок даёт пустую строку.
+type Template struct {
+	Name    string
+	Locale  string
+	Version int
+	Source  string
+	tmpl    *template.Template
+}
+
+// Prompt — отрендеренные системное и пользовательское сообщения
+type Prompt struct {
+	System string
+	User   string
+}
+
+// Registry хранит активную версию каждого шаблона для каждого языка
+type Registry struct {
+	templates map[string]map[string]*Template // имя → язык ("" — по умолчанию) → шаблон
+}
+
+// NewRegistry загружает встроенные шаблоны и, если задан overrideDir, шаблоны
+// из него. Шаблон из каталога заменяет встроенный с тем же именем и языком;
+// из нескольких версий одного шаблона в источнике активна старшая.
+func NewRegistry(overrideDir string) (*Registry, error) {
+	r := &Registry{templates: make(map[string]map[string]*Template)}
+
+	embedded, err := fs.Sub(builtin, "templates")
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(embedded, SourceBuiltin); err != nil {
+		return nil, err
+	}
+
+	if overrideDir != "" {
+		info, err := os.Stat(overrideDir)
+		if err != nil {
+			return nil, fmt.Errorf("prompt templates directory: %w", err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("prompt templates directory: %s is not a directory", overrideDir)
+		}
+		if err := r.load(os.DirFS(overrideDir), SourceOverride); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Registry) load(fsys fs.FS, source string) error {
+	loaded := make(map[string]map[string]*Template)
+
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			// Поддерживаются только файлы в корне и в каталогах языков
+			if p != "." && strings.Contains(p, "/") {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		m := fileNamePattern.FindStringSubmatch(d.Name())
+		if m == nil {
+			return nil
+		}
+		version, _ := strconv.Atoi(m[2])
+		locale := normalizeLocale(path.Dir(p))
+		if locale == "." {
+			locale = ""
+		}
+
+		if current := loaded[m[1]][locale]; current != nil && current.Version >= version {
+			return nil
+		}
+
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(p).Funcs(funcs).Option("missingkey=error").Parse(string(content))
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", models.ErrPromptTemplate, p, err)
+		}
+
+		if loaded[m[1]] == nil {
+			loaded[m[1]] = make(map[string]*Template)
+		}
+		loaded[m[1]][locale] = &Template{
+			Name:    m[1],
+			Locale:  locale,
+			Version: version,
+			Source:  source,
+			tmpl:    tmpl,
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for name, locales := range loaded {
+		if r.templates[name] == nil {
+			r.templates[name] = make(map[string]*Template)
+		}
+		for locale, t := range locales {
+			r.templates[name][locale] = t
+		}
+	}
+	return nil
+}
+
+// Lookup находит шаблон для языка: сначала точное совпадение (pt-br),
+// затем базовый язык (pt), затем шаблон по умолчанию
+func (r *Registry) Lookup(name, locale string) (*Template, error) {
+	locales := r.templates[name]
+	locale = normalizeLocale(locale)
+	for locale != "" {
+		if t := locales[locale]; t != nil {
+			return t, nil
+		}
+		base, _, found := strings.Cut(locale, "-")
+		if !found {
+			break
+		}
+		locale = base
+	}
+
+	if t := locales[""]; t != nil {
+		return t, nil
+	}
+	return nil, fmt.Errorf("%w: %s", models.ErrPromptNotFound, name)
+}
+
+// Render выбирает шаблон по языку из контекста и рендерит его блоки
+func (r *Registry) Render(ctx context.Context, name string, data any) (Prompt, error) {
+	t, err := r.Lookup(name, LocaleFromContext(ctx))
+	if err != nil {
+		return Prompt{}, err
+	}
+
+	system, err := t.execute("system", data)
+	if err != nil {
+		return Prompt{}, err
+	}
+	user, err := t.execute("user", data)
+	if err != nil {
+		return Prompt{}, err
+	}
+	return Prompt{System: system, User: user}, nil
+}
+
+func (t *Template) execute(block string, data any) (string, error) {
+	if t.tmpl.Lookup(block) == nil {
+		return "", nil
+	}
+
+	var out strings.Builder
+	if err := t.tmpl.ExecuteTemplate(&out, block, data); err != nil {
+		return "", fmt.Errorf("%w: %s v%d: %v", models.ErrPromptTemplate, t.Name, t.Version, err)
+	}
+	return strings.TrimSpace(out.String()), nil
+}
+
+// Active перечисляет активные шаблоны, отсортированные по имени и языку
+func (r *Registry) Active() []models.PromptTemplateInfo {
+	var active []models.PromptTemplateInfo
+	for _, locales := range r.templates {
+		for _, t := range locales {
+			active = append(active, models.PromptTemplateInfo{
+				Name:    t.Name,
+				Locale:  t.Locale,
+				Version: t.Version,
+				Source:  t.Source,
+			})
+		}
+	}
+
+	sort.Slice(active, func(i, j int) bool {
+		if active[i].Name != active[j].Name {
+			return active[i].Name < active[j].Name
+		}
+		return active[i].Locale < active[j].Locale
+	})
+	return active
+}
+
+// normalizeLocale приводит язык к виду pt-br
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+type localeKey struct{}
+
+// WithLocale задаёт язык, по которому выбираются шаблоны промптов
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
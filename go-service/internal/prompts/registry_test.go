@@ -0,0 +1,180 @@
+package prompts
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func templateFile(user string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(`{{define "user"}}` + user + `{{end}}`)}
+}
+
+func newTestRegistry(t *testing.T, fsys fstest.MapFS) *Registry {
+	t.Helper()
+	r := &Registry{templates: make(map[string]map[string]*Template)}
+	if err := r.load(fsys, SourceBuiltin); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	return r
+}
+
+func TestRegistryLookupLocale(t *testing.T) {
+	r := newTestRegistry(t, fstest.MapFS{
+		"greet.v1.tmpl":       templateFile("default"),
+		"pt/greet.v1.tmpl":    templateFile("pt"),
+		"pt-br/greet.v1.tmpl": templateFile("pt-br"),
+		"ru/only_ru.v1.tmpl":  templateFile("ru"),
+	})
+
+	tests := []struct {
+		name       string
+		template   string
+		locale     string
+		wantLocale string
+		wantErr    error
+	}{
+		{name: "exact", template: "greet", locale: "pt-br", wantLocale: "pt-br"},
+		{name: "normalized", template: "greet", locale: " PT_BR ", wantLocale: "pt-br"},
+		{name: "base language", template: "greet", locale: "pt-pt", wantLocale: "pt"},
+		{name: "default", template: "greet", locale: "de-at", wantLocale: ""},
+		{name: "no locale", template: "greet", locale: "", wantLocale: ""},
+		{name: "locale only", template: "only_ru", locale: "ru-ru", wantLocale: "ru"},
+		{name: "no default", template: "only_ru", locale: "en", wantErr: models.ErrPromptNotFound},
+		{name: "unknown", template: "missing", locale: "ru", wantErr: models.ErrPromptNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := r.Lookup(tt.template, tt.locale)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Lookup() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got.Locale != tt.wantLocale {
+				t.Errorf("Lookup() locale = %q, want %q", got.Locale, tt.wantLocale)
+			}
+		})
+	}
+}
+
+func TestRegistryLoadVersions(t *testing.T) {
+	r := newTestRegistry(t, fstest.MapFS{
+		"greet.v1.tmpl":     templateFile("v1"),
+		"greet.v10.tmpl":    templateFile("v10"),
+		"greet.v2.tmpl":     templateFile("v2"),
+		"ru/greet.v3.tmpl":  templateFile("ru v3"),
+		"notes.txt":         {Data: []byte("не шаблон")},
+		"a/b/greet.v9.tmpl": templateFile("слишком глубоко"),
+	})
+
+	tests := []struct {
+		locale      string
+		wantVersion int
+		wantUser    string
+	}{
+		{locale: "", wantVersion: 10, wantUser: "v10"},
+		{locale: "ru", wantVersion: 3, wantUser: "ru v3"},
+	}
+
+	for _, tt := range tests {
+		got, err := r.Lookup("greet", tt.locale)
+		if err != nil {
+			t.Fatalf("Lookup(%q) error = %v", tt.locale, err)
+		}
+		if got.Version != tt.wantVersion {
+			t.Errorf("Lookup(%q) version = %d, want %d", tt.locale, got.Version, tt.wantVersion)
+		}
+		prompt, err := r.Render(WithLocale(context.Background(), tt.locale), "greet", nil)
+		if err != nil || prompt.User != tt.wantUser || prompt.System != "" {
+			t.Errorf("Render(%q) = %+v, %v, want user %q", tt.locale, prompt, err, tt.wantUser)
+		}
+	}
+
+	if _, ok := r.templates["greet"]["a"]; ok {
+		t.Error("templates below locale directories must be skipped")
+	}
+}
+
+func TestRegistryOverride(t *testing.T) {
+	r := newTestRegistry(t, fstest.MapFS{
+		"greet.v2.tmpl": templateFile("builtin"),
+		"other.v1.tmpl": templateFile("other"),
+	})
+	// Шаблон из каталога заменяет встроенный, даже если его версия младше
+	if err := r.load(fstest.MapFS{"greet.v1.tmpl": templateFile("override")}, SourceOverride); err != nil {
+		t.Fatalf("load(override) error = %v", err)
+	}
+
+	got, err := r.Lookup("greet", "")
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if got.Source != SourceOverride || got.Version != 1 {
+		t.Errorf("greet = %s v%d, want override v1", got.Source, got.Version)
+	}
+	if other, _ := r.Lookup("other", ""); other == nil || other.Source != SourceBuiltin {
+		t.Error("templates missing from the override directory must stay built-in")
+	}
+}
+
+func TestNewRegistryOverrideDir(t *testing.T) {
+	dir := t.TempDir()
+	content := `{{define "user"}}Заголовок: {{.Question}}{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "session_title.v1.tmpl"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := NewRegistry(dir)
+	if err != nil {
+		t.Fatalf("NewRegistry() error = %v", err)
+	}
+	prompt, err := r.Render(context.Background(), "session_title", struct{ Question string }{"Что решили?"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if prompt.User != "Заголовок: Что решили?" || prompt.System != "" {
+		t.Errorf("Render() = %+v, want the override template", prompt)
+	}
+
+	if _, err := NewRegistry(filepath.Join(dir, "missing")); err == nil {
+		t.Error("NewRegistry() with a missing directory must fail")
+	}
+}
+
+func TestRegistryMissingKey(t *testing.T) {
+	r := newTestRegistry(t, fstest.MapFS{
+		"greet.v1.tmpl": {Data: []byte(`{{define "system"}}Hi {{.Name}}{{end}}{{define "user"}}{{.Question}}{{end}}`)},
+	})
+
+	tests := []struct {
+		name    string
+		data    any
+		wantErr error
+	}{
+		{name: "all keys", data: map[string]string{"Name": "Аня", "Question": "Как дела?"}},
+		{name: "missing map key", data: map[string]string{"Name": "Аня"}, wantErr: models.ErrPromptTemplate},
+		{name: "missing field", data: struct{ Name string }{"Аня"}, wantErr: models.ErrPromptTemplate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Render(context.Background(), "greet", tt.data)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Render() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegistryParseError(t *testing.T) {
+	r := &Registry{templates: make(map[string]map[string]*Template)}
+	err := r.load(fstest.MapFS{"broken.v1.tmpl": {Data: []byte(`{{define "user"}}{{.Question`)}}, SourceBuiltin)
+	if !errors.Is(err, models.ErrPromptTemplate) {
+		t.Errorf("load() error = %v, want ErrPromptTemplate", err)
+	}
+}
@@ -9,17 +9,30 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type AISessionRepository interface {
 	Create(ctx context.Context, session *models.AISession) (primitive.ObjectID, error)
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.AISession, error)
 	GetByVideoID(ctx context.Context, videoID primitive.ObjectID) ([]*models.AISession, error)
-	GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.AISession, error)
-	AddMessage(ctx context.Context, sessionID primitive.ObjectID, message models.AIMessage) error
-	UpdateTitle(ctx context.Context, sessionID primitive.ObjectID, title string) error
+	List(ctx context.Context, userID primitive.ObjectID, filter models.SessionFilter, page models.PageOptions) (*models.SessionPage, error)
+	// AddMessage присваивает сообщению ID и делает его концом активной ветки
+	AddMessage(ctx context.Context, sessionID primitive.ObjectID, message *models.AIMessage) error
+	SetActiveLeaf(ctx context.Context, sessionID, leafID primitive.ObjectID) error
+	Update(ctx context.Context, sessionID primitive.ObjectID, req models.UpdateSessionRequest) error
+	// SetTitleIfEmpty задаёт название, только если его ещё нет: название от пользователя не перезаписывается
+	SetTitleIfEmpty(ctx context.Context, sessionID primitive.ObjectID, title string) error
+	UpdateMemory(ctx context.Context, sessionID primitive.ObjectID, memory string, upTo int, anchorID primitive.ObjectID, prevUpTo int) error
 	//	UpdateSummary(ctx context.Context, sessionID primitive.ObjectID, summary string) error
 	Delete(ctx context.Context, sessionID primitive.ObjectID) error
+	DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+var sessionSortFields = map[string]string{
+	"created_at": "created_at",
+	"title":      "title",
 }
 
 type aiSessionRepository struct {
@@ -34,6 +47,7 @@ func NewAISessionRepository(db *mongo.Database) AISessionRepository {
 
 func (r *aiSessionRepository) Create(ctx context.Context, session *models.AISession) (primitive.ObjectID, error) {
 	session.CreatedAt = time.Now()
+	linkMessages(session)
 
 	result, err := r.collection.InsertOne(ctx, session)
 	if err != nil {
@@ -60,13 +74,79 @@ func (r *aiSessionRepository) GetByID(ctx context.Context, id primitive.ObjectID
 		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
 
+	if err := r.ensureMessageTree(ctx, &session); err != nil {
+		return nil, err
+	}
+
 	return &session, nil
 }
 
+// linkMessages выстраивает сообщения без ID в одну ветку по порядку;
+// false — сообщения уже связаны
+func linkMessages(session *models.AISession) bool {
+	if session.ActiveLeafID != nil || len(session.Messages) == 0 {
+		return false
+	}
+
+	var parent *primitive.ObjectID
+	for i := range session.Messages {
+		msg := &session.Messages[i]
+		if msg.ID.IsZero() {
+			msg.ID = primitive.NewObjectID()
+		}
+		msg.ParentID = parent
+		id := msg.ID
+		parent = &id
+	}
+	session.ActiveLeafID = parent
+
+	if session.MemoryUpTo > 0 && session.MemoryUpTo <= len(session.Messages) {
+		anchor := session.Messages[session.MemoryUpTo-1].ID
+		session.MemoryAnchorID = &anchor
+	}
+	return true
+}
+
+// ensureMessageTree переводит сессии, созданные до ветвления диалога, на дерево сообщений
+func (r *aiSessionRepository) ensureMessageTree(ctx context.Context, session *models.AISession) error {
+	if !linkMessages(session) {
+		return nil
+	}
+
+	set := bson.M{
+		"messages":       session.Messages,
+		"active_leaf_id": session.ActiveLeafID,
+	}
+	if session.MemoryAnchorID != nil {
+		set["memory_anchor_id"] = session.MemoryAnchorID
+	}
+
+	// Если сессию успели изменить, дерево уже построено параллельным запросом или
+	// добавлено новое сообщение — перечитываем
+	filter := bson.M{
+		"_id":            session.ID,
+		"active_leaf_id": bson.M{"$exists": false},
+		"messages":       bson.M{"$size": len(session.Messages)},
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrSessionUpdateFailed, err)
+	}
+	if result.MatchedCount == 0 {
+		fresh, err := r.GetByID(ctx, session.ID)
+		if err != nil {
+			return err
+		}
+		*session = *fresh
+	}
+
+	return nil
+}
+
 func (r *aiSessionRepository) GetByVideoID(ctx context.Context, videoID primitive.ObjectID) ([]*models.AISession, error) {
 	var sessions []*models.AISession
 
-	cursor, err := r.collection.Find(ctx, bson.M{"video_id": videoID})
+	cursor, err := r.collection.Find(ctx, bson.M{"$or": sessionVideoClauses(videoID)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to find sessions: %w", err)
 	}
@@ -79,33 +159,63 @@ func (r *aiSessionRepository) GetByVideoID(ctx context.Context, videoID primitiv
 	return sessions, nil
 }
 
-func (r *aiSessionRepository) GetByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.AISession, error) {
-	var sessions []*models.AISession
+// List отдаёт сессии без сообщений и памяти: дерево диалога отдаётся только по одной сессии
+func (r *aiSessionRepository) List(ctx context.Context, userID primitive.ObjectID, filter models.SessionFilter, page models.PageOptions) (*models.SessionPage, error) {
+	projection := options.Find().SetProjection(bson.M{"messages": 0, "memory": 0})
 
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+	sessions, nextCursor, total, err := findPage[models.AISession](ctx, r.collection, sessionFilterQuery(userID, filter), page, sessionSortFields, projection)
 	if err != nil {
-		return nil, fmt.Errorf("failed to find sessions: %w", err)
+		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	if err := cursor.All(ctx, &sessions); err != nil {
-		return nil, fmt.Errorf("failed to decode sessions: %w", err)
+	return &models.SessionPage{
+		Items:      sessions,
+		NextCursor: nextCursor,
+		Total:      total,
+	}, nil
+}
+
+// sessionFilterQuery выбирает сессии библиотеки, как videoFilterQuery: воркспейса
+// filter.WorkspaceID или личные сессии пользователя, без созданных им в воркспейсах
+func sessionFilterQuery(userID primitive.ObjectID, filter models.SessionFilter) bson.M {
+	query := bson.M{"user_id": userID, "workspace_id": bson.M{"$exists": false}}
+	if filter.WorkspaceID != nil {
+		query = bson.M{"workspace_id": *filter.WorkspaceID}
+	}
+	if !filter.VideoID.IsZero() {
+		query["$or"] = sessionVideoClauses(filter.VideoID)
 	}
+	applyDateRange(query, "created_at", filter.From, filter.To)
+	applyFlag(query, "archived", filter.Archived)
+	applyFlag(query, "pinned", filter.Pinned)
+	return query
+}
 
-	return sessions, nil
+// sessionVideoClauses находит сессии, в которых обсуждается видео, в том числе среди нескольких
+func sessionVideoClauses(videoID primitive.ObjectID) bson.A {
+	return bson.A{
+		bson.M{"video_id": videoID},
+		bson.M{"video_ids": videoID},
+	}
 }
 
-func (r *aiSessionRepository) AddMessage(ctx context.Context, sessionID primitive.ObjectID, message models.AIMessage) error {
+func (r *aiSessionRepository) AddMessage(ctx context.Context, sessionID primitive.ObjectID, message *models.AIMessage) error {
 	message.Time = time.Now()
 
 	if message.Role == "" {
 		return models.ErrInvalidMessageRole
 	}
+	if message.ID.IsZero() {
+		message.ID = primitive.NewObjectID()
+	}
 
 	update := bson.M{
 		"$push": bson.M{
 			"messages": message,
 		},
+		"$set": bson.M{
+			"active_leaf_id": message.ID,
+		},
 	}
 
 	result, err := r.collection.UpdateByID(ctx, sessionID, update)
@@ -120,14 +230,66 @@ func (r *aiSessionRepository) AddMessage(ctx context.Context, sessionID primitiv
 	return nil
 }
 
-func (r *aiSessionRepository) UpdateTitle(ctx context.Context, sessionID primitive.ObjectID, title string) error {
+func (r *aiSessionRepository) SetActiveLeaf(ctx context.Context, sessionID, leafID primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": sessionID, "messages.id": leafID},
+		bson.M{"$set": bson.M{"active_leaf_id": leafID}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrSessionUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// UpdateMemory сохраняет память, только если её не обновили параллельно (memory_up_to == prevUpTo);
+// anchorID — последнее свёрнутое сообщение ветки
+func (r *aiSessionRepository) UpdateMemory(ctx context.Context, sessionID primitive.ObjectID, memory string, upTo int, anchorID primitive.ObjectID, prevUpTo int) error {
+	filter := bson.M{"_id": sessionID, "memory_up_to": prevUpTo}
+	if prevUpTo == 0 {
+		filter = bson.M{"_id": sessionID, "memory_up_to": bson.M{"$in": bson.A{0, nil}}}
+	}
+
 	update := bson.M{
 		"$set": bson.M{
-			"title": title,
+			"memory":           memory,
+			"memory_up_to":     upTo,
+			"memory_anchor_id": anchorID,
 		},
 	}
 
-	result, err := r.collection.UpdateByID(ctx, sessionID, update)
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrSessionUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrSessionUpdateConflict
+	}
+
+	return nil
+}
+
+func (r *aiSessionRepository) Update(ctx context.Context, sessionID primitive.ObjectID, req models.UpdateSessionRequest) error {
+	set := bson.M{}
+	if req.Title != nil {
+		set["title"] = *req.Title
+	}
+	if req.Pinned != nil {
+		set["pinned"] = *req.Pinned
+	}
+	if req.Archived != nil {
+		set["archived"] = *req.Archived
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	result, err := r.collection.UpdateByID(ctx, sessionID, bson.M{"$set": set})
 	if err != nil {
 		return fmt.Errorf("%w: %v", models.ErrSessionUpdateFailed, err)
 	}
@@ -139,6 +301,17 @@ func (r *aiSessionRepository) UpdateTitle(ctx context.Context, sessionID primiti
 	return nil
 }
 
+func (r *aiSessionRepository) SetTitleIfEmpty(ctx context.Context, sessionID primitive.ObjectID, title string) error {
+	filter := bson.M{"_id": sessionID, "title": bson.M{"$in": bson.A{"", nil}}}
+
+	_, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"title": title}})
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrSessionUpdateFailed, err)
+	}
+
+	return nil
+}
+
 func (r *aiSessionRepository) Delete(ctx context.Context, sessionID primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": sessionID})
 	if err != nil {
@@ -151,3 +324,31 @@ func (r *aiSessionRepository) Delete(ctx context.Context, sessionID primitive.Ob
 
 	return nil
 }
+
+func (r *aiSessionRepository) DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"workspace_id": workspaceID}); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrSessionDeleteFailed, err)
+	}
+	return nil
+}
+
+func (r *aiSessionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "video_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "video_id", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "video_ids", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"video_ids": bson.M{"$exists": true}}),
+		},
+		{
+			Keys:    bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"workspace_id": bson.M{"$exists": true}}),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create session indexes: %w", err)
+	}
+	return nil
+}
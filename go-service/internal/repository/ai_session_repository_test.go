@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestLinkMessages(t *testing.T) {
+	leaf := primitive.NewObjectID()
+
+	tests := []struct {
+		name       string
+		session    *models.AISession
+		wantLinked bool
+	}{
+		{name: "empty", session: &models.AISession{}, wantLinked: false},
+		{
+			name:       "already a tree",
+			session:    &models.AISession{Messages: []models.AIMessage{{ID: leaf}}, ActiveLeafID: &leaf},
+			wantLinked: false,
+		},
+		{
+			name: "legacy list",
+			session: &models.AISession{
+				Messages:   []models.AIMessage{{Role: "user"}, {Role: "assistant"}, {Role: "user"}},
+				MemoryUpTo: 2,
+			},
+			wantLinked: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if linked := linkMessages(tt.session); linked != tt.wantLinked {
+				t.Fatalf("linkMessages() = %v, want %v", linked, tt.wantLinked)
+			}
+			if !tt.wantLinked {
+				return
+			}
+
+			msgs := tt.session.Messages
+			for i, msg := range msgs {
+				if msg.ID.IsZero() {
+					t.Errorf("message %d has no ID", i)
+				}
+				switch {
+				case i == 0 && msg.ParentID != nil:
+					t.Errorf("first message has parent %s", msg.ParentID.Hex())
+				case i > 0 && (msg.ParentID == nil || *msg.ParentID != msgs[i-1].ID):
+					t.Errorf("message %d is not linked to the previous one", i)
+				}
+			}
+			if tt.session.ActiveLeafID == nil || *tt.session.ActiveLeafID != msgs[len(msgs)-1].ID {
+				t.Error("active leaf is not the last message")
+			}
+			if tt.session.MemoryAnchorID == nil || *tt.session.MemoryAnchorID != msgs[tt.session.MemoryUpTo-1].ID {
+				t.Error("memory anchor is not the last folded message")
+			}
+			if got := len(tt.session.Branch()); got != len(msgs) {
+				t.Errorf("branch has %d messages, want %d", got, len(msgs))
+			}
+		})
+	}
+}
+
+func TestSessionFilterQuery(t *testing.T) {
+	userID := primitive.NewObjectID()
+	workspaceID := primitive.NewObjectID()
+	videoID := primitive.NewObjectID()
+	archived := false
+
+	tests := []struct {
+		name   string
+		filter models.SessionFilter
+		want   bson.M
+	}{
+		{
+			name:   "personal library excludes workspace sessions",
+			filter: models.SessionFilter{},
+			want:   bson.M{"user_id": userID, "workspace_id": bson.M{"$exists": false}},
+		},
+		{
+			name:   "workspace library includes other members",
+			filter: models.SessionFilter{WorkspaceID: &workspaceID},
+			want:   bson.M{"workspace_id": workspaceID},
+		},
+		{
+			name:   "video and flags",
+			filter: models.SessionFilter{VideoID: videoID, Archived: &archived},
+			want: bson.M{
+				"user_id":      userID,
+				"workspace_id": bson.M{"$exists": false},
+				"$or":          bson.A{bson.M{"video_id": videoID}, bson.M{"video_ids": videoID}},
+				"archived":     bson.M{"$ne": true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sessionFilterQuery(userID, tt.filter); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sessionFilterQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
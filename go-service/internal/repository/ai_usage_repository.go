@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type AIUsageRepository interface {
+	Create(ctx context.Context, record *models.AIUsageRecord) error
+	// TotalsByOperation суммирует расход пользователя с момента since по операциям
+	TotalsByOperation(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]models.AIUsageTotals, error)
+	EnsureIndexes(ctx context.Context) error
+}
+
+type aiUsageRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAIUsageRepository(db *mongo.Database) AIUsageRepository {
+	return &aiUsageRepository{
+		collection: db.Collection("ai_usage"),
+	}
+}
+
+func (r *aiUsageRepository) Create(ctx context.Context, record *models.AIUsageRecord) error {
+	if record.CreatedAt.IsZero() {
+		record.CreatedAt = time.Now()
+	}
+
+	result, err := r.collection.InsertOne(ctx, record)
+	if err != nil {
+		return fmt.Errorf("failed to save AI usage: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		record.ID = id
+	}
+	return nil
+}
+
+func (r *aiUsageRepository) TotalsByOperation(ctx context.Context, userID primitive.ObjectID, since time.Time) (map[string]models.AIUsageTotals, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": userID, "created_at": bson.M{"$gte": since}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":               "$operation",
+			"requests":          bson.M{"$sum": 1},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"total_tokens":      bson.M{"$sum": "$total_tokens"},
+			"cost":              bson.M{"$sum": "$cost"},
+		}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate AI usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Operation            string `bson:"_id"`
+		models.AIUsageTotals `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode AI usage: %w", err)
+	}
+
+	totals := make(map[string]models.AIUsageTotals, len(rows))
+	for _, row := range rows {
+		totals[row.Operation] = row.AIUsageTotals
+	}
+	return totals, nil
+}
+
+func (r *aiUsageRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "session_id", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create AI usage indexes: %w", err)
+	}
+	return nil
+}
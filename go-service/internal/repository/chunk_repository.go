@@ -0,0 +1,101 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type ChunkRepository interface {
+	ReplaceForVideo(ctx context.Context, videoID primitive.ObjectID, chunks []*models.ContentChunk) error
+	GetByVideos(ctx context.Context, videoIDs []primitive.ObjectID) ([]*models.ContentChunk, error)
+	GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.ContentChunk, error)
+	DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type chunkRepository struct {
+	collection *mongo.Collection
+}
+
+func NewChunkRepository(db *mongo.Database) ChunkRepository {
+	return &chunkRepository{
+		collection: db.Collection("video_chunks"),
+	}
+}
+
+func (r *chunkRepository) ReplaceForVideo(ctx context.Context, videoID primitive.ObjectID, chunks []*models.ContentChunk) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID}); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrChunkSaveFailed, err)
+	}
+
+	if len(chunks) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]any, 0, len(chunks))
+	for _, chunk := range chunks {
+		if chunk.ID.IsZero() {
+			chunk.ID = primitive.NewObjectID()
+		}
+		chunk.VideoID = videoID
+		chunk.CreatedAt = now
+		docs = append(docs, chunk)
+	}
+
+	if _, err := r.collection.InsertMany(ctx, docs); err != nil {
+		return fmt.Errorf("%w: %v", models.ErrChunkSaveFailed, err)
+	}
+
+	return nil
+}
+
+func (r *chunkRepository) GetByVideos(ctx context.Context, videoIDs []primitive.ObjectID) ([]*models.ContentChunk, error) {
+	if len(videoIDs) == 0 {
+		return nil, nil
+	}
+	return r.find(ctx, bson.M{"video_id": bson.M{"$in": videoIDs}})
+}
+
+func (r *chunkRepository) GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.ContentChunk, error) {
+	return r.find(ctx, bson.M{"video_id": videoID})
+}
+
+func (r *chunkRepository) find(ctx context.Context, filter bson.M) ([]*models.ContentChunk, error) {
+	var chunks []*models.ContentChunk
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find chunks: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &chunks); err != nil {
+		return nil, fmt.Errorf("failed to decode chunks: %w", err)
+	}
+
+	return chunks, nil
+}
+
+func (r *chunkRepository) DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID}); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+func (r *chunkRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "source", Value: 1}, {Key: "index", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create chunk indexes: %w", err)
+	}
+	return nil
+}
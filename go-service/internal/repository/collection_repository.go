@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type CollectionRepository interface {
+	Create(ctx context.Context, collection *models.Collection) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Collection, error)
+	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Collection, error)
+	Update(ctx context.Context, collection *models.Collection) error
+	DeleteMany(ctx context.Context, ids []primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type collectionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewCollectionRepository(db *mongo.Database) CollectionRepository {
+	return &collectionRepository{
+		collection: db.Collection("collections"),
+	}
+}
+
+func (r *collectionRepository) Create(ctx context.Context, collection *models.Collection) (primitive.ObjectID, error) {
+	collection.CreatedAt = time.Now()
+	collection.UpdatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, collection)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %v", models.ErrCollectionCreateFailed, err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("%w: failed to convert inserted ID", models.ErrCollectionCreateFailed)
+	}
+
+	collection.ID = insertedID
+	return insertedID, nil
+}
+
+func (r *collectionRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Collection, error) {
+	var collection models.Collection
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&collection)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrCollectionNotFound
+		}
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+
+	return &collection, nil
+}
+
+func (r *collectionRepository) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Collection, error) {
+	var collections []*models.Collection
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find collections: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &collections); err != nil {
+		return nil, fmt.Errorf("failed to decode collections: %w", err)
+	}
+
+	return collections, nil
+}
+
+func (r *collectionRepository) Update(ctx context.Context, collection *models.Collection) error {
+	collection.UpdatedAt = time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":        collection.Name,
+			"description": collection.Description,
+			"parent_id":   collection.ParentID,
+			"updated_at":  collection.UpdatedAt,
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, collection.ID, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrCollectionUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrCollectionNotFound
+	}
+
+	return nil
+}
+
+func (r *collectionRepository) DeleteMany(ctx context.Context, ids []primitive.ObjectID) error {
+	result, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrCollectionDeleteFailed, err)
+	}
+
+	if result.DeletedCount == 0 {
+		return models.ErrCollectionNotFound
+	}
+
+	return nil
+}
+
+func (r *collectionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "name", Value: 1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create collection indexes: %w", err)
+	}
+	return nil
+}
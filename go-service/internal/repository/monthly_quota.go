@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// recordMonthlyAnalysis атомарно учитывает анализ в документе с месячным счётчиком
+// (пользователь или воркспейс), только если лимит не исчерпан.
+// Первый анализ нового месяца заодно сбрасывает счётчик.
+func recordMonthlyAnalysis(ctx context.Context, collection *mongo.Collection, id primitive.ObjectID, monthlyLimit int, updateFailed, notFound error) error {
+	if monthlyLimit <= 0 {
+		return models.ErrMonthlyAnalysesLimitExceeded
+	}
+
+	now := time.Now()
+	month, year := int(now.Month()), now.Year()
+
+	result, err := collection.UpdateOne(ctx, bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"last_reset_month": bson.M{"$ne": month}},
+			{"last_reset_year": bson.M{"$ne": year}},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"monthly_analyses_used": 1,
+			"last_reset_month":      month,
+			"last_reset_year":       year,
+			"last_analysis_date":    now,
+		},
+		"$inc": bson.M{"analyses_count": 1},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", updateFailed, err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	result, err = collection.UpdateOne(ctx, bson.M{
+		"_id":                   id,
+		"last_reset_month":      month,
+		"last_reset_year":       year,
+		"monthly_analyses_used": bson.M{"$lt": monthlyLimit},
+	}, bson.M{
+		"$set": bson.M{"last_analysis_date": now},
+		"$inc": bson.M{"monthly_analyses_used": 1, "analyses_count": 1},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", updateFailed, err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+
+	exists, err := collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("%w: %v", updateFailed, err)
+	}
+	if exists == 0 {
+		return notFound
+	}
+	return models.ErrMonthlyAnalysesLimitExceeded
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// pageCursor хранит значение поля сортировки и _id последнего документа страницы.
+// _id используется как tie-breaker, чтобы пагинация была стабильной при равных значениях.
+// Поле и направление сортировки сохраняются, чтобы курсор нельзя было применить к другой сортировке.
+type pageCursor struct {
+	Field string             `bson:"f"`
+	Order int                `bson:"o"`
+	Value any                `bson:"v"`
+	ID    primitive.ObjectID `bson:"id"`
+}
+
+func encodeCursor(field string, order int, value any, id primitive.ObjectID) (string, error) {
+	raw, err := bson.Marshal(pageCursor{Field: field, Order: order, Value: value, ID: id})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func decodeCursor(cursor string) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, models.ErrInvalidCursor
+	}
+
+	var c pageCursor
+	if err := bson.Unmarshal(raw, &c); err != nil || c.ID.IsZero() || c.Field == "" {
+		return nil, models.ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func normalizePage(page models.PageOptions, sortFields map[string]string) (field string, order int, limit int, err error) {
+	field = "created_at"
+	if page.SortBy != "" {
+		var ok bool
+		if field, ok = sortFields[page.SortBy]; !ok {
+			return "", 0, 0, models.ErrInvalidSortField
+		}
+	}
+
+	order = -1
+	if page.SortOrder == models.SortAsc {
+		order = 1
+	}
+
+	limit = page.Limit
+	if limit <= 0 {
+		limit = models.DefaultPageLimit
+	}
+	if limit > models.MaxPageLimit {
+		limit = models.MaxPageLimit
+	}
+
+	return field, order, limit, nil
+}
+
+// afterCursor — условие «документ идёт после курсора» при сортировке по field и _id.
+// Документы без поля (null) MongoDB ставит в начало по возрастанию и в конец по убыванию,
+// а сравнения $gt/$lt с null их не находят, поэтому блок null обрабатывается отдельно.
+func afterCursor(field string, order int, value any, id primitive.ObjectID) bson.M {
+	cmp := "$lt"
+	if order == 1 {
+		cmp = "$gt"
+	}
+
+	if value == nil {
+		inNullBlock := bson.M{field: nil, "_id": bson.M{cmp: id}}
+		if order == 1 {
+			return bson.M{"$or": bson.A{inNullBlock, bson.M{field: bson.M{"$ne": nil}}}}
+		}
+		return inNullBlock
+	}
+
+	after := bson.A{
+		bson.M{field: bson.M{cmp: value}},
+		bson.M{field: value, "_id": bson.M{cmp: id}},
+	}
+	if order == -1 {
+		after = append(after, bson.M{field: nil})
+	}
+	return bson.M{"$or": after}
+}
+
+// findPage выполняет курсорную выборку из коллекции и возвращает документы страницы
+// и курсор следующей страницы. Общее количество документов по фильтру считается
+// только для первой страницы, для следующих возвращается nil.
+func findPage[T any](
+	ctx context.Context,
+	collection *mongo.Collection,
+	filter bson.M,
+	page models.PageOptions,
+	sortFields map[string]string,
+	findOpts ...*options.FindOptions,
+) ([]*T, string, *int64, error) {
+	field, order, limit, err := normalizePage(page, sortFields)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var total *int64
+	query := filter
+	if page.Cursor == "" {
+		count, err := collection.CountDocuments(ctx, filter)
+		if err != nil {
+			return nil, "", nil, fmt.Errorf("failed to count documents: %w", err)
+		}
+		total = &count
+	} else {
+		c, err := decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if c.Field != field || c.Order != order {
+			return nil, "", nil, models.ErrCursorMismatch
+		}
+
+		query = bson.M{"$and": bson.A{filter, afterCursor(field, order, c.Value, c.ID)}}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}).
+		SetLimit(int64(limit + 1))
+
+	cursor, err := collection.Find(ctx, query, append([]*options.FindOptions{opts}, findOpts...)...)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to find documents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var raws []bson.Raw
+	if err := cursor.All(ctx, &raws); err != nil {
+		return nil, "", nil, fmt.Errorf("failed to read documents: %w", err)
+	}
+
+	hasMore := len(raws) > limit
+	if hasMore {
+		raws = raws[:limit]
+	}
+
+	items := make([]*T, 0, len(raws))
+	for _, raw := range raws {
+		item := new(T)
+		if err := bson.Unmarshal(raw, item); err != nil {
+			return nil, "", nil, fmt.Errorf("failed to decode document: %w", err)
+		}
+		items = append(items, item)
+	}
+
+	nextCursor := ""
+	if hasMore {
+		last := raws[len(raws)-1]
+
+		var value any
+		if rv, err := last.LookupErr(field); err == nil {
+			if err := rv.Unmarshal(&value); err != nil {
+				return nil, "", nil, fmt.Errorf("failed to build cursor: %w", err)
+			}
+		}
+
+		id, ok := last.Lookup("_id").ObjectIDOK()
+		if !ok {
+			return nil, "", nil, fmt.Errorf("failed to build cursor: missing _id")
+		}
+
+		if nextCursor, err = encodeCursor(field, order, value, id); err != nil {
+			return nil, "", nil, fmt.Errorf("failed to build cursor: %w", err)
+		}
+	}
+
+	return items, nextCursor, total, nil
+}
+
+func applyDateRange(filter bson.M, field string, from, to *time.Time) {
+	if from == nil && to == nil {
+		return
+	}
+
+	rng := bson.M{}
+	if from != nil {
+		rng["$gte"] = *from
+	}
+	if to != nil {
+		rng["$lt"] = *to
+	}
+	filter[field] = rng
+}
+
+// applyFlag фильтрует по булеву полю; false совпадает и с отсутствующим полем
+func applyFlag(filter bson.M, field string, value *bool) {
+	if value == nil {
+		return
+	}
+	if *value {
+		filter[field] = true
+	} else {
+		filter[field] = bson.M{"$ne": true}
+	}
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := primitive.NewObjectID()
+	createdAt := primitive.NewDateTimeFromTime(time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC))
+
+	tests := []struct {
+		name  string
+		field string
+		order int
+		value any
+	}{
+		{name: "date desc", field: "created_at", order: -1, value: createdAt},
+		{name: "string asc", field: "title", order: 1, value: "Планёрка"},
+		{name: "int", field: "message_count", order: -1, value: int32(42)},
+		{name: "missing value", field: "updated_at", order: 1, value: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := encodeCursor(tt.field, tt.order, tt.value, id)
+			if err != nil {
+				t.Fatalf("encodeCursor: %v", err)
+			}
+
+			c, err := decodeCursor(encoded)
+			if err != nil {
+				t.Fatalf("decodeCursor: %v", err)
+			}
+			if c.Field != tt.field || c.Order != tt.order || c.ID != id {
+				t.Errorf("got field=%q order=%d id=%s, want field=%q order=%d id=%s",
+					c.Field, c.Order, c.ID.Hex(), tt.field, tt.order, id.Hex())
+			}
+			if c.Value != tt.value {
+				t.Errorf("value = %#v, want %#v", c.Value, tt.value)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	marshal := func(v any) string {
+		raw, err := bson.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(raw)
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{name: "not base64", cursor: "!!!"},
+		{name: "not bson", cursor: base64.RawURLEncoding.EncodeToString([]byte("hello"))},
+		{name: "missing id", cursor: marshal(bson.M{"f": "created_at", "o": -1, "v": "x"})},
+		{name: "missing field", cursor: marshal(bson.M{"o": -1, "v": "x", "id": primitive.NewObjectID()})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeCursor(tt.cursor); !errors.Is(err, models.ErrInvalidCursor) {
+				t.Errorf("decodeCursor() error = %v, want %v", err, models.ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestNormalizePage(t *testing.T) {
+	sortFields := map[string]string{"created_at": "created_at", "title": "title"}
+
+	tests := []struct {
+		name      string
+		page      models.PageOptions
+		wantField string
+		wantOrder int
+		wantLimit int
+		wantErr   error
+	}{
+		{name: "defaults", page: models.PageOptions{}, wantField: "created_at", wantOrder: -1, wantLimit: models.DefaultPageLimit},
+		{name: "asc by title", page: models.PageOptions{SortBy: "title", SortOrder: models.SortAsc, Limit: 5}, wantField: "title", wantOrder: 1, wantLimit: 5},
+		{name: "limit capped", page: models.PageOptions{Limit: models.MaxPageLimit + 1}, wantField: "created_at", wantOrder: -1, wantLimit: models.MaxPageLimit},
+		{name: "unknown field", page: models.PageOptions{SortBy: "password"}, wantErr: models.ErrInvalidSortField},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, order, limit, err := normalizePage(tt.page, sortFields)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if field != tt.wantField || order != tt.wantOrder || limit != tt.wantLimit {
+				t.Errorf("got (%q, %d, %d), want (%q, %d, %d)", field, order, limit, tt.wantField, tt.wantOrder, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestAfterCursor(t *testing.T) {
+	id := primitive.NewObjectID()
+	title := "Планёрка"
+
+	tests := []struct {
+		name  string
+		order int
+		value any
+		want  bson.M
+	}{
+		{
+			name:  "asc value",
+			order: 1,
+			value: title,
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": bson.M{"$gt": title}},
+				bson.M{"title": title, "_id": bson.M{"$gt": id}},
+			}},
+		},
+		{
+			name:  "desc value reaches null block",
+			order: -1,
+			value: title,
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": bson.M{"$lt": title}},
+				bson.M{"title": title, "_id": bson.M{"$lt": id}},
+				bson.M{"title": nil},
+			}},
+		},
+		{
+			name:  "asc null leaves null block",
+			order: 1,
+			value: nil,
+			want: bson.M{"$or": bson.A{
+				bson.M{"title": nil, "_id": bson.M{"$gt": id}},
+				bson.M{"title": bson.M{"$ne": nil}},
+			}},
+		},
+		{
+			name:  "desc null stays in null block",
+			order: -1,
+			value: nil,
+			want:  bson.M{"title": nil, "_id": bson.M{"$lt": id}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := afterCursor("title", tt.order, tt.value, id); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("afterCursor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type QuizRepository interface {
+	Create(ctx context.Context, quiz *models.Quiz) error
+	GetByID(ctx context.Context, videoID, quizID primitive.ObjectID) (*models.Quiz, error)
+	// GetByVideo возвращает тесты видео от новых к старым
+	GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.Quiz, error)
+	DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type quizRepository struct {
+	collection *mongo.Collection
+}
+
+func NewQuizRepository(db *mongo.Database) QuizRepository {
+	return &quizRepository{
+		collection: db.Collection("quizzes"),
+	}
+}
+
+func (r *quizRepository) Create(ctx context.Context, quiz *models.Quiz) error {
+	quiz.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, quiz)
+	if err != nil {
+		return fmt.Errorf("failed to create quiz: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		quiz.ID = id
+	}
+	return nil
+}
+
+func (r *quizRepository) GetByID(ctx context.Context, videoID, quizID primitive.ObjectID) (*models.Quiz, error) {
+	var quiz models.Quiz
+
+	err := r.collection.FindOne(ctx, bson.M{"_id": quizID, "video_id": videoID}).Decode(&quiz)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrQuizNotFound
+		}
+		return nil, fmt.Errorf("failed to get quiz: %w", err)
+	}
+
+	return &quiz, nil
+}
+
+func (r *quizRepository) GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.Quiz, error) {
+	quizzes := []*models.Quiz{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"video_id": videoID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find quizzes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &quizzes); err != nil {
+		return nil, fmt.Errorf("failed to decode quizzes: %w", err)
+	}
+
+	return quizzes, nil
+}
+
+func (r *quizRepository) DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID}); err != nil {
+		return fmt.Errorf("failed to delete quizzes: %w", err)
+	}
+	return nil
+}
+
+func (r *quizRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create quiz indexes: %w", err)
+	}
+	return nil
+}
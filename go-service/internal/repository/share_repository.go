@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/pkg/password"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ShareRepository interface {
+	Create(ctx context.Context, share *models.Share) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Share, error)
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.Share, error)
+	GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.Share, error)
+	Revoke(ctx context.Context, id primitive.ObjectID) error
+	DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error
+	RecordView(ctx context.Context, id primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type shareRepository struct {
+	collection *mongo.Collection
+}
+
+func NewShareRepository(db *mongo.Database) ShareRepository {
+	return &shareRepository{
+		collection: db.Collection("shares"),
+	}
+}
+
+func (r *shareRepository) Create(ctx context.Context, share *models.Share) (primitive.ObjectID, error) {
+	share.CreatedAt = time.Now()
+
+	result, err := r.collection.InsertOne(ctx, share)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %v", models.ErrShareCreateFailed, err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("%w: failed to convert inserted ID", models.ErrShareCreateFailed)
+	}
+
+	share.ID = insertedID
+	return insertedID, nil
+}
+
+func (r *shareRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Share, error) {
+	return r.findOne(ctx, bson.M{"_id": id})
+}
+
+func (r *shareRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Share, error) {
+	return r.findOne(ctx, bson.M{"token_hash": tokenHash})
+}
+
+func (r *shareRepository) findOne(ctx context.Context, filter bson.M) (*models.Share, error) {
+	var share models.Share
+
+	err := r.collection.FindOne(ctx, filter).Decode(&share)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrShareNotFound
+		}
+		return nil, fmt.Errorf("failed to get share: %w", err)
+	}
+
+	return &share, nil
+}
+
+func (r *shareRepository) GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.Share, error) {
+	shares := []*models.Share{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"video_id": videoID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find shares: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, fmt.Errorf("failed to decode shares: %w", err)
+	}
+
+	return shares, nil
+}
+
+func (r *shareRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke share: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrShareNotFound
+	}
+
+	return nil
+}
+
+func (r *shareRepository) DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID}); err != nil {
+		return fmt.Errorf("failed to delete shares: %w", err)
+	}
+	return nil
+}
+
+func (r *shareRepository) RecordView(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateByID(ctx, id, bson.M{
+		"$inc": bson.M{"view_count": 1},
+		"$set": bson.M{"last_viewed_at": time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record share view: %w", err)
+	}
+	return nil
+}
+
+func (r *shareRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.hashPlainTokens(ctx); err != nil {
+		return err
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create share indexes: %w", err)
+	}
+	return nil
+}
+
+// hashPlainTokens переводит ссылки, созданные до хеширования токенов, на token_hash
+// и удаляет старый уникальный индекс по token: без поля он считал бы все ссылки дубликатами
+func (r *shareRepository) hashPlainTokens(ctx context.Context) error {
+	cursor, err := r.collection.Find(ctx, bson.M{"token": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"token": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find plaintext share tokens: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID    primitive.ObjectID `bson:"_id"`
+			Token string             `bson:"token"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return fmt.Errorf("failed to decode share token: %w", err)
+		}
+		_, err := r.collection.UpdateByID(ctx, doc.ID, bson.M{
+			"$set":   bson.M{"token_hash": password.HashToken(doc.Token)},
+			"$unset": bson.M{"token": ""},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to hash share token: %w", err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return fmt.Errorf("failed to iterate share tokens: %w", err)
+	}
+
+	if _, err := r.collection.Indexes().DropOne(ctx, "token_1"); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("failed to drop share token index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Повторы при гонке за номер версии
+const versionInsertRetries = 3
+
+type SummaryVersionRepository interface {
+	// Create присваивает версии следующий номер для видео
+	Create(ctx context.Context, version *models.SummaryVersion) error
+	// CreateBaseline сохраняет версию 1, если у видео ещё нет версий
+	CreateBaseline(ctx context.Context, version *models.SummaryVersion) error
+	GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.SummaryVersion, error)
+	GetVersion(ctx context.Context, videoID primitive.ObjectID, version int) (*models.SummaryVersion, error)
+	CountByVideo(ctx context.Context, videoID primitive.ObjectID) (int64, error)
+	DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type summaryVersionRepository struct {
+	collection *mongo.Collection
+}
+
+func NewSummaryVersionRepository(db *mongo.Database) SummaryVersionRepository {
+	return &summaryVersionRepository{
+		collection: db.Collection("summary_versions"),
+	}
+}
+
+func (r *summaryVersionRepository) Create(ctx context.Context, version *models.SummaryVersion) error {
+	version.CreatedAt = time.Now()
+	version.Length = utf8.RuneCountInString(version.Content)
+
+	for i := 0; i < versionInsertRetries; i++ {
+		last, err := r.lastVersion(ctx, version.VideoID)
+		if err != nil {
+			return err
+		}
+		version.Version = last + 1
+
+		result, err := r.collection.InsertOne(ctx, version)
+		if err != nil {
+			if mongo.IsDuplicateKeyError(err) {
+				continue
+			}
+			return fmt.Errorf("failed to create summary version: %w", err)
+		}
+
+		if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+			version.ID = id
+		}
+		return nil
+	}
+
+	return models.ErrSummaryVersionConflict
+}
+
+// CreateBaseline не считает версии заранее: при параллельных вызовах уникальный
+// индекс пропускает только первую вставку, остальные ничего не делают
+func (r *summaryVersionRepository) CreateBaseline(ctx context.Context, version *models.SummaryVersion) error {
+	version.CreatedAt = time.Now()
+	version.Length = utf8.RuneCountInString(version.Content)
+	version.Version = 1
+
+	result, err := r.collection.InsertOne(ctx, version)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to create summary version: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		version.ID = id
+	}
+	return nil
+}
+
+func (r *summaryVersionRepository) lastVersion(ctx context.Context, videoID primitive.ObjectID) (int, error) {
+	var last struct {
+		Version int `bson:"version"`
+	}
+
+	opts := options.FindOne().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"version": 1})
+	err := r.collection.FindOne(ctx, bson.M{"video_id": videoID}, opts).Decode(&last)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to get last summary version: %w", err)
+	}
+
+	return last.Version, nil
+}
+
+// GetByVideo возвращает версии без текста, от новых к старым
+func (r *summaryVersionRepository) GetByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.SummaryVersion, error) {
+	versions := []*models.SummaryVersion{}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetProjection(bson.M{"content": 0})
+	cursor, err := r.collection.Find(ctx, bson.M{"video_id": videoID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find summary versions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, fmt.Errorf("failed to decode summary versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (r *summaryVersionRepository) GetVersion(ctx context.Context, videoID primitive.ObjectID, version int) (*models.SummaryVersion, error) {
+	var v models.SummaryVersion
+
+	err := r.collection.FindOne(ctx, bson.M{"video_id": videoID, "version": version}).Decode(&v)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrSummaryVersionNotFound
+		}
+		return nil, fmt.Errorf("failed to get summary version: %w", err)
+	}
+
+	return &v, nil
+}
+
+func (r *summaryVersionRepository) CountByVideo(ctx context.Context, videoID primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"video_id": videoID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count summary versions: %w", err)
+	}
+	return count, nil
+}
+
+func (r *summaryVersionRepository) DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID}); err != nil {
+		return fmt.Errorf("failed to delete summary versions: %w", err)
+	}
+	return nil
+}
+
+func (r *summaryVersionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "version", Value: -1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create summary version indexes: %w", err)
+	}
+	return nil
+}
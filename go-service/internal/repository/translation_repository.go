@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TranslationRepository interface {
+	// Start ставит перевод на язык в работу: создаёт запись или перезапускает
+	// завершённую, неудачную или зависшую (начатую до staleBefore). Прежний текст
+	// остаётся до завершения. Если перевод уже идёт — ErrTranslationInProgress.
+	Start(ctx context.Context, translation *models.Translation, staleBefore time.Time) error
+	Complete(ctx context.Context, id primitive.ObjectID, summary, transcript string, segments []models.TranscriptSegment) error
+	Fail(ctx context.Context, id primitive.ObjectID, errMsg string) error
+	Get(ctx context.Context, videoID primitive.ObjectID, language string) (*models.Translation, error)
+	// ListByVideo возвращает переводы видео без текста
+	ListByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.Translation, error)
+	DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+type translationRepository struct {
+	collection *mongo.Collection
+}
+
+func NewTranslationRepository(db *mongo.Database) TranslationRepository {
+	return &translationRepository{
+		collection: db.Collection("translations"),
+	}
+}
+
+func (r *translationRepository) Start(ctx context.Context, translation *models.Translation, staleBefore time.Time) error {
+	filter := bson.M{
+		"video_id": translation.VideoID,
+		"language": translation.Language,
+		"$or": []bson.M{
+			{"status": bson.M{"$ne": models.TranslationProcessing}},
+			{"started_at": bson.M{"$lt": staleBefore}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"source_language": translation.SourceLanguage,
+			"status":          models.TranslationProcessing,
+			"source_hash":     translation.SourceHash,
+			"requested_by":    translation.RequestedBy,
+			"started_at":      translation.StartedAt,
+		},
+		"$unset": bson.M{"error": "", "finished_at": ""},
+	}
+
+	// Идущий перевод не совпадает с фильтром, и вставка упирается в уникальный индекс
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var saved models.Translation
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrTranslationInProgress
+		}
+		return fmt.Errorf("failed to start translation: %w", err)
+	}
+
+	*translation = saved
+	return nil
+}
+
+func (r *translationRepository) Complete(ctx context.Context, id primitive.ObjectID, summary, transcript string, segments []models.TranscriptSegment) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.TranslationCompleted,
+			"summary":     summary,
+			"transcript":  transcript,
+			"segments":    segments,
+			"finished_at": time.Now(),
+		},
+	}
+
+	if _, err := r.collection.UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to complete translation: %w", err)
+	}
+	return nil
+}
+
+func (r *translationRepository) Fail(ctx context.Context, id primitive.ObjectID, errMsg string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"status":      models.TranslationFailed,
+			"error":       errMsg,
+			"finished_at": time.Now(),
+		},
+	}
+
+	if _, err := r.collection.UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to update translation: %w", err)
+	}
+	return nil
+}
+
+func (r *translationRepository) Get(ctx context.Context, videoID primitive.ObjectID, language string) (*models.Translation, error) {
+	var translation models.Translation
+
+	err := r.collection.FindOne(ctx, bson.M{"video_id": videoID, "language": language}).Decode(&translation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrTranslationNotFound
+		}
+		return nil, fmt.Errorf("failed to get translation: %w", err)
+	}
+
+	return &translation, nil
+}
+
+func (r *translationRepository) ListByVideo(ctx context.Context, videoID primitive.ObjectID) ([]*models.Translation, error) {
+	translations := []*models.Translation{}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "language", Value: 1}}).
+		SetProjection(bson.M{"summary": 0, "transcript": 0, "segments": 0})
+	cursor, err := r.collection.Find(ctx, bson.M{"video_id": videoID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find translations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &translations); err != nil {
+		return nil, fmt.Errorf("failed to decode translations: %w", err)
+	}
+
+	return translations, nil
+}
+
+func (r *translationRepository) DeleteByVideo(ctx context.Context, videoID primitive.ObjectID) error {
+	if _, err := r.collection.DeleteMany(ctx, bson.M{"video_id": videoID}); err != nil {
+		return fmt.Errorf("failed to delete translations: %w", err)
+	}
+	return nil
+}
+
+func (r *translationRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "video_id", Value: 1}, {Key: "language", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create translation indexes: %w", err)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ type UserRepository interface {
 	GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error)
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	UpdateUser(ctx context.Context, user *models.User) error
+	RecordAnalysis(ctx context.Context, id primitive.ObjectID, monthlyLimit int) error
 	DeleteUser(ctx context.Context, id primitive.ObjectID) error
 	UserExists(ctx context.Context, email string) (bool, error)
 }
@@ -36,7 +37,7 @@ func (r *userRepository) CreateUser(ctx context.Context, user *models.User) (pri
 		user.Subscription = "free"
 	}
 	if user.Role == "" {
-		user.Role = "user"
+		user.Role = models.UserRoleUser
 	}
 
 	result, err := r.collection.InsertOne(ctx, user)
@@ -112,6 +113,11 @@ func (r *userRepository) UpdateUser(ctx context.Context, user *models.User) erro
 	return nil
 }
 
+// RecordAnalysis атомарно учитывает анализ, только если месячный лимит не исчерпан
+func (r *userRepository) RecordAnalysis(ctx context.Context, id primitive.ObjectID, monthlyLimit int) error {
+	return recordMonthlyAnalysis(ctx, r.collection, id, monthlyLimit, models.ErrUserUpdateFailed, models.ErrUserNotFound)
+}
+
 func (r *userRepository) DeleteUser(ctx context.Context, id primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	if err != nil {
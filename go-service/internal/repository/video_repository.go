@@ -3,6 +3,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -10,15 +11,39 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type VideoRepository interface {
 	Create(ctx context.Context, video *models.Video) (primitive.ObjectID, error)
 	UpdateStatus(ctx context.Context, id primitive.ObjectID, status string) error
 	UpdateSummary(ctx context.Context, id primitive.ObjectID, summary string) error
+	UpdateTranscript(ctx context.Context, id primitive.ObjectID, transcript string, segments []models.TranscriptSegment, language string) error
 	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Video, error)
-	GetByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Video, error)
+	List(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter, page models.PageOptions) (*models.VideoPage, error)
+	ListIDs(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter) ([]primitive.ObjectID, error)
+	Search(ctx context.Context, userID primitive.ObjectID, query, language string, filter models.VideoFilter, limit int) ([]*models.ScoredVideo, error)
+	AddTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error)
+	RemoveTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error)
+	UpdateSuggestedTags(ctx context.Context, id primitive.ObjectID, tags []string) error
+	UpdateInsights(ctx context.Context, id primitive.ObjectID, insights *models.VideoInsights) error
+	StartSummaryJob(ctx context.Context, id primitive.ObjectID, job *models.SummaryJob, staleBefore time.Time) error
+	FinishSummaryJob(ctx context.Context, id, jobID primitive.ObjectID, status string, version int, errMsg string) error
+	// ListTags и CountByCollection считают видео личной библиотеки или воркспейса workspaceID
+	ListTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]models.TagCount, error)
+	AddToCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, collectionID primitive.ObjectID) (*models.BulkResult, error)
+	RemoveFromCollections(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, collectionIDs []primitive.ObjectID) (*models.BulkResult, error)
+	DetachCollections(ctx context.Context, collectionIDs []primitive.ObjectID) error
+	CountByCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) (map[primitive.ObjectID]int, error)
 	Delete(ctx context.Context, videoID primitive.ObjectID) error
+	EnsureIndexes(ctx context.Context) error
+}
+
+var videoSortFields = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"title":      "title",
+	"status":     "status",
 }
 
 type videoRepository struct {
@@ -35,6 +60,7 @@ func (r *videoRepository) Create(ctx context.Context, video *models.Video) (prim
 	video.CreatedAt = time.Now()
 	video.UpdatedAt = time.Now()
 	video.Status = "uploaded"
+	video.LibraryID = models.LibraryID(video.UserID, video.WorkspaceID)
 
 	result, err := r.collection.InsertOne(ctx, video)
 	if err != nil {
@@ -90,6 +116,29 @@ func (r *videoRepository) UpdateSummary(ctx context.Context, id primitive.Object
 	return nil
 }
 
+func (r *videoRepository) UpdateTranscript(ctx context.Context, id primitive.ObjectID, transcript string, segments []models.TranscriptSegment, language string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"transcript":      transcript,
+			"segments":        segments,
+			"language":        language,
+			"search_language": textSearchLanguage(language),
+			"updated_at":      time.Now(),
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, id, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrVideoNotFound
+	}
+
+	return nil
+}
+
 func (r *videoRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Video, error) {
 	var video models.Video
 
@@ -104,15 +153,69 @@ func (r *videoRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*
 	return &video, nil
 }
 
-func (r *videoRepository) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Video, error) {
-	var videos []*models.Video
+func (r *videoRepository) List(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter, page models.PageOptions) (*models.VideoPage, error) {
+	query := videoFilterQuery(userID, filter)
+
+	// Транскрипт не нужен в списке и может быть большим
+	projection := options.Find().SetProjection(bson.M{"transcript": 0, "segments": 0})
+
+	videos, nextCursor, total, err := findPage[models.Video](ctx, r.collection, query, page, videoSortFields, projection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.VideoPage{
+		Items:      videos,
+		NextCursor: nextCursor,
+		Total:      total,
+	}, nil
+}
+
+func (r *videoRepository) ListIDs(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter) ([]primitive.ObjectID, error) {
+	return r.findIDs(ctx, videoFilterQuery(userID, filter))
+}
 
-	cursor, err := r.collection.Find(ctx, bson.M{"user_id": userID})
+func (r *videoRepository) findIDs(ctx context.Context, query bson.M) ([]primitive.ObjectID, error) {
+	opts := options.Find().SetProjection(bson.M{"_id": 1})
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find videos: %w", err)
 	}
 	defer cursor.Close(ctx)
 
+	var docs []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode videos: %w", err)
+	}
+
+	ids := make([]primitive.ObjectID, len(docs))
+	for i, d := range docs {
+		ids[i] = d.ID
+	}
+	return ids, nil
+}
+
+func (r *videoRepository) Search(ctx context.Context, userID primitive.ObjectID, query, language string, filter models.VideoFilter, limit int) ([]*models.ScoredVideo, error) {
+	q := videoFilterQuery(userID, filter)
+	q["$text"] = bson.M{
+		"$search":   query,
+		"$language": textSearchLanguage(language),
+	}
+
+	opts := options.Find().
+		SetProjection(bson.M{"score": bson.M{"$meta": "textScore"}}).
+		SetSort(bson.D{{Key: "score", Value: bson.M{"$meta": "textScore"}}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.collection.Find(ctx, q, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search videos: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var videos []*models.ScoredVideo
 	if err := cursor.All(ctx, &videos); err != nil {
 		return nil, fmt.Errorf("failed to decode videos: %w", err)
 	}
@@ -120,6 +223,222 @@ func (r *videoRepository) GetByUser(ctx context.Context, userID primitive.Object
 	return videos, nil
 }
 
+// AddTags не меняет видео, у которых с новыми тегами их станет больше
+// MaxTagsPerVideo, и возвращает такие видео в Failed
+func (r *videoRepository) AddTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error) {
+	libraryID := models.LibraryID(userID, workspaceID)
+	result, err := r.bulkUpdate(ctx, libraryID, videoIDs, tagCountQuery("$lte", tags), bson.M{
+		"$addToSet": bson.M{"tags": bson.M{"$each": tags}},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+	if err != nil || int(result.Matched) == len(videoIDs) {
+		return result, err
+	}
+
+	query := tagCountQuery("$gt", tags)
+	query["library_id"] = libraryID
+	query["_id"] = bson.M{"$in": videoIDs}
+	if result.Failed, err = r.findIDs(ctx, query); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// tagCountQuery сравнивает оператором op число тегов видео после добавления tags с MaxTagsPerVideo
+func tagCountQuery(op string, tags []string) bson.M {
+	merged := bson.M{"$setUnion": bson.A{bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}, tags}}
+	return bson.M{"$expr": bson.M{op: bson.A{bson.M{"$size": merged}, models.MaxTagsPerVideo}}}
+}
+
+func (r *videoRepository) RemoveTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error) {
+	return r.bulkUpdate(ctx, models.LibraryID(userID, workspaceID), videoIDs, nil, bson.M{
+		"$pull": bson.M{"tags": bson.M{"$in": tags}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *videoRepository) UpdateSuggestedTags(ctx context.Context, id primitive.ObjectID, tags []string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"suggested_tags": tags,
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, id, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrVideoNotFound
+	}
+
+	return nil
+}
+
+func (r *videoRepository) UpdateInsights(ctx context.Context, id primitive.ObjectID, insights *models.VideoInsights) error {
+	update := bson.M{
+		"$set": bson.M{
+			"insights": insights,
+		},
+	}
+
+	result, err := r.collection.UpdateByID(ctx, id, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrVideoNotFound
+	}
+
+	return nil
+}
+
+// StartSummaryJob ставит задачу, если у видео нет незавершённой; задачи,
+// начатые до staleBefore, считаются зависшими и перезаписываются
+func (r *videoRepository) StartSummaryJob(ctx context.Context, id primitive.ObjectID, job *models.SummaryJob, staleBefore time.Time) error {
+	filter := bson.M{
+		"_id": id,
+		"$or": []bson.M{
+			{"summary_job.status": bson.M{"$ne": models.SummaryJobProcessing}},
+			{"summary_job.started_at": bson.M{"$lt": staleBefore}},
+		},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"summary_job": job}})
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+		if err != nil {
+			return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+		}
+		if count == 0 {
+			return models.ErrVideoNotFound
+		}
+		return models.ErrSummaryJobInProgress
+	}
+
+	return nil
+}
+
+func (r *videoRepository) FinishSummaryJob(ctx context.Context, id, jobID primitive.ObjectID, status string, version int, errMsg string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"summary_job.status":      status,
+			"summary_job.version":     version,
+			"summary_job.error":       errMsg,
+			"summary_job.finished_at": time.Now(),
+		},
+	}
+
+	// Задачу могли перезапустить как зависшую — обновляем только свою
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "summary_job.id": jobID}, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+
+	return nil
+}
+
+func (r *videoRepository) ListTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]models.TagCount, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"library_id": models.LibraryID(userID, workspaceID)}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$group", Value: bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate tags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	tags := []models.TagCount{}
+	if err := cursor.All(ctx, &tags); err != nil {
+		return nil, fmt.Errorf("failed to decode tags: %w", err)
+	}
+
+	return tags, nil
+}
+
+func (r *videoRepository) AddToCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, collectionID primitive.ObjectID) (*models.BulkResult, error) {
+	return r.bulkUpdate(ctx, models.LibraryID(userID, workspaceID), videoIDs, nil, bson.M{
+		"$addToSet": bson.M{"collection_ids": collectionID},
+		"$set":      bson.M{"updated_at": time.Now()},
+	})
+}
+
+func (r *videoRepository) RemoveFromCollections(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, collectionIDs []primitive.ObjectID) (*models.BulkResult, error) {
+	return r.bulkUpdate(ctx, models.LibraryID(userID, workspaceID), videoIDs, nil, bson.M{
+		"$pull": bson.M{"collection_ids": bson.M{"$in": collectionIDs}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+// DetachCollections убирает удалённые коллекции из всех видео, в какой бы библиотеке они ни лежали
+func (r *videoRepository) DetachCollections(ctx context.Context, collectionIDs []primitive.ObjectID) error {
+	_, err := r.collection.UpdateMany(ctx, bson.M{"collection_ids": bson.M{"$in": collectionIDs}}, bson.M{
+		"$pull": bson.M{"collection_ids": bson.M{"$in": collectionIDs}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+	return nil
+}
+
+func (r *videoRepository) CountByCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) (map[primitive.ObjectID]int, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"library_id": models.LibraryID(userID, workspaceID)}}},
+		{{Key: "$unwind", Value: "$collection_ids"}},
+		{{Key: "$group", Value: bson.M{"_id": "$collection_ids", "count": bson.M{"$sum": 1}}}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate collections: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int                `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode collection counts: %w", err)
+	}
+
+	counts := make(map[primitive.ObjectID]int, len(rows))
+	for _, row := range rows {
+		counts[row.ID] = row.Count
+	}
+	return counts, nil
+}
+
+// bulkUpdate применяет update к видео библиотеки, подходящим под condition (nil — ко всем);
+// видео из других библиотек в videoIDs не затрагиваются
+func (r *videoRepository) bulkUpdate(ctx context.Context, libraryID primitive.ObjectID, videoIDs []primitive.ObjectID, condition bson.M, update bson.M) (*models.BulkResult, error) {
+	filter := bson.M{"library_id": libraryID, "_id": bson.M{"$in": videoIDs}}
+	for key, value := range condition {
+		filter[key] = value
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrVideoUpdateFailed, err)
+	}
+
+	return &models.BulkResult{
+		Matched:  result.MatchedCount,
+		Modified: result.ModifiedCount,
+	}, nil
+}
+
 func (r *videoRepository) Delete(ctx context.Context, videoID primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": videoID})
 	if err != nil {
@@ -132,3 +451,91 @@ func (r *videoRepository) Delete(ctx context.Context, videoID primitive.ObjectID
 
 	return nil
 }
+
+func (r *videoRepository) EnsureIndexes(ctx context.Context) error {
+	// Видео, созданные до library_id: библиотека — воркспейс или автор
+	backfill := mongo.Pipeline{{{Key: "$set", Value: bson.M{
+		"library_id": bson.M{"$ifNull": bson.A{"$workspace_id", "$user_id"}},
+	}}}}
+	if _, err := r.collection.UpdateMany(ctx, bson.M{"library_id": bson.M{"$exists": false}}, backfill); err != nil {
+		return fmt.Errorf("failed to backfill video libraries: %w", err)
+	}
+
+	// Текстовый индекс у коллекции один; прежний был с префиксом user_id
+	if _, err := r.collection.Indexes().DropOne(ctx, "video_text"); err != nil && !isIndexNotFound(err) {
+		return fmt.Errorf("failed to drop video text index: %w", err)
+	}
+	// Теги и коллекции считаются по library_id, индексы по user_id не нужны
+	for _, name := range []string{"user_id_1_tags_1", "user_id_1_collection_ids_1"} {
+		if _, err := r.collection.Indexes().DropOne(ctx, name); err != nil && !isIndexNotFound(err) {
+			return fmt.Errorf("failed to drop video index %s: %w", name, err)
+		}
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "updated_at", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "title", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "status", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "tags", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "language", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "library_id", Value: 1}, {Key: "collection_ids", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "collection_ids", Value: 1}}},
+		{
+			Keys: bson.D{
+				{Key: "library_id", Value: 1},
+				{Key: "title", Value: "text"},
+				{Key: "summary", Value: "text"},
+				{Key: "transcript", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("video_library_text").
+				SetWeights(bson.M{"title": 10, "summary": 5, "transcript": 1}).
+				SetDefaultLanguage("russian").
+				SetLanguageOverride("search_language"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create video indexes: %w", err)
+	}
+	return nil
+}
+
+// videoFilterQuery ограничивает выборку библиотекой: воркспейсом из фильтра или
+// личными видео пользователя
+func videoFilterQuery(userID primitive.ObjectID, filter models.VideoFilter) bson.M {
+	query := bson.M{"library_id": models.LibraryID(userID, filter.WorkspaceID)}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	if filter.Tag != "" {
+		query["tags"] = filter.Tag
+	}
+	if filter.Language != "" {
+		query["language"] = filter.Language
+	}
+	if filter.CollectionIDs != nil {
+		query["collection_ids"] = bson.M{"$in": filter.CollectionIDs}
+	}
+	applyDateRange(query, "created_at", filter.From, filter.To)
+	return query
+}
+
+// textSearchLanguage сопоставляет код языка транскрипции с языком стемминга MongoDB.
+// Для неподдерживаемых языков стемминг отключается, иначе вставка документа упадёт.
+func textSearchLanguage(language string) string {
+	switch language {
+	case "ru", "russian":
+		return "russian"
+	case "en", "english":
+		return "english"
+	default:
+		return "none"
+	}
+}
+
+// isIndexNotFound — индекса или коллекции ещё нет, удалять нечего
+func isIndexNotFound(err error) bool {
+	var cmdErr mongo.CommandError
+	return errors.As(err, &cmdErr) && (cmdErr.Code == 26 || cmdErr.Code == 27)
+}
@@ -0,0 +1,380 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WorkspaceRepository хранит воркспейсы, их участников и приглашения
+type WorkspaceRepository interface {
+	Create(ctx context.Context, workspace *models.Workspace) (primitive.ObjectID, error)
+	GetByID(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error)
+	GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Workspace, error)
+	Update(ctx context.Context, workspace *models.Workspace) error
+	UpdateSubscription(ctx context.Context, id primitive.ObjectID, subscription string) error
+	RecordAnalysis(ctx context.Context, id primitive.ObjectID, monthlyLimit int) error
+	Delete(ctx context.Context, id primitive.ObjectID) error
+
+	AddMember(ctx context.Context, member *models.WorkspaceMember) error
+	GetMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error)
+	ListMembers(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceMember, error)
+	ListMemberships(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceMember, error)
+	UpdateMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role string) error
+	RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) error
+
+	CreateInvite(ctx context.Context, invite *models.WorkspaceInvite) (primitive.ObjectID, error)
+	GetInvite(ctx context.Context, id primitive.ObjectID) (*models.WorkspaceInvite, error)
+	GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.WorkspaceInvite, error)
+	ListInvites(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceInvite, error)
+	ListInvitesByEmail(ctx context.Context, email string) ([]*models.WorkspaceInvite, error)
+	MarkInviteAccepted(ctx context.Context, id primitive.ObjectID) error
+	DeleteInvite(ctx context.Context, id primitive.ObjectID) error
+
+	EnsureIndexes(ctx context.Context) error
+}
+
+type workspaceRepository struct {
+	workspaces *mongo.Collection
+	members    *mongo.Collection
+	invites    *mongo.Collection
+}
+
+func NewWorkspaceRepository(db *mongo.Database) WorkspaceRepository {
+	return &workspaceRepository{
+		workspaces: db.Collection("workspaces"),
+		members:    db.Collection("workspace_members"),
+		invites:    db.Collection("workspace_invites"),
+	}
+}
+
+func (r *workspaceRepository) Create(ctx context.Context, workspace *models.Workspace) (primitive.ObjectID, error) {
+	workspace.CreatedAt = time.Now()
+	workspace.UpdatedAt = time.Now()
+	if workspace.Subscription == "" {
+		workspace.Subscription = "free"
+	}
+
+	result, err := r.workspaces.InsertOne(ctx, workspace)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: %v", models.ErrWorkspaceCreateFailed, err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("%w: failed to convert inserted ID", models.ErrWorkspaceCreateFailed)
+	}
+
+	workspace.ID = insertedID
+	return insertedID, nil
+}
+
+func (r *workspaceRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	var workspace models.Workspace
+
+	err := r.workspaces.FindOne(ctx, bson.M{"_id": id}).Decode(&workspace)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrWorkspaceNotFound
+		}
+		return nil, fmt.Errorf("failed to get workspace: %w", err)
+	}
+
+	return &workspace, nil
+}
+
+func (r *workspaceRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.Workspace, error) {
+	workspaces := []*models.Workspace{}
+	if len(ids) == 0 {
+		return workspaces, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.workspaces.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspaces: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return nil, fmt.Errorf("failed to decode workspaces: %w", err)
+	}
+
+	return workspaces, nil
+}
+
+func (r *workspaceRepository) Update(ctx context.Context, workspace *models.Workspace) error {
+	workspace.UpdatedAt = time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":       workspace.Name,
+			"redact_pii": workspace.RedactPII,
+			"updated_at": workspace.UpdatedAt,
+		},
+	}
+
+	result, err := r.workspaces.UpdateByID(ctx, workspace.ID, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrWorkspaceUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) UpdateSubscription(ctx context.Context, id primitive.ObjectID, subscription string) error {
+	update := bson.M{"$set": bson.M{"subscription": subscription, "updated_at": time.Now()}}
+
+	result, err := r.workspaces.UpdateByID(ctx, id, update)
+	if err != nil {
+		return fmt.Errorf("%w: %v", models.ErrWorkspaceUpdateFailed, err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrWorkspaceNotFound
+	}
+
+	return nil
+}
+
+// RecordAnalysis атомарно списывает анализ с общей квоты, только если месячный лимит не исчерпан
+func (r *workspaceRepository) RecordAnalysis(ctx context.Context, id primitive.ObjectID, monthlyLimit int) error {
+	return recordMonthlyAnalysis(ctx, r.workspaces, id, monthlyLimit, models.ErrWorkspaceUpdateFailed, models.ErrWorkspaceNotFound)
+}
+
+// Delete удаляет воркспейс вместе с участниками и приглашениями
+func (r *workspaceRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.workspaces.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete workspace: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return models.ErrWorkspaceNotFound
+	}
+
+	if _, err := r.members.DeleteMany(ctx, bson.M{"workspace_id": id}); err != nil {
+		return fmt.Errorf("failed to delete workspace members: %w", err)
+	}
+	if _, err := r.invites.DeleteMany(ctx, bson.M{"workspace_id": id}); err != nil {
+		return fmt.Errorf("failed to delete workspace invites: %w", err)
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	member.JoinedAt = time.Now()
+
+	result, err := r.members.InsertOne(ctx, member)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return models.ErrAlreadyMember
+		}
+		return fmt.Errorf("failed to add workspace member: %w", err)
+	}
+
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		member.ID = id
+	}
+	return nil
+}
+
+func (r *workspaceRepository) GetMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	var member models.WorkspaceMember
+
+	err := r.members.FindOne(ctx, bson.M{"workspace_id": workspaceID, "user_id": userID}).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrMemberNotFound
+		}
+		return nil, fmt.Errorf("failed to get workspace member: %w", err)
+	}
+
+	return &member, nil
+}
+
+func (r *workspaceRepository) ListMembers(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceMember, error) {
+	return r.findMembers(ctx, bson.M{"workspace_id": workspaceID})
+}
+
+func (r *workspaceRepository) ListMemberships(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceMember, error) {
+	return r.findMembers(ctx, bson.M{"user_id": userID})
+}
+
+func (r *workspaceRepository) findMembers(ctx context.Context, filter bson.M) ([]*models.WorkspaceMember, error) {
+	members := []*models.WorkspaceMember{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "joined_at", Value: 1}})
+	cursor, err := r.members.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find workspace members: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, fmt.Errorf("failed to decode workspace members: %w", err)
+	}
+
+	return members, nil
+}
+
+func (r *workspaceRepository) UpdateMemberRole(ctx context.Context, workspaceID, userID primitive.ObjectID, role string) error {
+	result, err := r.members.UpdateOne(ctx,
+		bson.M{"workspace_id": workspaceID, "user_id": userID},
+		bson.M{"$set": bson.M{"role": role}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update workspace member: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrMemberNotFound
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) RemoveMember(ctx context.Context, workspaceID, userID primitive.ObjectID) error {
+	result, err := r.members.DeleteOne(ctx, bson.M{"workspace_id": workspaceID, "user_id": userID})
+	if err != nil {
+		return fmt.Errorf("failed to remove workspace member: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return models.ErrMemberNotFound
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) CreateInvite(ctx context.Context, invite *models.WorkspaceInvite) (primitive.ObjectID, error) {
+	invite.CreatedAt = time.Now()
+
+	result, err := r.invites.InsertOne(ctx, invite)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	insertedID, ok := result.InsertedID.(primitive.ObjectID)
+	if !ok {
+		return primitive.NilObjectID, fmt.Errorf("failed to create invite: failed to convert inserted ID")
+	}
+
+	invite.ID = insertedID
+	return insertedID, nil
+}
+
+func (r *workspaceRepository) GetInvite(ctx context.Context, id primitive.ObjectID) (*models.WorkspaceInvite, error) {
+	return r.findInvite(ctx, bson.M{"_id": id})
+}
+
+func (r *workspaceRepository) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.WorkspaceInvite, error) {
+	return r.findInvite(ctx, bson.M{"token_hash": tokenHash})
+}
+
+func (r *workspaceRepository) findInvite(ctx context.Context, filter bson.M) (*models.WorkspaceInvite, error) {
+	var invite models.WorkspaceInvite
+
+	err := r.invites.FindOne(ctx, filter).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, models.ErrInviteNotFound
+		}
+		return nil, fmt.Errorf("failed to get invite: %w", err)
+	}
+
+	return &invite, nil
+}
+
+// ListInvites возвращает непринятые приглашения воркспейса, включая просроченные
+func (r *workspaceRepository) ListInvites(ctx context.Context, workspaceID primitive.ObjectID) ([]*models.WorkspaceInvite, error) {
+	return r.findInvites(ctx, bson.M{
+		"workspace_id": workspaceID,
+		"accepted_at":  bson.M{"$exists": false},
+	})
+}
+
+// ListInvitesByEmail возвращает действующие приглашения на email
+func (r *workspaceRepository) ListInvitesByEmail(ctx context.Context, email string) ([]*models.WorkspaceInvite, error) {
+	return r.findInvites(ctx, bson.M{
+		"email":       email,
+		"accepted_at": bson.M{"$exists": false},
+		"expires_at":  bson.M{"$gt": time.Now()},
+	})
+}
+
+func (r *workspaceRepository) findInvites(ctx context.Context, filter bson.M) ([]*models.WorkspaceInvite, error) {
+	invites := []*models.WorkspaceInvite{}
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.invites.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find invites: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &invites); err != nil {
+		return nil, fmt.Errorf("failed to decode invites: %w", err)
+	}
+
+	return invites, nil
+}
+
+func (r *workspaceRepository) MarkInviteAccepted(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.invites.UpdateOne(ctx,
+		bson.M{"_id": id, "accepted_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"accepted_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to accept invite: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return models.ErrInviteNotFound
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) DeleteInvite(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.invites.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("failed to delete invite: %w", err)
+	}
+
+	if result.DeletedCount == 0 {
+		return models.ErrInviteNotFound
+	}
+
+	return nil
+}
+
+func (r *workspaceRepository) EnsureIndexes(ctx context.Context) error {
+	if _, err := r.members.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "user_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+	}); err != nil {
+		return fmt.Errorf("failed to create workspace member indexes: %w", err)
+	}
+
+	if _, err := r.invites.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "workspace_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		{Keys: bson.D{{Key: "email", Value: 1}, {Key: "expires_at", Value: 1}}},
+		{Keys: bson.D{{Key: "token_hash", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+	}); err != nil {
+		return fmt.Errorf("failed to create workspace invite indexes: %w", err)
+	}
+
+	return nil
+}
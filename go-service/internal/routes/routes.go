@@ -12,6 +12,15 @@ func SetupRoutes(
 	userHandlers *handlers.UserHandlers,
 	videoHandlers *handlers.VideoHandlers,
 	aiHandlers *handlers.AIHandlers,
+	searchHandlers *handlers.SearchHandlers,
+	libraryHandlers *handlers.LibraryHandlers,
+	shareHandlers *handlers.ShareHandlers,
+	workspaceHandlers *handlers.WorkspaceHandlers,
+	summaryHandlers *handlers.SummaryHandlers,
+	adminHandlers *handlers.AdminHandlers,
+	quizHandlers *handlers.QuizHandlers,
+	insightsHandlers *handlers.InsightsHandlers,
+	translationHandlers *handlers.TranslationHandlers,
 ) {
 	api := app.Group("/api/v1")
 
@@ -23,6 +32,9 @@ func SetupRoutes(
 		authGroup.Post("/refresh", userHandlers.RefreshToken)
 	}
 
+	// Public share links (no JWT)
+	app.Get("/s/:token", shareHandlers.OpenShare)
+
 	// Protected routes (require JWT)
 	protected := api.Group("", jwtManager.Middleware())
 	{
@@ -40,9 +52,70 @@ func SetupRoutes(
 		{
 			videosGroup.Post("/upload", videoHandlers.UploadVideo)
 			videosGroup.Get("/", videoHandlers.GetUserVideos)
+			videosGroup.Post("/tags/assign", libraryHandlers.AssignTags)
+			videosGroup.Post("/tags/unassign", libraryHandlers.UnassignTags)
 			videosGroup.Get("/:id", videoHandlers.GetVideoStatus)
 			videosGroup.Get("/:id/result", videoHandlers.GetVideoResult)
 			videosGroup.Delete("/:id", videoHandlers.DeleteVideo)
+			videosGroup.Post("/:id/tags/suggest", libraryHandlers.SuggestTags)
+			videosGroup.Put("/:id/summary", summaryHandlers.UpdateSummary)
+			videosGroup.Get("/:id/summary/versions", summaryHandlers.ListVersions)
+			videosGroup.Get("/:id/summary/versions/:version", summaryHandlers.GetVersion)
+			videosGroup.Post("/:id/summary/versions/:version/restore", summaryHandlers.RestoreVersion)
+			videosGroup.Get("/:id/summary/diff", summaryHandlers.Diff)
+			videosGroup.Post("/:id/summary/improve", summaryHandlers.ImproveSummary)
+			videosGroup.Post("/:id/summary/fix", summaryHandlers.FixSummary)
+			videosGroup.Get("/:id/insights", insightsHandlers.GetInsights)
+			videosGroup.Post("/:id/insights", insightsHandlers.RefreshInsights)
+			videosGroup.Post("/:id/translations", translationHandlers.TranslateVideo)
+			videosGroup.Get("/:id/translations", translationHandlers.ListTranslations)
+			videosGroup.Get("/:id/translations/:lang", translationHandlers.GetTranslation)
+			videosGroup.Get("/:id/subtitles", translationHandlers.GetSubtitles)
+			videosGroup.Post("/:id/quiz", quizHandlers.GenerateQuiz)
+			videosGroup.Get("/:id/quizzes", quizHandlers.ListQuizzes)
+			videosGroup.Get("/:id/quizzes/:quizId", quizHandlers.GetQuiz)
+			videosGroup.Get("/:id/quizzes/:quizId/export", quizHandlers.ExportQuiz)
+			videosGroup.Post("/:id/shares", shareHandlers.CreateShare)
+			videosGroup.Get("/:id/shares", shareHandlers.ListShares)
+		}
+
+		// Share links
+		protected.Delete("/shares/:id", shareHandlers.RevokeShare)
+
+		// Tags and collections
+		protected.Get("/tags", libraryHandlers.ListTags)
+		collectionsGroup := protected.Group("/collections")
+		{
+			collectionsGroup.Get("/", libraryHandlers.ListCollections)
+			collectionsGroup.Post("/", libraryHandlers.CreateCollection)
+			collectionsGroup.Patch("/:id", libraryHandlers.UpdateCollection)
+			collectionsGroup.Delete("/:id", libraryHandlers.DeleteCollection)
+			collectionsGroup.Post("/:id/videos", libraryHandlers.AddVideos)
+			collectionsGroup.Post("/:id/videos/remove", libraryHandlers.RemoveVideos)
+		}
+
+		// Search routes
+		protected.Get("/search", searchHandlers.Search)
+		protected.Get("/search/semantic", searchHandlers.SemanticSearch)
+
+		// Workspace routes
+		workspacesGroup := protected.Group("/workspaces")
+		{
+			workspacesGroup.Get("/", workspaceHandlers.ListWorkspaces)
+			workspacesGroup.Post("/", workspaceHandlers.CreateWorkspace)
+			// Приглашения текущего пользователя; регистрируются до /:id
+			workspacesGroup.Get("/invites", workspaceHandlers.ListMyInvites)
+			workspacesGroup.Post("/invites/accept", workspaceHandlers.AcceptInvite)
+			workspacesGroup.Get("/:id", workspaceHandlers.GetWorkspace)
+			workspacesGroup.Patch("/:id", workspaceHandlers.UpdateWorkspace)
+			workspacesGroup.Delete("/:id", workspaceHandlers.DeleteWorkspace)
+			workspacesGroup.Get("/:id/analytics", workspaceHandlers.GetAnalytics)
+			workspacesGroup.Get("/:id/members", workspaceHandlers.ListMembers)
+			workspacesGroup.Patch("/:id/members/:userId", workspaceHandlers.UpdateMemberRole)
+			workspacesGroup.Delete("/:id/members/:userId", workspaceHandlers.RemoveMember)
+			workspacesGroup.Get("/:id/invites", workspaceHandlers.ListInvites)
+			workspacesGroup.Post("/:id/invites", workspaceHandlers.InviteMember)
+			workspacesGroup.Delete("/:id/invites/:inviteId", workspaceHandlers.RevokeInvite)
 		}
 
 		// AI routes
@@ -52,10 +125,23 @@ func SetupRoutes(
 			aiGroup.Post("/sessions", aiHandlers.CreateSession)
 			aiGroup.Get("/sessions", aiHandlers.GetUserSessions)
 			aiGroup.Get("/sessions/:id", aiHandlers.GetSession)
+			aiGroup.Patch("/sessions/:id", aiHandlers.UpdateSession)
+			aiGroup.Get("/sessions/:id/export", aiHandlers.ExportSession)
 			aiGroup.Post("/sessions/:id/message", aiHandlers.SendMessage)
+			aiGroup.Post("/sessions/:id/message/stream", aiHandlers.StreamMessage)
+			aiGroup.Put("/sessions/:id/messages/:messageId", aiHandlers.EditMessage)
+			aiGroup.Post("/sessions/:id/messages/:messageId/regenerate", aiHandlers.RegenerateMessage)
+			aiGroup.Post("/sessions/:id/messages/:messageId/activate", aiHandlers.ActivateMessage)
 			aiGroup.Delete("/sessions/:id", aiHandlers.DeleteSession)
+			aiGroup.Post("/sessions/:id/summarize", summaryHandlers.SummarizeSession)
 
 		}
+
+		// Администрирование; доступно только пользователям с ролью admin
+		adminGroup := protected.Group("/admin", adminHandlers.RequireAdmin)
+		{
+			adminGroup.Get("/prompts", adminHandlers.ListPrompts)
+		}
 	}
 
 	// 404 Handler
@@ -1,282 +1,829 @@
 package services
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 	"time"
 
 	"github.com/code-zt/vidnotes/config"
 	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
 )
 
+// ChatSource — материалы одного видео сессии для контекста модели
+type ChatSource struct {
+	// Метка видео (V1, V2…) в сессиях по нескольким видео; по ней модель указывает источник
+	Label   string
+	Title   string
+	Summary string
+	// Фрагменты транскрипта по убыванию важности; в контекст попадает столько, сколько помещается
+	Excerpts []string
+}
+
 type AIService interface {
-	SendMessage(ctx context.Context, session *models.AISession, userMessage string) (string, error)
-	CreateSessionContext(videoSummary string) string
+	// SendMessage возвращает ответ и модель, которая фактически ответила
+	SendMessage(ctx context.Context, session *models.AISession, sources []ChatSource, userMessage string) (ChatResult, error)
+	// StreamMessage передаёт фрагменты ответа в onDelta по мере генерации и возвращает полный текст.
+	// Ошибка из onDelta прерывает запрос к модели.
+	StreamMessage(ctx context.Context, session *models.AISession, sources []ChatSource, userMessage string, onDelta func(delta string) error) (ChatResult, error)
+	// CheckMessage возвращает ErrContextTooLong, если сообщение не помещается в контекст модели чата,
+	// и ErrMonthlyAITokensExceeded, если пользователь из контекста исчерпал месячный лимит токенов
+	CheckMessage(ctx context.Context, userMessage string) error
 	ImproveSummary(ctx context.Context, currentSummary string, issues []string) (string, error)
+	// CondenseSummary оставляет в саммари только существенное — для контекста новой сессии
+	CondenseSummary(ctx context.Context, summary string) (string, error)
 	FixSummaryErrors(ctx context.Context, currentSummary string) (string, error)
 	CreateSummaryFromDialogue(ctx context.Context, messages []models.AIMessage) (string, error)
+	// CompressMemory дополняет память сессии репликами, выпадающими из истории
+	CompressMemory(ctx context.Context, memory string, messages []models.AIMessage) (string, error)
+	SuggestTags(ctx context.Context, summary string, existingTags []string) ([]string, error)
+	// GenerateTitle придумывает название сессии по первому вопросу и ответу
+	GenerateTitle(ctx context.Context, question, answer string) (string, error)
+	// GenerateQuiz составляет вопросы и карточки по видео. Ответ модели проверяется
+	// по JSON-схеме; если он не проходит, модель получает ошибку и отвечает заново.
+	// Заполнены только вопросы, карточки и модель; сегменты не сверены с транскриптом.
+	GenerateQuiz(ctx context.Context, source VideoSource, questions, flashcards int) (*models.Quiz, error)
+	// ExtractInsights выделяет ключевые тезисы, задачи, решения и открытые вопросы
+	// с проверкой по JSON-схеме, как GenerateQuiz. Сегменты не сверены с транскриптом.
+	ExtractInsights(ctx context.Context, source VideoSource) (*models.VideoInsights, error)
+	// TranslateText переводит текст на язык language (название языка для модели),
+	// длинный — частями по абзацам
+	TranslateText(ctx context.Context, text, language string) (string, error)
+	// TranslateSegments переводит сегменты транскрипта частями; номера и таймкоды
+	// сохраняются, каждая часть проверяется по JSON-схеме, как в GenerateQuiz
+	TranslateSegments(ctx context.Context, segments []models.TranscriptSegment, language string) ([]models.TranscriptSegment, error)
 }
 
-type OpenRouterService struct {
-	config *config.OpenRouterConfig
-	client *http.Client
+// VideoSource — материалы видео для структурированной генерации;
+// Transcript — в формате TranscriptExcerpt
+type VideoSource struct {
+	Title      string
+	Summary    string
+	Transcript string
 }
 
-func NewOpenRouterService(cfg *config.OpenRouterConfig) AIService {
-	return &OpenRouterService{
-		config: cfg,
-		client: &http.Client{
-			Timeout: time.Duration(cfg.Timeout) * time.Second,
-		},
-	}
+// fit укладывает материалы в limit токенов: саммари — не больше четверти, остальное — транскрипт
+func (src VideoSource) fit(budget ContextBudget, limit int) VideoSource {
+	src.Summary = budget.Truncate(src.Summary, limit/4)
+	src.Transcript = budget.Truncate(src.Transcript, limit-budget.Count(src.Summary))
+	return src
 }
 
-type OpenRouterRequest struct {
-	Model    string              `json:"model"`
-	Messages []OpenRouterMessage `json:"messages"`
-	Stream   bool                `json:"stream"`
+// aiService рендерит промпты из шаблонов; язык шаблона берётся из контекста
+// (prompts.WithLocale), сам запрос уходит по цепочке моделей операции.
+// Если контекст помечен WithAIUsageScope, перед запросом проверяется лимит
+// токенов пользователя, а после — сохраняется расход.
+type aiService struct {
+	router   *LLMRouter
+	prompts  *prompts.Registry
+	meter    AIUsageMeter
+	redactor *PIIRedactor
 }
 
-type OpenRouterMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+func NewAIService(router *LLMRouter, registry *prompts.Registry, meter AIUsageMeter, redactor *PIIRedactor) AIService {
+	return &aiService{router: router, prompts: registry, meter: meter, redactor: redactor}
 }
 
-type OpenRouterResponse struct {
-	Choices []OpenRouterChoice `json:"choices"`
-	Error   *OpenRouterError   `json:"error,omitempty"`
+// Шаблоны промптов
+const (
+	promptChat            = "chat"
+	promptImproveSummary  = "improve_summary"
+	promptFixSummary      = "fix_summary"
+	promptCondenseSummary = "condense_summary"
+	promptDialogueSummary = "dialogue_summary"
+	promptCompressMemory  = "compress_memory"
+	promptSuggestTags     = "suggest_tags"
+	promptSessionTitle    = "session_title"
+	promptQuiz            = "quiz"
+	promptInsights        = "insights"
+	promptTranslateText   = "translate_text"
+	promptTranslateSegs   = "translate_segments"
+	promptCorrection      = "structured_correction"
+)
+
+type chatPromptData struct {
+	Multi   bool
+	Sources []ChatSource
+	Memory  string
 }
 
-type OpenRouterChoice struct {
-	Message OpenRouterMessage `json:"message"`
+type summaryPromptData struct {
+	Summary string
+	Focus   []string
 }
 
-type OpenRouterError struct {
-	Message string `json:"message"`
-	Type    string `json:"type"`
+type dialoguePromptData struct {
+	Dialogue string
+	Memory   string
 }
 
-func (s *OpenRouterService) CreateSessionContext(videoSummary string) string {
-	if videoSummary == "" {
-		return "Video analysis assistant. Respond in user's language."
-	}
+type titlePromptData struct {
+	Question string
+	Answer   string
+}
 
-	if len(videoSummary) > 8000 {
-		videoSummary = videoSummary[:8000] + "\n\n... (text truncated)"
-	}
+type quizPromptData struct {
+	VideoSource
+	Questions  int
+	Flashcards int
+	Schema     string
+}
 
-	return fmt.Sprintf("Video context:\n%s\n\nRespond in user's language.", videoSummary)
+type insightsPromptData struct {
+	VideoSource
+	Schema string
 }
 
-func (s *OpenRouterService) SendMessage(ctx context.Context, session *models.AISession, userMessage string) (string, error) {
-	if s.config.APIKey == "" {
-		return "", models.ErrAIServiceUnavailable
-	}
+type translatePromptData struct {
+	Language string
+	Text     string
+	Segments string
+	Schema   string
+}
 
-	systemMessage := s.CreateSessionContext(session.Summary)
+type correctionPromptData struct {
+	Error string
+}
 
-	messages := []OpenRouterMessage{
-		{Role: "system", Content: systemMessage},
-	}
+type tagsPromptData struct {
+	Summary      string
+	ExistingTags []string
+}
+
+// Время на сохранение расхода, если запрос уже отменён
+const usageRecordTimeout = 5 * time.Second
+
+func (s *aiService) chat(ctx context.Context, operation string, messages []ChatMessage) (string, error) {
+	result, err := s.call(ctx, operation, messages, nil)
+	return result.Content, err
+}
 
-	startIdx := 0
-	if len(session.Messages) > 10 {
-		startIdx = len(session.Messages) - 10
+// call отправляет запрос по цепочке моделей операции, потоково при onDelta != nil, и учитывает расход.
+// Персональные данные заменяются плейсхолдерами до отправки и возвращаются в ответ.
+func (s *aiService) call(ctx context.Context, operation string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	if err := s.checkQuota(ctx); err != nil {
+		return ChatResult{}, err
 	}
 
-	for i := startIdx; i < len(session.Messages); i++ {
-		messages = append(messages, OpenRouterMessage{
-			Role:    session.Messages[i].Role,
-			Content: session.Messages[i].Content,
-		})
+	redaction := s.redactor.begin(ctx)
+	var flush func() error
+	if redaction != nil {
+		messages = redaction.messages(messages)
+		if onDelta != nil {
+			onDelta, flush = redaction.stream(onDelta)
+		}
 	}
 
-	messages = append(messages, OpenRouterMessage{
-		Role:    "user",
-		Content: userMessage,
-	})
+	result, err := s.router.Chat(ctx, operation, messages, onDelta)
+	if redaction != nil {
+		result.Content = redaction.restore(result.Content)
+		if flush != nil && err == nil {
+			err = flush()
+		}
+	}
 
-	requestBody := OpenRouterRequest{
-		Model:    s.config.Model,
-		Messages: messages,
-		Stream:   false,
+	// Прерванный поток тоже расходует токены, поэтому учитываем и частичный ответ
+	if scope, ok := usageScopeFrom(ctx); ok && (err == nil || result.Content != "") {
+		s.recordUsage(ctx, usageRecord(scope, operation, messages, result))
 	}
 
-	return s.sendOpenRouterRequest(ctx, requestBody)
+	return result, err
 }
 
-func (s *OpenRouterService) ImproveSummary(ctx context.Context, currentSummary string, issues []string) (string, error) {
-	if s.config.APIKey == "" {
-		return "", models.ErrAIServiceUnavailable
+func (s *aiService) checkQuota(ctx context.Context) error {
+	scope, ok := usageScopeFrom(ctx)
+	if !ok {
+		return nil
 	}
+	return s.meter.CheckAIQuota(ctx, scope.UserID)
+}
+
+func (s *aiService) recordUsage(ctx context.Context, record *models.AIUsageRecord) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), usageRecordTimeout)
+	defer cancel()
 
-	if len(currentSummary) > 6000 {
-		currentSummary = currentSummary[:6000] + "\n\n... (text truncated)"
+	if err := s.meter.RecordAIUsage(ctx, record); err != nil {
+		fmt.Printf("Failed to record AI usage for user %s: %v\n", record.UserID.Hex(), err)
 	}
+}
 
-	issuesText := ""
-	if len(issues) > 0 {
-		issuesText = "Focus on: " + strings.Join(issues, ", ")
+// complete рендерит шаблон и отправляет его системный и пользовательский блоки
+func (s *aiService) complete(ctx context.Context, operation, name string, data any) (string, error) {
+	prompt, err := s.prompts.Render(ctx, name, data)
+	if err != nil {
+		return "", err
 	}
+	return s.chat(ctx, operation, promptMessages(prompt))
+}
 
-	prompt := fmt.Sprintf(`Improve this video summary. Keep original language.
+func promptMessages(prompt prompts.Prompt) []ChatMessage {
+	messages := make([]ChatMessage, 0, 2)
+	if prompt.System != "" {
+		messages = append(messages, ChatMessage{Role: "system", Content: prompt.System})
+	}
+	return append(messages, ChatMessage{Role: "user", Content: prompt.User})
+}
 
-%s
+// Попыток получить от модели ответ, проходящий схему
+const structuredAttempts = 3
+
+// completeJSON отправляет промпт и разбирает ответ по схеме. Ответ, не прошедший
+// схему или check, возвращается модели с описанием ошибки, всего до
+// structuredAttempts попыток. Возвращает результат и модель, которая его дала.
+func completeJSON[T any](ctx context.Context, s *aiService, operation string, prompt prompts.Prompt, schema *JSONSchema, check func(*T) error) (*T, string, error) {
+	messages := promptMessages(prompt)
+	ctx = withResponseSchema(ctx, schema)
+
+	var lastErr error
+	for attempt := 0; attempt < structuredAttempts; attempt++ {
+		result, err := s.call(ctx, operation, messages, nil)
+		if err != nil {
+			return nil, "", err
+		}
 
-%s
+		out := new(T)
+		err = schema.Decode([]byte(extractJSON(result.Content, '{', '}')), out)
+		if err == nil && check != nil {
+			err = check(out)
+		}
+		if err == nil {
+			return out, result.Model, nil
+		}
+
+		lastErr = err
+		fmt.Printf("Structured %s reply %d from %s is invalid: %v\n", operation, attempt+1, result.Model, err)
+
+		correction, err := s.prompts.Render(ctx, promptCorrection, correctionPromptData{Error: lastErr.Error()})
+		if err != nil {
+			return nil, "", err
+		}
+		messages = append(messages,
+			ChatMessage{Role: "assistant", Content: result.Content},
+			ChatMessage{Role: "user", Content: correction.User},
+		)
+	}
 
-Return only improved summary.`, currentSummary, issuesText)
+	return nil, "", fmt.Errorf("%w: %v", models.ErrInvalidAIOutput, lastErr)
+}
 
-	messages := []OpenRouterMessage{
-		{
-			Role:    "system",
-			Content: "Improve text quality. Keep original language. Return only improved text.",
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
+const (
+	// Служебные токены на каждое сообщение и заголовки разделов контекста
+	messageOverheadTokens = 4
+	chatSectionTokens     = 16
+	// Лимит на одно сообщение в саммари по диалогу
+	dialogueMessageTokens = 300
+	// Потолок памяти сессии в контексте
+	memoryTokens = 800
+)
+
+func (s *aiService) CheckMessage(ctx context.Context, userMessage string) error {
+	budget := s.router.Budget(config.LLMOperationChat)
+	overhead, err := s.chatOverhead(ctx, budget, false)
+	if err != nil {
+		return err
 	}
+	if chatAvailable(budget, overhead, userMessage) <= 0 {
+		return models.ErrContextTooLong
+	}
+	return s.checkQuota(ctx)
+}
 
-	requestBody := OpenRouterRequest{
-		Model:    s.config.Model,
-		Messages: messages,
-		Stream:   false,
+func (s *aiService) SendMessage(ctx context.Context, session *models.AISession, sources []ChatSource, userMessage string) (ChatResult, error) {
+	messages, err := s.buildChatMessages(ctx, session, sources, userMessage)
+	if err != nil {
+		return ChatResult{}, err
 	}
+	return s.call(ctx, config.LLMOperationChat, messages, nil)
+}
 
-	return s.sendOpenRouterRequest(ctx, requestBody)
+func (s *aiService) StreamMessage(ctx context.Context, session *models.AISession, sources []ChatSource, userMessage string, onDelta func(delta string) error) (ChatResult, error) {
+	messages, err := s.buildChatMessages(ctx, session, sources, userMessage)
+	if err != nil {
+		return ChatResult{}, err
+	}
+	return s.call(ctx, config.LLMOperationChat, messages, onDelta)
 }
 
-func (s *OpenRouterService) FixSummaryErrors(ctx context.Context, currentSummary string) (string, error) {
-	if s.config.APIKey == "" {
-		return "", models.ErrAIServiceUnavailable
+// chatOverhead — стоимость системного промпта без материалов видео и памяти
+func (s *aiService) chatOverhead(ctx context.Context, budget ContextBudget, multi bool) (int, error) {
+	prompt, err := s.prompts.Render(ctx, promptChat, chatPromptData{Multi: multi})
+	if err != nil {
+		return 0, err
 	}
+	return budget.Count(prompt.System), nil
+}
 
-	if len(currentSummary) > 6000 {
-		currentSummary = currentSummary[:6000] + "\n\n... (text truncated)"
+func chatAvailable(budget ContextBudget, overhead int, userMessage string) int {
+	return budget.Input - overhead - chatSectionTokens -
+		budget.Count(userMessage) - 2*messageOverheadTokens
+}
+
+// buildChatMessages распределяет бюджет модели: сначала последние реплики истории
+// (не больше трети) и память о более ранних, затем саммари и фрагменты транскрипта
+// поровну; оставшееся после них отдаётся более старой истории. Между видео
+// сессии саммари и фрагменты делятся поровну, недобранное одним видео переходит
+// следующим. Реплики, уже свёрнутые в память, в историю не попадают.
+func (s *aiService) buildChatMessages(ctx context.Context, session *models.AISession, sources []ChatSource, userMessage string) ([]ChatMessage, error) {
+	budget := s.router.Budget(config.LLMOperationChat)
+	multi := len(sources) > 1
+	overhead, err := s.chatOverhead(ctx, budget, multi)
+	if err != nil {
+		return nil, err
 	}
+	available := chatAvailable(budget, overhead, userMessage)
+	if multi {
+		available -= len(sources) * chatSectionTokens
+	}
+	if available <= 0 {
+		return nil, models.ErrContextTooLong
+	}
+
+	// В контекст идёт только активная ветка диалога
+	branch := session.Branch()
+	memory, memoryUpTo := session.BranchMemory(branch)
+	turns := branch[memoryUpTo:]
+	history, historyTokens := recentHistory(budget, turns, available/3)
+	rest := available - historyTokens
 
-	prompt := fmt.Sprintf(`Clean errors in this summary. Keep original language.
+	memory = budget.Truncate(memory, min(memoryTokens, rest/3))
+	rest -= budget.Count(memory)
 
-%s
+	hasExcerpts := false
+	for _, source := range sources {
+		hasExcerpts = hasExcerpts || len(source.Excerpts) > 0
+	}
+	summaryLimit := rest
+	if hasExcerpts {
+		summaryLimit = rest / 2
+	}
 
-Remove noise, fix errors. Return only corrected text.`, currentSummary)
+	picked := make([]ChatSource, len(sources))
+	summaryRest := summaryLimit
+	for i, source := range sources {
+		share := summaryRest / (len(sources) - i)
+		summary := budget.Truncate(source.Summary, share)
+		summaryRest -= budget.Count(summary)
+		picked[i] = ChatSource{Label: source.Label, Title: source.Title, Summary: summary}
+	}
+	rest -= summaryLimit - summaryRest
+
+	for i, source := range sources {
+		share := rest / (len(sources) - i)
+		for _, excerpt := range source.Excerpts {
+			excerpt = budget.Truncate(excerpt, share)
+			if excerpt == "" {
+				break
+			}
+			picked[i].Excerpts = append(picked[i].Excerpts, excerpt)
+			cost := budget.Count(excerpt) + 1
+			share -= cost
+			rest -= cost
+		}
+	}
 
-	messages := []OpenRouterMessage{
-		{
-			Role:    "system",
-			Content: "Fix text errors. Keep original language. Return only corrected text.",
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
+	if rest > 0 {
+		history, _ = recentHistory(budget, turns, historyTokens+rest)
 	}
 
-	requestBody := OpenRouterRequest{
-		Model:    s.config.Model,
-		Messages: messages,
-		Stream:   false,
+	systemPrompt, err := s.prompts.Render(ctx, promptChat, chatPromptData{Multi: multi, Sources: picked, Memory: memory})
+	if err != nil {
+		return nil, err
 	}
 
-	return s.sendOpenRouterRequest(ctx, requestBody)
+	messages := make([]ChatMessage, 0, len(history)+2)
+	messages = append(messages, ChatMessage{Role: "system", Content: systemPrompt.System})
+	messages = append(messages, history...)
+	return append(messages, ChatMessage{
+		Role:    "user",
+		Content: userMessage,
+	}), nil
 }
 
-func (s *OpenRouterService) CreateSummaryFromDialogue(ctx context.Context, messages []models.AIMessage) (string, error) {
-	if s.config.APIKey == "" {
-		return "", models.ErrAIServiceUnavailable
+// recentHistory берёт реплики пользователя и ассистента с конца, пока они целиком помещаются в limit
+func recentHistory(budget ContextBudget, messages []models.AIMessage, limit int) ([]ChatMessage, int) {
+	used := 0
+	start := len(messages)
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != "user" && msg.Role != "assistant" {
+			continue
+		}
+		cost := budget.Count(msg.Content) + messageOverheadTokens
+		if used+cost > limit {
+			break
+		}
+		used += cost
+		start = i
 	}
 
+	history := make([]ChatMessage, 0, len(messages)-start)
+	for _, msg := range messages[start:] {
+		if msg.Role == "user" || msg.Role == "assistant" {
+			history = append(history, ChatMessage{Role: msg.Role, Content: msg.Content})
+		}
+	}
+	return history, used
+}
+
+// textBudget — сколько токенов входного текста отдать операции над саммари:
+// половина окна, вторая остаётся на промпт и сопоставимый по длине ответ
+func (s *aiService) textBudget(operation string) (ContextBudget, int) {
+	budget := s.router.Budget(operation)
+	return budget, budget.Input / 2
+}
+
+func (s *aiService) ImproveSummary(ctx context.Context, currentSummary string, issues []string) (string, error) {
+	budget, limit := s.textBudget(config.LLMOperationSummary)
+	return s.complete(ctx, config.LLMOperationSummary, promptImproveSummary, summaryPromptData{
+		Summary: budget.Truncate(currentSummary, limit),
+		Focus:   issues,
+	})
+}
+
+func (s *aiService) CondenseSummary(ctx context.Context, summary string) (string, error) {
+	budget, limit := s.textBudget(config.LLMOperationSummary)
+	return s.complete(ctx, config.LLMOperationSummary, promptCondenseSummary, summaryPromptData{
+		Summary: budget.Truncate(summary, limit),
+	})
+}
+
+func (s *aiService) FixSummaryErrors(ctx context.Context, currentSummary string) (string, error) {
+	budget, limit := s.textBudget(config.LLMOperationSummary)
+	return s.complete(ctx, config.LLMOperationSummary, promptFixSummary, summaryPromptData{
+		Summary: budget.Truncate(currentSummary, limit),
+	})
+}
+
+func (s *aiService) CreateSummaryFromDialogue(ctx context.Context, messages []models.AIMessage) (string, error) {
+	budget, limit := s.textBudget(config.LLMOperationSummary)
+	return s.complete(ctx, config.LLMOperationSummary, promptDialogueSummary, dialoguePromptData{
+		Dialogue: budget.Truncate(formatDialogue(budget, messages), limit),
+	})
+}
+
+func (s *aiService) CompressMemory(ctx context.Context, memory string, messages []models.AIMessage) (string, error) {
+	budget, limit := s.textBudget(config.LLMOperationSummary)
+	memory = budget.Truncate(memory, memoryTokens)
+	return s.complete(ctx, config.LLMOperationSummary, promptCompressMemory, dialoguePromptData{
+		Memory:   memory,
+		Dialogue: budget.Truncate(formatDialogue(budget, messages), limit-budget.Count(memory)),
+	})
+}
+
+// formatDialogue выводит реплики пользователя и ассистента строками "role: text"
+func formatDialogue(budget ContextBudget, messages []models.AIMessage) string {
 	var dialogue strings.Builder
 	for _, msg := range messages {
 		if msg.Role == "user" || msg.Role == "assistant" {
-			content := msg.Content
-			if len(content) > 1000 {
-				content = content[:1000] + "..."
-			}
+			content := budget.Truncate(msg.Content, dialogueMessageTokens)
 			dialogue.WriteString(fmt.Sprintf("%s: %s\n", msg.Role, content))
 		}
 	}
+	return dialogue.String()
+}
 
-	dialogueText := dialogue.String()
-	if len(dialogueText) > 6000 {
-		dialogueText = dialogueText[:6000] + "\n\n... (dialogue truncated)"
+func (s *aiService) SuggestTags(ctx context.Context, summary string, existingTags []string) ([]string, error) {
+	budget, limit := s.textBudget(config.LLMOperationTags)
+	content, err := s.complete(ctx, config.LLMOperationTags, promptSuggestTags, tagsPromptData{
+		Summary:      budget.Truncate(summary, limit),
+		ExistingTags: existingTags,
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	prompt := fmt.Sprintf(`Create video summary from dialogue. Use dialogue language.
+	return parseTagList(content), nil
+}
 
-%s
+// Сколько токенов вопроса и ответа хватает, чтобы понять тему разговора
+const titleExcerptTokens = 300
 
-Extract key facts and themes. Return only summary.`, dialogueText)
+func (s *aiService) GenerateTitle(ctx context.Context, question, answer string) (string, error) {
+	budget := s.router.Budget(config.LLMOperationTitle)
+	content, err := s.complete(ctx, config.LLMOperationTitle, promptSessionTitle, titlePromptData{
+		Question: budget.Truncate(question, titleExcerptTokens),
+		Answer:   budget.Truncate(answer, titleExcerptTokens),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return cleanTitle(content), nil
+}
+
+func (s *aiService) GenerateQuiz(ctx context.Context, source VideoSource, questions, flashcards int) (*models.Quiz, error) {
+	budget, limit := s.textBudget(config.LLMOperationQuiz)
+	schema := quizSchema(questions, flashcards)
 
-	messagesReq := []OpenRouterMessage{
-		{
-			Role:    "system",
-			Content: "Create concise summaries. Use same language as input.",
-		},
-		{
-			Role:    "user",
-			Content: prompt,
-		},
+	prompt, err := s.prompts.Render(ctx, promptQuiz, quizPromptData{
+		VideoSource: source.fit(budget, limit),
+		Questions:   questions,
+		Flashcards:  flashcards,
+		Schema:      schema.String(),
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	requestBody := OpenRouterRequest{
-		Model:    s.config.Model,
-		Messages: messagesReq,
-		Stream:   false,
+	quiz, model, err := completeJSON(ctx, s, config.LLMOperationQuiz, prompt, schema, checkQuiz)
+	if err != nil {
+		return nil, err
 	}
+	quiz.Model = model
+	return quiz, nil
+}
+
+// quizSchema — схема ответа с тестом; вопросов и карточек не больше запрошенного
+func quizSchema(questions, flashcards int) *JSONSchema {
+	one, minOptions, maxOptions := 1, models.MinQuizOptions, models.MaxQuizOptions
+	zero := 0.0
+
+	text := &JSONSchema{Type: "string", MinLength: &one}
+	segment := &JSONSchema{Type: "integer", Minimum: &zero}
+
+	return jsonObject(map[string]*JSONSchema{
+		"questions": {Type: "array", MinItems: &one, MaxItems: &questions, Items: jsonObject(map[string]*JSONSchema{
+			"question":    text,
+			"options":     {Type: "array", MinItems: &minOptions, MaxItems: &maxOptions, Items: text},
+			"answer":      {Type: "integer", Minimum: &zero},
+			"explanation": {Type: "string"},
+			"segment_id":  segment,
+		}, "question", "options", "answer")},
+		"flashcards": {Type: "array", MinItems: &one, MaxItems: &flashcards, Items: jsonObject(map[string]*JSONSchema{
+			"front":      text,
+			"back":       text,
+			"segment_id": segment,
+		}, "front", "back")},
+	}, "questions", "flashcards")
+}
 
-	return s.sendOpenRouterRequest(ctx, requestBody)
+// checkQuiz сверяет индекс верного ответа с вариантами — схема этого не проверяет
+func checkQuiz(quiz *models.Quiz) error {
+	for i, q := range quiz.Questions {
+		if q.Answer >= len(q.Options) {
+			return fmt.Errorf("$.questions[%d].answer: must be an index into options (0-%d)", i, len(q.Options)-1)
+		}
+	}
+	return nil
 }
 
-func (s *OpenRouterService) sendOpenRouterRequest(ctx context.Context, requestBody OpenRouterRequest) (string, error) {
-	jsonData, err := json.Marshal(requestBody)
+// Потолок пунктов в каждом разделе выводов
+const maxInsightItems = 20
+
+func (s *aiService) ExtractInsights(ctx context.Context, source VideoSource) (*models.VideoInsights, error) {
+	budget, limit := s.textBudget(config.LLMOperationInsights)
+	schema := insightsSchema()
+
+	prompt, err := s.prompts.Render(ctx, promptInsights, insightsPromptData{
+		VideoSource: source.fit(budget, limit),
+		Schema:      schema.String(),
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", s.config.BaseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	insights, model, err := completeJSON(ctx, s, config.LLMOperationInsights, prompt, schema, checkInsights)
 	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
+	}
+	insights.Model = model
+	return insights, nil
+}
+
+// insightsSchema — схема ответа с выводами; пустые разделы допустимы
+func insightsSchema() *JSONSchema {
+	one, limit := 1, maxInsightItems
+	zero := 0.0
+
+	text := &JSONSchema{Type: "string", MinLength: &one}
+	segment := &JSONSchema{Type: "integer", Minimum: &zero}
+	items := func(item *JSONSchema) *JSONSchema {
+		return &JSONSchema{Type: "array", MaxItems: &limit, Items: item}
+	}
+	point := jsonObject(map[string]*JSONSchema{"text": text, "segment_id": segment}, "text")
+
+	return jsonObject(map[string]*JSONSchema{
+		"key_points": items(point),
+		"action_items": items(jsonObject(map[string]*JSONSchema{
+			"task":       text,
+			"owner":      {Type: "string"},
+			"due_date":   {Type: "string"},
+			"segment_id": segment,
+		}, "task")),
+		"decisions":      items(point),
+		"open_questions": items(point),
+	}, "key_points", "action_items", "decisions", "open_questions")
+}
+
+// checkInsights проверяет формат сроков задач — схема его не проверяет
+func checkInsights(insights *models.VideoInsights) error {
+	for i, item := range insights.ActionItems {
+		if item.DueDate == "" {
+			continue
+		}
+		if _, err := time.Parse(models.DueDateLayout, item.DueDate); err != nil {
+			return fmt.Errorf("$.action_items[%d].due_date: must be a date in YYYY-MM-DD format or omitted", i)
+		}
+	}
+	return nil
+}
+
+// Потолок исходного текста в одном запросе перевода: перевод должен уместиться в ответ модели
+const translationChunkTokens = 700
+
+func (s *aiService) translationChunk(operation string) (ContextBudget, int) {
+	budget, limit := s.textBudget(operation)
+	return budget, min(translationChunkTokens, limit)
+}
+
+func (s *aiService) TranslateText(ctx context.Context, text, language string) (string, error) {
+	budget, limit := s.translationChunk(config.LLMOperationTranslate)
+
+	chunks := splitText(budget, text, limit)
+	translated := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		content, err := s.complete(ctx, config.LLMOperationTranslate, promptTranslateText, translatePromptData{
+			Language: language,
+			Text:     chunk,
+		})
+		if err != nil {
+			return "", err
+		}
+		translated = append(translated, strings.TrimSpace(content))
 	}
+	return strings.Join(translated, "\n\n"), nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.config.APIKey)
-	req.Header.Set("HTTP-Referer", "https://vidnotes.app")
-	req.Header.Set("X-Title", "VidNotes AI")
+// translatedSegment — сегмент в запросе и ответе модели
+type translatedSegment struct {
+	ID   int    `json:"id"`
+	Text string `json:"text"`
+}
 
-	resp, err := s.client.Do(req)
+type translatedSegments struct {
+	Segments []translatedSegment `json:"segments"`
+}
+
+func (s *aiService) TranslateSegments(ctx context.Context, segments []models.TranscriptSegment, language string) ([]models.TranscriptSegment, error) {
+	budget, limit := s.translationChunk(config.LLMOperationTranslate)
+
+	translated := make([]models.TranscriptSegment, 0, len(segments))
+	for start := 0; start < len(segments); {
+		// Часть — сколько сегментов помещается в лимит, но не меньше одного
+		end, tokens := start, 0
+		for end < len(segments) && (end == start || tokens+budget.Count(segments[end].Text) <= limit) {
+			tokens += budget.Count(segments[end].Text)
+			end++
+		}
+		chunk := segments[start:end]
+
+		texts, err := s.translateSegmentChunk(ctx, chunk, language)
+		if err != nil {
+			return nil, err
+		}
+		for i, seg := range chunk {
+			seg.Text = texts[i]
+			translated = append(translated, seg)
+		}
+		start = end
+	}
+	return translated, nil
+}
+
+// translateSegmentChunk возвращает переводы сегментов части по порядку
+func (s *aiService) translateSegmentChunk(ctx context.Context, chunk []models.TranscriptSegment, language string) ([]string, error) {
+	source := translatedSegments{Segments: make([]translatedSegment, len(chunk))}
+	for i, seg := range chunk {
+		source.Segments[i] = translatedSegment{ID: seg.ID, Text: strings.TrimSpace(seg.Text)}
+	}
+	data, err := json.Marshal(source)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	count, zero := len(chunk), 0.0
+	schema := jsonObject(map[string]*JSONSchema{
+		"segments": {Type: "array", MinItems: &count, MaxItems: &count, Items: jsonObject(map[string]*JSONSchema{
+			"id":   {Type: "integer", Minimum: &zero},
+			"text": {Type: "string"},
+		}, "id", "text")},
+	}, "segments")
+
+	prompt, err := s.prompts.Render(ctx, promptTranslateSegs, translatePromptData{
+		Language: language,
+		Segments: string(data),
+		Schema:   schema.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Номера должны совпасть с исходными по порядку, иначе таймкоды разъедутся
+	result, _, err := completeJSON(ctx, s, config.LLMOperationTranslate, prompt, schema, func(out *translatedSegments) error {
+		for i, seg := range out.Segments {
+			if seg.ID != chunk[i].ID {
+				return fmt.Errorf("$.segments[%d].id: expected %d, got %d", i, chunk[i].ID, seg.ID)
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return nil, err
+	}
+
+	texts := make([]string, len(result.Segments))
+	for i, seg := range result.Segments {
+		texts[i] = strings.TrimSpace(seg.Text)
 	}
+	return texts, nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		var errorResp OpenRouterResponse
-		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != nil {
-			return "", fmt.Errorf("openrouter error: %s", errorResp.Error.Message)
+// splitText делит текст на части не больше limit токенов по абзацам; слишком
+// длинный абзац делится по словам
+func splitText(budget ContextBudget, text string, limit int) []string {
+	var chunks []string
+	var current []string
+	tokens := 0
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, "\n\n"))
+			current, tokens = nil, 0
 		}
-		return "", fmt.Errorf("openrouter API error: %s", string(body))
 	}
 
-	var openRouterResp OpenRouterResponse
-	if err := json.Unmarshal(body, &openRouterResp); err != nil {
-		return "", fmt.Errorf("failed to parse response: %w", err)
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		paragraph = strings.TrimSpace(paragraph)
+		if paragraph == "" {
+			continue
+		}
+
+		count := budget.Count(paragraph)
+		if count > limit {
+			flush()
+			chunks = append(chunks, splitLongParagraph(budget, paragraph, limit)...)
+			continue
+		}
+		if tokens+count > limit {
+			flush()
+		}
+		current = append(current, paragraph)
+		tokens += count
 	}
+	flush()
+	return chunks
+}
 
-	if len(openRouterResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+func splitLongParagraph(budget ContextBudget, paragraph string, limit int) []string {
+	var chunks []string
+	var current []string
+	tokens := 0
+	for _, word := range strings.Fields(paragraph) {
+		count := budget.Count(word + " ")
+		if tokens+count > limit && len(current) > 0 {
+			chunks = append(chunks, strings.Join(current, " "))
+			current, tokens = nil, 0
+		}
+		current = append(current, word)
+		tokens += count
 	}
+	if len(current) > 0 {
+		chunks = append(chunks, strings.Join(current, " "))
+	}
+	return chunks
+}
+
+// cleanTitle оставляет первую строку ответа модели без кавычек, разметки и точки в конце
+func cleanTitle(content string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(content), "\n")
+	title = strings.TrimLeft(title, "# ")
+	title = strings.TrimPrefix(title, "Title:")
+	title = strings.Trim(strings.TrimSpace(title), "\"'`«»“”*.")
+	title = strings.TrimSpace(title)
+
+	if runes := []rune(title); len(runes) > models.MaxSessionTitleLength {
+		title = strings.TrimSpace(string(runes[:models.MaxSessionTitleLength]))
+	}
+	return title
+}
 
-	return openRouterResp.Choices[0].Message.Content, nil
+// parseTagList разбирает JSON-массив из ответа модели; если модель ответила
+// не JSON, теги берутся из строк или значений через запятую
+func parseTagList(content string) []string {
+	content = strings.TrimSpace(content)
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
+
+	if start, end := strings.Index(content, "["), strings.LastIndex(content, "]"); start >= 0 && end > start {
+		var tags []string
+		if err := json.Unmarshal([]byte(content[start:end+1]), &tags); err == nil {
+			return tags
+		}
+	}
+
+	return strings.FieldsFunc(content, func(r rune) bool {
+		return r == ',' || r == '\n'
+	})
 }
@@ -0,0 +1,89 @@
+// services/ai_usage.go
+package services
+
+import (
+	"context"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AIUsageMeter проверяет месячный лимит токенов и сохраняет расход запросов к LLM
+type AIUsageMeter interface {
+	CheckAIQuota(ctx context.Context, userID primitive.ObjectID) error
+	RecordAIUsage(ctx context.Context, record *models.AIUsageRecord) error
+}
+
+// AIUsageScope — на чей счёт идут запросы к LLM
+type AIUsageScope struct {
+	UserID    primitive.ObjectID
+	SessionID *primitive.ObjectID
+}
+
+type usageScopeKey struct{}
+
+// WithAIUsageScope помечает контекст пользователем и сессией для учёта расхода
+func WithAIUsageScope(ctx context.Context, userID primitive.ObjectID, sessionID *primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, usageScopeKey{}, AIUsageScope{UserID: userID, SessionID: sessionID})
+}
+
+func usageScopeFrom(ctx context.Context) (AIUsageScope, bool) {
+	scope, ok := ctx.Value(usageScopeKey{}).(AIUsageScope)
+	return scope, ok && !scope.UserID.IsZero()
+}
+
+// modelPrice — цена модели в USD за миллион токенов
+type modelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Цены известных моделей для оценки стоимости, когда провайдер её не сообщает;
+// локальные и неизвестные модели считаются бесплатными
+var knownModelPrices = map[string]modelPrice{
+	"gpt-3.5-turbo":     {0.5, 1.5},
+	"gpt-4":             {30, 60},
+	"gpt-4-turbo":       {10, 30},
+	"gpt-4o":            {2.5, 10},
+	"gpt-4o-mini":       {0.15, 0.6},
+	"gpt-4.1":           {2, 8},
+	"gpt-4.1-mini":      {0.4, 1.6},
+	"gpt-4.1-nano":      {0.1, 0.4},
+	"claude-3-haiku":    {0.25, 1.25},
+	"claude-3.5-haiku":  {0.8, 4},
+	"claude-3.5-sonnet": {3, 15},
+	"gemini-1.5-flash":  {0.075, 0.3},
+	"gemini-1.5-pro":    {1.25, 5},
+}
+
+// usageRecord собирает запись расхода; если провайдер не сообщил токены,
+// они оцениваются по тексту запроса и ответа
+func usageRecord(scope AIUsageScope, operation string, messages []ChatMessage, result ChatResult) *models.AIUsageRecord {
+	record := &models.AIUsageRecord{
+		UserID:           scope.UserID,
+		SessionID:        scope.SessionID,
+		Operation:        operation,
+		Model:            result.Model,
+		PromptTokens:     result.Usage.PromptTokens,
+		CompletionTokens: result.Usage.CompletionTokens,
+		Cost:             result.Usage.Cost,
+	}
+
+	if record.PromptTokens == 0 && record.CompletionTokens == 0 {
+		tokenizer := estimateTokenizer{}
+		for _, msg := range messages {
+			record.PromptTokens += tokenizer.Count(msg.Content) + messageOverheadTokens
+		}
+		record.CompletionTokens = tokenizer.Count(result.Content)
+		record.Estimated = true
+	}
+	record.TotalTokens = record.PromptTokens + record.CompletionTokens
+
+	if record.Cost == 0 {
+		if price, ok := lookupModel(knownModelPrices, result.Model); ok {
+			record.Cost = (float64(record.PromptTokens)*price.Prompt + float64(record.CompletionTokens)*price.Completion) / 1e6
+		}
+	}
+
+	return record
+}
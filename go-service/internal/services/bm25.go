@@ -0,0 +1,86 @@
+// services/bm25.go
+package services
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+	// Грубый стемминг: слова обрезаются до этой длины, чтобы формы слова совпадали
+	bm25StemRunes = 6
+	// Константа reciprocal rank fusion
+	rrfK = 60
+)
+
+// bm25Terms разбивает текст на нормализованные термы; слова короче 3 рун отбрасываются
+func bm25Terms(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	terms := words[:0]
+	for _, word := range words {
+		runes := []rune(word)
+		if len(runes) < 3 {
+			continue
+		}
+		if len(runes) > bm25StemRunes {
+			word = string(runes[:bm25StemRunes])
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+// bm25Scores считает BM25 запроса для каждого документа набора
+func bm25Scores(query string, docs []string) []float64 {
+	queryTerms := bm25Terms(query)
+	scores := make([]float64, len(docs))
+	if len(queryTerms) == 0 || len(docs) == 0 {
+		return scores
+	}
+
+	termFreqs := make([]map[string]int, len(docs))
+	docFreq := make(map[string]int)
+	totalLen := 0
+	for i, doc := range docs {
+		terms := bm25Terms(doc)
+		totalLen += len(terms)
+		tf := make(map[string]int, len(terms))
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term := range tf {
+			docFreq[term]++
+		}
+		termFreqs[i] = tf
+	}
+
+	avgLen := float64(totalLen) / float64(len(docs))
+	if avgLen == 0 {
+		return scores
+	}
+
+	n := float64(len(docs))
+	for i, tf := range termFreqs {
+		docLen := 0
+		for _, c := range tf {
+			docLen += c
+		}
+		for _, term := range queryTerms {
+			f := float64(tf[term])
+			if f == 0 {
+				continue
+			}
+			df := float64(docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			scores[i] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*float64(docLen)/avgLen))
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBM25Terms(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: []string{}},
+		{name: "short words dropped", text: "я и он в ИИ", want: []string{}},
+		{name: "lowercase and punctuation", text: "Бюджет, СРОКИ; api!", want: []string{"бюджет", "сроки", "api"}},
+		{name: "stemmed to prefix", text: "презентация презентации", want: []string{"презен", "презен"}},
+		{name: "digits kept", text: "релиз 2025 v2", want: []string{"релиз", "2025"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bm25Terms(tt.text)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("bm25Terms(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25Scores(t *testing.T) {
+	docs := []string{
+		"Обсудили бюджет проекта и сроки релиза",
+		"Команда выбрала новый дизайн главной страницы",
+		"Бюджет бюджет бюджет: финальное согласование бюджета",
+		"",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		// Индексы документов по убыванию оценки; остальные должны получить ноль
+		wantOrder []int
+	}{
+		{name: "empty query", query: "", wantOrder: nil},
+		{name: "only short words", query: "и в на", wantOrder: nil},
+		{name: "no match", query: "отпуск", wantOrder: nil},
+		{name: "term frequency wins", query: "бюджет", wantOrder: []int{2, 0}},
+		{name: "word forms match", query: "дизайна страниц", wantOrder: []int{1}},
+		{name: "more matched terms win", query: "сроки бюджета", wantOrder: []int{0, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scores := bm25Scores(tt.query, docs)
+			if len(scores) != len(docs) {
+				t.Fatalf("got %d scores, want %d", len(scores), len(docs))
+			}
+
+			matched := make(map[int]bool)
+			for i, idx := range tt.wantOrder {
+				matched[idx] = true
+				if scores[idx] <= 0 {
+					t.Errorf("doc %d score = %v, want > 0", idx, scores[idx])
+				}
+				if i > 0 && scores[tt.wantOrder[i-1]] <= scores[idx] {
+					t.Errorf("doc %d (%v) should rank above doc %d (%v)",
+						tt.wantOrder[i-1], scores[tt.wantOrder[i-1]], idx, scores[idx])
+				}
+			}
+			for i, score := range scores {
+				if !matched[i] && score != 0 {
+					t.Errorf("doc %d score = %v, want 0", i, score)
+				}
+			}
+		})
+	}
+}
+
+func TestAddRanking(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []float64
+		want   []float64
+	}{
+		{name: "all zero", scores: []float64{0, 0}, want: []float64{0, 0}},
+		{
+			name:   "ranked by score",
+			scores: []float64{0.2, 0, 0.9},
+			want:   []float64{1.0 / (rrfK + 2), 0, 1.0 / (rrfK + 1)},
+		},
+		{
+			name:   "ties keep order",
+			scores: []float64{0.5, 0.5},
+			want:   []float64{1.0 / (rrfK + 1), 1.0 / (rrfK + 2)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fused := make([]float64, len(tt.scores))
+			addRanking(fused, tt.scores)
+			if !reflect.DeepEqual(fused, tt.want) {
+				t.Errorf("addRanking(%v) = %v, want %v", tt.scores, fused, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// services/chunker.go
+package services
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+const (
+	chunkMaxRunes = 800
+	// Сколько последних сегментов транскрипта повторять в начале следующего чанка
+	chunkOverlapSegments = 1
+)
+
+// chunkVideo разбивает саммари и транскрипт видео на фрагменты для эмбеддингов.
+// Чанки транскрипта собираются из целых сегментов и сохраняют их таймкоды.
+func chunkVideo(video *models.Video) []*models.ContentChunk {
+	var chunks []*models.ContentChunk
+
+	for i, text := range chunkText(video.Summary, chunkMaxRunes) {
+		chunks = append(chunks, &models.ContentChunk{
+			UserID:  video.UserID,
+			VideoID: video.ID,
+			Source:  models.ChunkSourceSummary,
+			Index:   i,
+			Text:    text,
+		})
+	}
+
+	var transcript []*models.ContentChunk
+	if len(video.Segments) > 0 {
+		transcript = chunkSegments(video.Segments, chunkMaxRunes, chunkOverlapSegments)
+	} else {
+		for _, text := range chunkText(video.Transcript, chunkMaxRunes) {
+			transcript = append(transcript, &models.ContentChunk{Text: text})
+		}
+	}
+
+	for i, chunk := range transcript {
+		chunk.UserID = video.UserID
+		chunk.VideoID = video.ID
+		chunk.Source = models.ChunkSourceTranscript
+		chunk.Index = i
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks
+}
+
+func chunkSegments(segments []models.TranscriptSegment, maxRunes, overlap int) []*models.ContentChunk {
+	var chunks []*models.ContentChunk
+
+	for start := 0; start < len(segments); {
+		end := start
+		size := 0
+		for end < len(segments) {
+			n := utf8.RuneCountInString(segments[end].Text) + 1
+			if size > 0 && size+n > maxRunes {
+				break
+			}
+			size += n
+			end++
+		}
+
+		texts := make([]string, 0, end-start)
+		for _, seg := range segments[start:end] {
+			texts = append(texts, strings.TrimSpace(seg.Text))
+		}
+
+		chunks = append(chunks, &models.ContentChunk{
+			Text:  strings.Join(texts, " "),
+			Start: segments[start].Start,
+			End:   segments[end-1].End,
+		})
+
+		if end == len(segments) {
+			break
+		}
+		// Перекрытие не должно останавливать продвижение вперёд
+		start = max(end-overlap, start+1)
+	}
+
+	return chunks
+}
+
+// chunkText собирает предложения в фрагменты не длиннее maxRunes.
+// Предложение длиннее лимита режется по границе слова.
+func chunkText(text string, maxRunes int) []string {
+	var chunks []string
+	var current strings.Builder
+	size := 0
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			chunks = append(chunks, s)
+		}
+		current.Reset()
+		size = 0
+	}
+
+	for _, sentence := range splitSentences(text) {
+		for sentence != "" {
+			n := utf8.RuneCountInString(sentence)
+			if size > 0 && size+n+1 > maxRunes {
+				flush()
+			}
+			if n <= maxRunes {
+				if size > 0 {
+					current.WriteByte(' ')
+					size++
+				}
+				current.WriteString(sentence)
+				size += n
+				sentence = ""
+				continue
+			}
+
+			head, tail := truncateRunesAtWord(sentence, maxRunes)
+			current.WriteString(head)
+			flush()
+			sentence = strings.TrimSpace(tail)
+		}
+	}
+	flush()
+
+	return chunks
+}
+
+// splitSentences делит текст на предложения по .!? и переводам строк
+func splitSentences(text string) []string {
+	var sentences []string
+	runes := []rune(text)
+	start := 0
+
+	for i, r := range runes {
+		end := -1
+		switch {
+		case r == '\n':
+			end = i
+		case r == '.' || r == '!' || r == '?' || r == '…':
+			if i+1 == len(runes) || unicode.IsSpace(runes[i+1]) {
+				end = i + 1
+			}
+		}
+		if end < 0 {
+			continue
+		}
+
+		if s := strings.TrimSpace(string(runes[start:end])); s != "" {
+			sentences = append(sentences, s)
+		}
+		start = max(end, i+1)
+	}
+
+	if s := strings.TrimSpace(string(runes[start:])); s != "" {
+		sentences = append(sentences, s)
+	}
+	return sentences
+}
+
+// truncateRunesAtWord обрезает строку до maxRunes рун, не разрывая слово, если это возможно
+func truncateRunesAtWord(s string, maxRunes int) (head, tail string) {
+	runes := []rune(s)
+	if len(runes) <= maxRunes {
+		return s, ""
+	}
+
+	cut := maxRunes
+	for i := maxRunes; i > maxRunes/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return string(runes[:cut]), string(runes[cut:])
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func TestSplitSentences(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "only spaces", text: "  \n\t ", want: nil},
+		{name: "no terminator", text: "просто текст", want: []string{"просто текст"}},
+		{
+			name: "terminators",
+			text: "Первое. Второе! Третье? Четвёртое…",
+			want: []string{"Первое.", "Второе!", "Третье?", "Четвёртое…"},
+		},
+		{
+			name: "dot inside token",
+			text: "Версия 1.5 вышла. Смотри example.com сегодня",
+			want: []string{"Версия 1.5 вышла.", "Смотри example.com сегодня"},
+		},
+		{
+			name: "newlines",
+			text: "строка один\nстрока два\n\nстрока три",
+			want: []string{"строка один", "строка два", "строка три"},
+		},
+		{name: "repeated terminators", text: "Что?! Да.", want: []string{"Что?!", "Да."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitSentences(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitSentences(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkText(t *testing.T) {
+	tenWords := strings.TrimSpace(strings.Repeat("слово ", 10))
+
+	tests := []struct {
+		name     string
+		text     string
+		maxRunes int
+		want     []string
+	}{
+		{name: "empty", text: "", maxRunes: 20, want: nil},
+		{name: "fits", text: "Раз. Два.", maxRunes: 20, want: []string{"Раз. Два."}},
+		{
+			name:     "sentences grouped",
+			text:     "Первое. Второе. Третье.",
+			maxRunes: 16,
+			want:     []string{"Первое. Второе.", "Третье."},
+		},
+		{
+			name:     "long sentence cut at word",
+			text:     "alpha beta gamma delta",
+			maxRunes: 12,
+			want:     []string{"alpha beta", "gamma delta"},
+		},
+		{
+			name:     "word longer than limit",
+			text:     "abcdefghij",
+			maxRunes: 4,
+			want:     []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name:     "exact limit with separator",
+			text:     "aaaa. bbbb.",
+			maxRunes: 10,
+			want:     []string{"aaaa.", "bbbb."},
+		},
+		{
+			name:     "limit counts runes, not bytes",
+			text:     strings.Repeat("слово ", 30),
+			maxRunes: 60,
+			want:     []string{tenWords, tenWords, tenWords},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := chunkText(tt.text, tt.maxRunes)
+			for _, chunk := range got {
+				if n := utf8.RuneCountInString(chunk); n > tt.maxRunes {
+					t.Errorf("chunk %q has %d runes, limit %d", chunk, n, tt.maxRunes)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chunkText(%q, %d) = %q, want %q", tt.text, tt.maxRunes, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkSegments(t *testing.T) {
+	segments := []models.TranscriptSegment{
+		{Start: 0, End: 2, Text: "aaaa"},
+		{Start: 2, End: 4, Text: "bbbb"},
+		{Start: 4, End: 6, Text: "cccc"},
+		{Start: 6, End: 8, Text: "dddd"},
+	}
+
+	tests := []struct {
+		name      string
+		maxRunes  int
+		overlap   int
+		wantTexts []string
+		wantSpans [][2]float64
+	}{
+		{
+			name:      "single chunk",
+			maxRunes:  100,
+			wantTexts: []string{"aaaa bbbb cccc dddd"},
+			wantSpans: [][2]float64{{0, 8}},
+		},
+		{
+			name:      "no overlap",
+			maxRunes:  10,
+			wantTexts: []string{"aaaa bbbb", "cccc dddd"},
+			wantSpans: [][2]float64{{0, 4}, {4, 8}},
+		},
+		{
+			name:      "overlap repeats last segment",
+			maxRunes:  10,
+			overlap:   1,
+			wantTexts: []string{"aaaa bbbb", "bbbb cccc", "cccc dddd"},
+			wantSpans: [][2]float64{{0, 4}, {2, 6}, {4, 8}},
+		},
+		{
+			name:      "overlap never stalls",
+			maxRunes:  1,
+			overlap:   3,
+			wantTexts: []string{"aaaa", "bbbb", "cccc", "dddd"},
+			wantSpans: [][2]float64{{0, 2}, {2, 4}, {4, 6}, {6, 8}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := chunkSegments(segments, tt.maxRunes, tt.overlap)
+
+			texts := make([]string, len(chunks))
+			spans := make([][2]float64, len(chunks))
+			for i, c := range chunks {
+				texts[i] = c.Text
+				spans[i] = [2]float64{c.Start, c.End}
+			}
+			if !reflect.DeepEqual(texts, tt.wantTexts) {
+				t.Errorf("texts = %q, want %q", texts, tt.wantTexts)
+			}
+			if !reflect.DeepEqual(spans, tt.wantSpans) {
+				t.Errorf("spans = %v, want %v", spans, tt.wantSpans)
+			}
+		})
+	}
+}
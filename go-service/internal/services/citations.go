@@ -0,0 +1,181 @@
+// services/citations.go
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Ссылка в ответе модели: [S12] или [S12: "точная цитата"], в сессиях по нескольким
+// видео — с меткой видео: [V2 S12]. Шаблон захватывает и пробел перед ссылкой,
+// чтобы удалённая ссылка не оставляла двойной пробел.
+var citationPattern = regexp.MustCompile(`( ?)\[(?:(V\d+) )?S(\d+)(?::\s*["“«]([^"”»\]]*)["”»])?\]`)
+
+// CitedVideo — видео, на сегменты которого может ссылаться ответ
+type CitedVideo struct {
+	ID       primitive.ObjectID
+	Label    string
+	Segments []models.TranscriptSegment
+}
+
+// CitableExcerpts превращает найденные чанки в фрагменты транскрипта, где каждая
+// строка — сегмент с номером и таймкодами, на который модель может сослаться.
+// Без сегментов фрагмент остаётся текстом чанка.
+func CitableExcerpts(video *models.Video, label string, chunks []*models.ContentChunk) []string {
+	excerpts := make([]string, 0, len(chunks))
+	for _, chunk := range chunks {
+		var lines []string
+		for _, seg := range video.Segments {
+			if seg.Start >= chunk.Start && seg.End <= chunk.End {
+				lines = append(lines, formatSegment(label, seg))
+			}
+		}
+		if len(lines) == 0 {
+			excerpts = append(excerpts, chunk.Text)
+			continue
+		}
+		excerpts = append(excerpts, strings.Join(lines, "\n"))
+	}
+	return excerpts
+}
+
+// TranscriptExcerpt — весь транскрипт в том же формате, что и CitableExcerpts
+func TranscriptExcerpt(video *models.Video, label string) string {
+	if len(video.Segments) == 0 {
+		return video.Transcript
+	}
+
+	lines := make([]string, len(video.Segments))
+	for i, seg := range video.Segments {
+		lines[i] = formatSegment(label, seg)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatSegment(label string, seg models.TranscriptSegment) string {
+	if label != "" {
+		label += " "
+	}
+	return fmt.Sprintf("[%sS%d %s–%s] %s", label, seg.ID, formatTimestamp(seg.Start), formatTimestamp(seg.End), strings.TrimSpace(seg.Text))
+}
+
+// formatTimestamp печатает секунды как mm:ss или h:mm:ss
+func formatTimestamp(seconds float64) string {
+	total := int(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total%3600/60, total%60)
+	}
+	return fmt.Sprintf("%02d:%02d", total/60, total%60)
+}
+
+// NewVideoSource собирает саммари и транскрипт с номерами сегментов для
+// структурированной генерации; у видео без них — ErrNoVideoContent
+func NewVideoSource(video *models.Video) (VideoSource, error) {
+	if strings.TrimSpace(video.Transcript) == "" && strings.TrimSpace(video.Summary) == "" {
+		return VideoSource{}, models.ErrNoVideoContent
+	}
+
+	source := VideoSource{Title: video.Title, Summary: video.Summary}
+	if video.Transcript != "" {
+		source.Transcript = TranscriptExcerpt(video, "")
+	}
+	return source, nil
+}
+
+// segmentStarts — начало каждого сегмента транскрипта по номеру
+type segmentStarts map[int]float64
+
+func newSegmentStarts(video *models.Video) segmentStarts {
+	starts := make(segmentStarts, len(video.Segments))
+	for _, seg := range video.Segments {
+		starts[seg.ID] = seg.Start
+	}
+	return starts
+}
+
+// resolve возвращает номер сегмента с его началом; ссылку на несуществующий сегмент — nil
+func (s segmentStarts) resolve(id *int) (*int, *float64) {
+	if id == nil {
+		return nil, nil
+	}
+	start, ok := s[*id]
+	if !ok {
+		return nil, nil
+	}
+	return id, &start
+}
+
+// ResolveCitations сверяет ссылки ответа с транскриптами видео. Ссылки на
+// несуществующие сегменты и ссылки без метки, когда видео несколько, удаляются
+// из текста, остальные приводятся к виду [S12] или [V2 S12]. Цитата, которой
+// нет в тексте сегмента, заменяется самим сегментом.
+func ResolveCitations(answer string, videos []CitedVideo) (string, []models.AICitation) {
+	type segmentKey struct {
+		label string
+		id    int
+	}
+
+	byKey := make(map[segmentKey]models.TranscriptSegment)
+	videoIDs := make(map[string]primitive.ObjectID, len(videos))
+	for _, video := range videos {
+		videoIDs[video.Label] = video.ID
+		for _, seg := range video.Segments {
+			byKey[segmentKey{video.Label, seg.ID}] = seg
+		}
+	}
+	if len(byKey) == 0 {
+		return answer, nil
+	}
+
+	var citations []models.AICitation
+	cited := make(map[segmentKey]bool)
+
+	content := citationPattern.ReplaceAllStringFunc(answer, func(match string) string {
+		parts := citationPattern.FindStringSubmatch(match)
+		id, err := strconv.Atoi(parts[3])
+		if err != nil {
+			return ""
+		}
+		key := segmentKey{parts[2], id}
+		seg, ok := byKey[key]
+		if !ok {
+			return ""
+		}
+
+		if !cited[key] {
+			cited[key] = true
+			quote := strings.TrimSpace(parts[4])
+			if quote == "" || !strings.Contains(normalizeQuote(seg.Text), normalizeQuote(quote)) {
+				quote = strings.TrimSpace(seg.Text)
+			}
+			citations = append(citations, models.AICitation{
+				VideoID:   videoIDs[key.label],
+				SegmentID: seg.ID,
+				Start:     seg.Start,
+				End:       seg.End,
+				Quote:     quote,
+			})
+		}
+
+		if key.label != "" {
+			return parts[1] + "[" + key.label + " S" + parts[3] + "]"
+		}
+		return parts[1] + "[S" + parts[3] + "]"
+	})
+
+	return content, citations
+}
+
+// normalizeQuote оставляет буквы и цифры в нижнем регистре, разделяя слова одним пробелом
+func normalizeQuote(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, " ")
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds float64
+		want    string
+	}{
+		{seconds: 0, want: "00:00"},
+		{seconds: 65.9, want: "01:05"},
+		{seconds: 3599, want: "59:59"},
+		{seconds: 3600, want: "1:00:00"},
+		{seconds: 7384, want: "2:03:04"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := formatTimestamp(tt.seconds); got != tt.want {
+				t.Errorf("formatTimestamp(%v) = %q, want %q", tt.seconds, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeQuote(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{text: "", want: ""},
+		{text: "  Бюджет — утверждён!  ", want: "бюджет утверждён"},
+		{text: "«Сроки»,\nрелиз 2.0", want: "сроки релиз 2 0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := normalizeQuote(tt.text); got != tt.want {
+				t.Errorf("normalizeQuote(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTranscriptExcerpt(t *testing.T) {
+	segments := []models.TranscriptSegment{
+		{ID: 1, Start: 0, End: 4.5, Text: " Добрый день. "},
+		{ID: 2, Start: 4.5, End: 3700, Text: "Начнём с бюджета."},
+	}
+
+	tests := []struct {
+		name  string
+		video *models.Video
+		label string
+		want  string
+	}{
+		{name: "no segments", video: &models.Video{Transcript: "сплошной текст"}, want: "сплошной текст"},
+		{
+			name:  "segments",
+			video: &models.Video{Transcript: "x", Segments: segments},
+			want:  "[S1 00:00–00:04] Добрый день.\n[S2 00:04–1:01:40] Начнём с бюджета.",
+		},
+		{
+			name:  "labeled",
+			video: &models.Video{Transcript: "x", Segments: segments[:1]},
+			label: "V2",
+			want:  "[V2 S1 00:00–00:04] Добрый день.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TranscriptExcerpt(tt.video, tt.label); got != tt.want {
+				t.Errorf("TranscriptExcerpt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCitations(t *testing.T) {
+	first := primitive.NewObjectID()
+	second := primitive.NewObjectID()
+	segments := []models.TranscriptSegment{
+		{ID: 1, Start: 0, End: 5, Text: "Бюджет проекта утверждён на квартал."},
+		{ID: 2, Start: 5, End: 9, Text: "Релиз переносится на март."},
+	}
+	single := []CitedVideo{{ID: first, Segments: segments}}
+	multi := []CitedVideo{
+		{ID: first, Label: "V1", Segments: segments},
+		{ID: second, Label: "V2", Segments: segments[1:]},
+	}
+
+	tests := []struct {
+		name          string
+		answer        string
+		videos        []CitedVideo
+		wantContent   string
+		wantCitations []models.AICitation
+	}{
+		{
+			name:        "no segments",
+			answer:      "Ответ [S1].",
+			videos:      []CitedVideo{{ID: first}},
+			wantContent: "Ответ [S1].",
+		},
+		{
+			name:        "plain reference",
+			answer:      "Бюджет утверждён [S1].",
+			videos:      single,
+			wantContent: "Бюджет утверждён [S1].",
+			wantCitations: []models.AICitation{
+				{VideoID: first, SegmentID: 1, Start: 0, End: 5, Quote: "Бюджет проекта утверждён на квартал."},
+			},
+		},
+		{
+			name:        "matching quote kept",
+			answer:      `Релиз сдвинут [S2: «переносится НА март»].`,
+			videos:      single,
+			wantContent: "Релиз сдвинут [S2].",
+			wantCitations: []models.AICitation{
+				{VideoID: first, SegmentID: 2, Start: 5, End: 9, Quote: "переносится НА март"},
+			},
+		},
+		{
+			name:        "invented quote replaced",
+			answer:      `Релиз сдвинут [S2: "релиз отменён"].`,
+			videos:      single,
+			wantContent: "Релиз сдвинут [S2].",
+			wantCitations: []models.AICitation{
+				{VideoID: first, SegmentID: 2, Start: 5, End: 9, Quote: "Релиз переносится на март."},
+			},
+		},
+		{
+			name:        "unknown segment removed",
+			answer:      "Такого не было [S7], зато было [S1] и [S1].",
+			videos:      single,
+			wantContent: "Такого не было, зато было [S1] и [S1].",
+			wantCitations: []models.AICitation{
+				{VideoID: first, SegmentID: 1, Start: 0, End: 5, Quote: "Бюджет проекта утверждён на квартал."},
+			},
+		},
+		{
+			name:        "labeled references",
+			answer:      "Бюджет [V1 S1], релиз [V2 S2], без метки [S2], чужой [V2 S1].",
+			videos:      multi,
+			wantContent: "Бюджет [V1 S1], релиз [V2 S2], без метки, чужой.",
+			wantCitations: []models.AICitation{
+				{VideoID: first, SegmentID: 1, Start: 0, End: 5, Quote: "Бюджет проекта утверждён на квартал."},
+				{VideoID: second, SegmentID: 2, Start: 5, End: 9, Quote: "Релиз переносится на март."},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, citations := ResolveCitations(tt.answer, tt.videos)
+			if content != tt.wantContent {
+				t.Errorf("content = %q, want %q", content, tt.wantContent)
+			}
+			if !reflect.DeepEqual(citations, tt.wantCitations) {
+				t.Errorf("citations = %+v, want %+v", citations, tt.wantCitations)
+			}
+		})
+	}
+}
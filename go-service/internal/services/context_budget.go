@@ -0,0 +1,187 @@
+// services/context_budget.go
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/code-zt/vidnotes/config"
+)
+
+// Tokenizer считает токены текста. Сейчас единственная реализация —
+// estimateTokenizer: это оценка, а не настоящий BPE-токенизатор модели
+type Tokenizer interface {
+	Count(text string) int
+}
+
+// estimateTokenizer не токенизирует текст, а оценивает число токенов по классам
+// символов, приближая BPE-токенизаторы (cl100k и похожие) без словаря:
+// латиница ≈ 4 символа на токен, кириллица и прочие алфавиты ≈ 2, цифры ≈ 3,
+// знаки препинания — по токену. Оценка намеренно чуть завышена, чтобы запрос
+// не превысил окно модели; точный подсчёт подключается через Tokenizer.
+type estimateTokenizer struct{}
+
+func (estimateTokenizer) Count(text string) int {
+	tokens := 0
+	var latin, other, digits int
+	flush := func() {
+		tokens += ceilDiv(latin, 4) + ceilDiv(other, 2) + ceilDiv(digits, 3)
+		latin, other, digits = 0, 0, 0
+	}
+
+	for _, r := range text {
+		switch {
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			latin++
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			other++
+		case unicode.IsDigit(r):
+			digits++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+func ceilDiv(a, b int) int {
+	return (a + b - 1) / b
+}
+
+const defaultContextWindow = 8192
+
+// Окна контекста известных моделей; ищется самый длинный префикс имени без провайдера
+var knownContextWindows = map[string]int{
+	"gpt-3.5-turbo": 16385,
+	"gpt-4":         8192,
+	"gpt-4-turbo":   128000,
+	"gpt-4o":        128000,
+	"gpt-4.1":       1047576,
+	"claude":        200000,
+	"gemini":        1000000,
+	"llama3":        8192,
+	"llama3.1":      131072,
+	"llama-3.1":     131072,
+	"mistral":       32768,
+	"qwen2.5":       32768,
+	"fake":          4096,
+}
+
+func contextWindowFor(model string) int {
+	if window, ok := lookupModel(knownContextWindows, model); ok {
+		return window
+	}
+	return defaultContextWindow
+}
+
+// lookupModel ищет значение по самому длинному префиксу имени модели без провайдера
+func lookupModel[T any](table map[string]T, model string) (T, bool) {
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	model = strings.ToLower(model)
+
+	var value T
+	matched := 0
+	for prefix, v := range table {
+		if strings.HasPrefix(model, prefix) && len(prefix) > matched {
+			value, matched = v, len(prefix)
+		}
+	}
+	return value, matched > 0
+}
+
+// ContextBudget — сколько токенов входа доступно модели за один запрос;
+// токены текста считаются оценкой tokenizer
+type ContextBudget struct {
+	tokenizer Tokenizer
+	Input     int
+}
+
+func newContextBudget(cfg *config.LLMConfig, model string) ContextBudget {
+	window := cfg.ContextWindow
+	if window <= 0 {
+		window = contextWindowFor(model)
+	}
+
+	input := window - cfg.ResponseTokens
+	if cfg.MaxInputTokens > 0 && input > cfg.MaxInputTokens {
+		input = cfg.MaxInputTokens
+	}
+
+	return ContextBudget{tokenizer: estimateTokenizer{}, Input: input}
+}
+
+func (b ContextBudget) Count(text string) int {
+	return b.tokenizer.Count(text)
+}
+
+const truncationMark = " …"
+
+// Truncate укладывает текст в limit токенов. Обрезка идёт по границе предложения,
+// если она есть в последней четверти, иначе по границе слова; руны не разрываются.
+func (b ContextBudget) Truncate(text string, limit int) string {
+	if limit <= 0 {
+		return ""
+	}
+	if b.Count(text) <= limit {
+		return text
+	}
+
+	limit -= b.Count(truncationMark)
+	if limit <= 0 {
+		return ""
+	}
+
+	// Бинарный поиск самого длинного префикса, укладывающегося в лимит
+	runes := []rune(text)
+	lo, hi := 0, len(runes)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if b.Count(string(runes[:mid])) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	prefix := runes[:lo]
+
+	cut := len(prefix)
+	minCut := len(prefix) * 3 / 4
+	if i := lastSentenceEnd(prefix); i >= minCut && i > 0 {
+		cut = i
+	} else if i := lastSpace(prefix); i > 0 {
+		cut = i
+	}
+
+	result := strings.TrimSpace(string(prefix[:cut]))
+	if result == "" {
+		return ""
+	}
+	return result + truncationMark
+}
+
+// lastSentenceEnd возвращает позицию сразу после последнего знака конца предложения или перевода строки
+func lastSentenceEnd(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		switch runes[i] {
+		case '.', '!', '?', '…', '\n', '。':
+			return i + 1
+		}
+	}
+	return -1
+}
+
+func lastSpace(runes []rune) int {
+	for i := len(runes) - 1; i >= 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/config"
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func TestEstimateTokenizerCount(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want int
+	}{
+		{name: "empty", text: "", want: 0},
+		{name: "latin word", text: "budget", want: 2},
+		{name: "cyrillic word", text: "бюджет", want: 3},
+		{name: "digits", text: "2025", want: 2},
+		{name: "punctuation", text: "да, нет!", want: 5},
+		{name: "mixed words", text: "релиз v2", want: 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (estimateTokenizer{}).Count(tt.text); got != tt.want {
+				t.Errorf("Count(%q) = %d, want %d", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContextBudgetTruncate(t *testing.T) {
+	budget := ContextBudget{tokenizer: estimateTokenizer{}}
+	sentences := "Команда обсудила релиз. Бюджет утвердили без правок. Сроки сдвинули на неделю"
+
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  string
+	}{
+		{name: "fits", text: "Короткий текст", limit: 100, want: "Короткий текст"},
+		{name: "zero limit", text: "Короткий текст", limit: 0, want: ""},
+		{name: "sentence boundary", text: sentences, limit: 27, want: "Команда обсудила релиз. Бюджет утвердили без правок. …"},
+		{name: "word boundary", text: "Обсуждаем релиз бюджет сроки дизайн", limit: 10, want: "Обсуждаем релиз …"},
+		{name: "rune boundary without spaces", text: "Обсуждаемрелиз", limit: 3, want: "Обсу …"},
+		{name: "no room for text", text: "Обсуждаем релиз", limit: 1, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := budget.Truncate(tt.text, tt.limit)
+			if got != tt.want {
+				t.Errorf("Truncate(%q, %d) = %q, want %q", tt.text, tt.limit, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("Truncate(%q, %d) split a rune: %q", tt.text, tt.limit, got)
+			}
+			if n := budget.Count(got); n > tt.limit {
+				t.Errorf("Truncate(%q, %d) = %d tokens", tt.text, tt.limit, n)
+			}
+		})
+	}
+}
+
+func TestContextWindowFor(t *testing.T) {
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{model: "gpt-4", want: 8192},
+		{model: "gpt-4o-mini", want: 128000},
+		{model: "openai/gpt-4.1-mini", want: 1047576},
+		{model: "anthropic/Claude-3.5-Sonnet", want: 200000},
+		{model: "llama3.1:8b", want: 131072},
+		{model: "llama3", want: 8192},
+		{model: "unknown-model", want: defaultContextWindow},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := contextWindowFor(tt.model); got != tt.want {
+				t.Errorf("contextWindowFor(%q) = %d, want %d", tt.model, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewContextBudget(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.LLMConfig
+		model string
+		want  int
+	}{
+		{name: "known window", cfg: config.LLMConfig{ResponseTokens: 1024}, model: "gpt-4", want: 8192 - 1024},
+		{name: "input cap", cfg: config.LLMConfig{ResponseTokens: 1024, MaxInputTokens: 16000}, model: "gpt-4o", want: 16000},
+		{name: "configured window", cfg: config.LLMConfig{ContextWindow: 4000, ResponseTokens: 500}, model: "gpt-4o", want: 3500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newContextBudget(&tt.cfg, tt.model).Input; got != tt.want {
+				t.Errorf("Input = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildChatMessagesContextTooLong(t *testing.T) {
+	ai := newFakeAIService(t).(*aiService)
+	session := &models.AISession{}
+	sources := []ChatSource{{Title: "Планёрка", Summary: "Команда обсудила релиз"}}
+
+	tests := []struct {
+		name    string
+		message string
+		wantErr error
+	}{
+		{name: "short question", message: "Что решили по релизу?"},
+		{name: "question over the window", message: strings.Repeat("бюджет ", 20000), wantErr: models.ErrContextTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := ai.buildChatMessages(context.Background(), session, sources, tt.message)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("buildChatMessages() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && messages[len(messages)-1].Content != tt.message {
+				t.Errorf("last message = %q, want the question", messages[len(messages)-1].Content)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+// services/diff.go
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+// Предел правок для алгоритма Майерса: память растёт квадратично от числа правок
+const maxDiffEdits = 1000
+
+// diffText строит пословный diff двух текстов. Если правок слишком много,
+// сравнивает по строкам, а в крайнем случае отдаёт полную замену.
+func diffText(a, b string) []models.DiffOp {
+	if ops, ok := myersDiff(splitKeepSpace(a), splitKeepSpace(b)); ok {
+		return ops
+	}
+	if ops, ok := myersDiff(splitLinesKeepEnd(a), splitLinesKeepEnd(b)); ok {
+		return ops
+	}
+
+	var ops []models.DiffOp
+	if a != "" {
+		ops = append(ops, models.DiffOp{Op: models.DiffDelete, Text: a})
+	}
+	if b != "" {
+		ops = append(ops, models.DiffOp{Op: models.DiffInsert, Text: b})
+	}
+	return ops
+}
+
+// myersDiff — классический O((N+M)D) diff по токенам
+func myersDiff(a, b []string) ([]models.DiffOp, bool) {
+	n, m := len(a), len(b)
+	maxD := min(n+m, maxDiffEdits)
+	offset := maxD + 1
+	v := make([]int, 2*maxD+3)
+
+	// trace[d] хранит v[k] для k из [-d, d] после шага d
+	var trace [][]int
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				snapshot := make([]int, 2*d+1)
+				copy(snapshot, v[offset-d:offset+d+1])
+				trace = append(trace, snapshot)
+				return backtrackDiff(trace, a, b), true
+			}
+		}
+
+		snapshot := make([]int, 2*d+1)
+		copy(snapshot, v[offset-d:offset+d+1])
+		trace = append(trace, snapshot)
+	}
+
+	return nil, false
+}
+
+func backtrackDiff(trace [][]int, a, b []string) []models.DiffOp {
+	var reversed []models.DiffOp
+	x, y := len(a), len(b)
+
+	for d := len(trace) - 1; d > 0; d-- {
+		prev := trace[d-1]
+		at := func(k int) int { return prev[k+d-1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, models.DiffOp{Op: models.DiffEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			reversed = append(reversed, models.DiffOp{Op: models.DiffInsert, Text: b[y-1]})
+			y--
+		} else {
+			reversed = append(reversed, models.DiffOp{Op: models.DiffDelete, Text: a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		reversed = append(reversed, models.DiffOp{Op: models.DiffEqual, Text: a[x-1]})
+		x--
+		y--
+	}
+
+	// Разворачиваем и склеиваем соседние операции одного типа
+	var ops []models.DiffOp
+	for i := len(reversed) - 1; i >= 0; i-- {
+		op := reversed[i]
+		if last := len(ops) - 1; last >= 0 && ops[last].Op == op.Op {
+			ops[last].Text += op.Text
+			continue
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// splitKeepSpace режет текст на слова вместе с последующими пробелами,
+// так что склейка токенов даёт исходный текст
+func splitKeepSpace(s string) []string {
+	var tokens []string
+	start := 0
+	inSpace := false
+	for i, r := range s {
+		space := unicode.IsSpace(r)
+		if !space && inSpace && i > start {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+	return tokens
+}
+
+func splitLinesKeepEnd(s string) []string {
+	if s == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func countDiff(ops []models.DiffOp) (insertions, deletions int) {
+	for _, op := range ops {
+		switch op.Op {
+		case models.DiffInsert:
+			insertions += len(splitKeepSpace(op.Text))
+		case models.DiffDelete:
+			deletions += len(splitKeepSpace(op.Text))
+		}
+	}
+	return insertions, deletions
+}
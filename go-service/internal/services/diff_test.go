@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func TestSplitKeepSpace(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "single word", text: "слово", want: []string{"слово"}},
+		{name: "trailing spaces", text: "раз  два\n", want: []string{"раз  ", "два\n"}},
+		{name: "leading space", text: " раз два", want: []string{" ", "раз ", "два"}},
+		{name: "only spaces", text: " \t ", want: []string{" \t "}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitKeepSpace(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitKeepSpace(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if joined := strings.Join(got, ""); joined != tt.text {
+				t.Errorf("joined tokens = %q, want %q", joined, tt.text)
+			}
+		})
+	}
+}
+
+func TestSplitLinesKeepEnd(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "empty", text: "", want: nil},
+		{name: "no newline", text: "строка", want: []string{"строка"}},
+		{name: "trailing newline", text: "a\nb\n", want: []string{"a\n", "b\n"}},
+		{name: "blank line", text: "a\n\nb", want: []string{"a\n", "\n", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitLinesKeepEnd(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitLinesKeepEnd(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffText(t *testing.T) {
+	// Слишком много пословных правок: diff уходит на уровень строк
+	var oldWords, newWords []string
+	for i := 0; i < maxDiffEdits; i++ {
+		oldWords = append(oldWords, fmt.Sprintf("old%d", i))
+		newWords = append(newWords, fmt.Sprintf("new%d", i))
+	}
+	oldLine := strings.Join(oldWords, " ") + "\n"
+	newLine := strings.Join(newWords, " ") + "\n"
+
+	tests := []struct {
+		name string
+		a, b string
+		want []models.DiffOp
+	}{
+		{name: "both empty", a: "", b: "", want: nil},
+		{name: "equal", a: "раз два", b: "раз два", want: []models.DiffOp{{Op: models.DiffEqual, Text: "раз два"}}},
+		{name: "from empty", a: "", b: "новый текст", want: []models.DiffOp{{Op: models.DiffInsert, Text: "новый текст"}}},
+		{name: "to empty", a: "старый текст", b: "", want: []models.DiffOp{{Op: models.DiffDelete, Text: "старый текст"}}},
+		{
+			name: "word replaced",
+			a:    "встреча во вторник утром",
+			b:    "встреча в среду утром",
+			want: []models.DiffOp{
+				{Op: models.DiffEqual, Text: "встреча "},
+				{Op: models.DiffDelete, Text: "во вторник "},
+				{Op: models.DiffInsert, Text: "в среду "},
+				{Op: models.DiffEqual, Text: "утром"},
+			},
+		},
+		{
+			name: "word appended",
+			a:    "итоги встречи",
+			b:    "итоги встречи и задачи",
+			want: []models.DiffOp{
+				{Op: models.DiffEqual, Text: "итоги "},
+				{Op: models.DiffDelete, Text: "встречи"},
+				{Op: models.DiffInsert, Text: "встречи и задачи"},
+			},
+		},
+		{
+			name: "line fallback",
+			a:    oldLine + "общая строка\n",
+			b:    newLine + "общая строка\n",
+			want: []models.DiffOp{
+				{Op: models.DiffDelete, Text: oldLine},
+				{Op: models.DiffInsert, Text: newLine},
+				{Op: models.DiffEqual, Text: "общая строка\n"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := diffText(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffText() = %q, want %q", got, tt.want)
+			}
+
+			// Из операций должны собираться оба исходных текста
+			var a, b strings.Builder
+			for _, op := range got {
+				if op.Op != models.DiffInsert {
+					a.WriteString(op.Text)
+				}
+				if op.Op != models.DiffDelete {
+					b.WriteString(op.Text)
+				}
+			}
+			if a.String() != tt.a || b.String() != tt.b {
+				t.Errorf("ops rebuild (%q, %q), want (%q, %q)", a.String(), b.String(), tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestCountDiff(t *testing.T) {
+	tests := []struct {
+		name           string
+		ops            []models.DiffOp
+		wantInsertions int
+		wantDeletions  int
+	}{
+		{name: "no ops", ops: nil},
+		{name: "only equal", ops: []models.DiffOp{{Op: models.DiffEqual, Text: "раз два три"}}},
+		{
+			name: "mixed",
+			ops: []models.DiffOp{
+				{Op: models.DiffEqual, Text: "встреча "},
+				{Op: models.DiffDelete, Text: "во вторник "},
+				{Op: models.DiffInsert, Text: "в эту среду "},
+			},
+			wantInsertions: 3,
+			wantDeletions:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			insertions, deletions := countDiff(tt.ops)
+			if insertions != tt.wantInsertions || deletions != tt.wantDeletions {
+				t.Errorf("countDiff() = (%d, %d), want (%d, %d)", insertions, deletions, tt.wantInsertions, tt.wantDeletions)
+			}
+		})
+	}
+}
@@ -3,6 +3,9 @@ package services
 import (
 	"context"
 	"fmt"
+	"html"
+	"net/url"
+	"strings"
 
 	"gopkg.in/gomail.v2"
 )
@@ -54,6 +57,18 @@ func (s *emailService) SendPasswordResetEmail(ctx context.Context, email, token
 	return s.dialer.DialAndSend(m)
 }
 
+func (s *emailService) SendWorkspaceInviteEmail(ctx context.Context, email, workspaceName, token string) error {
+	inviteLink := fmt.Sprintf("%s/invite?token=%s", strings.TrimRight(s.config.AppURL, "/"), url.QueryEscape(token))
+
+	m := gomail.NewMessage()
+	m.SetHeader("From", s.config.From)
+	m.SetHeader("To", email)
+	m.SetHeader("Subject", "Приглашение в воркспейс")
+	m.SetBody("text/html", s.buildWorkspaceInviteTemplate(workspaceName, inviteLink))
+
+	return s.dialer.DialAndSend(m)
+}
+
 func (s *emailService) buildConfirmationTemplate(link string) string {
 	return fmt.Sprintf(`
         <h2>Подтверждение email</h2>
@@ -80,3 +95,12 @@ func (s *emailService) buildPasswordResetTemplate(link string) string {
         <p>Ссылка действительна 24 часа</p>
     `, link)
 }
+
+func (s *emailService) buildWorkspaceInviteTemplate(workspaceName, link string) string {
+	return fmt.Sprintf(`
+        <h2>Приглашение в воркспейс</h2>
+        <p>Вас пригласили в воркспейс «%s». Чтобы присоединиться, перейдите по ссылке:</p>
+        <a href="%s">Принять приглашение</a>
+        <p>Ссылка одноразовая и действительна 7 дней</p>
+    `, html.EscapeString(workspaceName), link)
+}
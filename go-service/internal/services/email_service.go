@@ -6,6 +6,7 @@ type EmailService interface {
 	SendConfirmationEmail(ctx context.Context, email, token string) error
 	SendWelcomeEmail(ctx context.Context, email, name string) error
 	SendPasswordResetEmail(ctx context.Context, email, token string) error
+	SendWorkspaceInviteEmail(ctx context.Context, email, workspaceName, token string) error
 }
 
 type SMTPConfig struct {
@@ -14,4 +15,6 @@ type SMTPConfig struct {
 	Username string
 	Password string
 	From     string
+	// Адрес веб-приложения для ссылок в письмах
+	AppURL string
 }
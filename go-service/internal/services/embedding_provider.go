@@ -0,0 +1,205 @@
+// services/embedding_provider.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/code-zt/vidnotes/config"
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+// EmbeddingProvider вычисляет векторы для набора текстов.
+// Возвращаемые векторы нормализованы и идут в том же порядке, что и входные тексты.
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Model() string
+}
+
+func NewEmbeddingProvider(cfg *config.EmbeddingConfig) (EmbeddingProvider, error) {
+	switch cfg.Provider {
+	case "openai":
+		return NewOpenAIEmbeddingProvider(cfg), nil
+	case "fake":
+		return NewFakeEmbeddingProvider(cfg.Dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown embeddings provider: %s", cfg.Provider)
+	}
+}
+
+// OpenAIEmbeddingProvider работает с любым OpenAI-совместимым /embeddings endpoint
+type OpenAIEmbeddingProvider struct {
+	config *config.EmbeddingConfig
+	client *http.Client
+}
+
+func NewOpenAIEmbeddingProvider(cfg *config.EmbeddingConfig) EmbeddingProvider {
+	return &OpenAIEmbeddingProvider{
+		config: cfg,
+		client: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
+		},
+	}
+}
+
+type embeddingRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float32 `json:"embedding"`
+	} `json:"data"`
+	Error *openAIError `json:"error,omitempty"`
+}
+
+func (p *OpenAIEmbeddingProvider) Model() string {
+	return p.config.Model
+}
+
+func (p *OpenAIEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if p.config.APIKey == "" {
+		return nil, models.ErrEmbeddingsUnavailable
+	}
+
+	batchSize := p.config.BatchSize
+	if batchSize <= 0 {
+		batchSize = len(texts)
+	}
+
+	vectors := make([][]float32, 0, len(texts))
+	for i := 0; i < len(texts); i += batchSize {
+		end := min(i+batchSize, len(texts))
+		batch, err := p.embedBatch(ctx, texts[i:end])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+
+	return vectors, nil
+}
+
+func (p *OpenAIEmbeddingProvider) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	jsonData, err := json.Marshal(embeddingRequest{Model: p.config.Model, Input: texts})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var embResp embeddingResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("embeddings API error: %s", string(body))
+		}
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if embResp.Error != nil {
+			return nil, fmt.Errorf("embeddings error: %s", embResp.Error.Message)
+		}
+		return nil, fmt.Errorf("embeddings API error: %s", string(body))
+	}
+
+	if len(embResp.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings API returned %d vectors for %d inputs", len(embResp.Data), len(texts))
+	}
+
+	vectors := make([][]float32, len(texts))
+	for _, d := range embResp.Data {
+		if d.Index < 0 || d.Index >= len(texts) {
+			return nil, fmt.Errorf("embeddings API returned invalid index %d", d.Index)
+		}
+		vectors[d.Index] = normalizeVector(d.Embedding)
+	}
+
+	return vectors, nil
+}
+
+// FakeEmbeddingProvider строит детерминированные векторы через feature hashing слов.
+// Не требует сети, поэтому подходит для тестов и офлайн-разработки;
+// тексты с общими словами получают близкие векторы.
+type FakeEmbeddingProvider struct {
+	dimensions int
+}
+
+func NewFakeEmbeddingProvider(dimensions int) EmbeddingProvider {
+	if dimensions <= 0 {
+		dimensions = 256
+	}
+	return &FakeEmbeddingProvider{dimensions: dimensions}
+}
+
+func (p *FakeEmbeddingProvider) Model() string {
+	return fmt.Sprintf("fake-%d", p.dimensions)
+}
+
+func (p *FakeEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		vec := make([]float32, p.dimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			h := fnv.New64a()
+			h.Write([]byte(word))
+			sum := h.Sum64()
+
+			sign := float32(1)
+			if sum&(1<<63) != 0 {
+				sign = -1
+			}
+			vec[sum%uint64(p.dimensions)] += sign
+		}
+		vectors[i] = normalizeVector(vec)
+	}
+	return vectors, nil
+}
+
+func normalizeVector(vec []float32) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return vec
+	}
+
+	scale := float32(1 / math.Sqrt(norm))
+	out := make([]float32, len(vec))
+	for i, v := range vec {
+		out[i] = v * scale
+	}
+	return out
+}
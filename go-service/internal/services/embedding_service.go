@@ -0,0 +1,288 @@
+// services/embedding_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxHitsPerVideo = 2
+	indexLoadRetry  = 3
+)
+
+type EmbeddingService interface {
+	IndexVideo(ctx context.Context, video *models.Video) error
+	RemoveVideo(ctx context.Context, libraryID, videoID primitive.ObjectID) error
+	// SemanticSearch ищет в личной библиотеке пользователя или в воркспейсе filter.WorkspaceID
+	SemanticSearch(ctx context.Context, userID primitive.ObjectID, query string, filter models.VideoFilter, limit int) (*models.SemanticSearchResult, error)
+	// RetrieveChunks подбирает фрагменты транскрипта видео под вопрос, по убыванию релевантности
+	RetrieveChunks(ctx context.Context, video *models.Video, query string, limit int) ([]*models.ContentChunk, error)
+}
+
+type embeddingService struct {
+	provider  EmbeddingProvider
+	chunkRepo repository.ChunkRepository
+	videoRepo repository.VideoRepository
+	index     *VectorIndex
+}
+
+func NewEmbeddingService(provider EmbeddingProvider, chunkRepo repository.ChunkRepository, videoRepo repository.VideoRepository) EmbeddingService {
+	return &embeddingService{
+		provider:  provider,
+		chunkRepo: chunkRepo,
+		videoRepo: videoRepo,
+		index:     NewVectorIndex(),
+	}
+}
+
+func (s *embeddingService) IndexVideo(ctx context.Context, video *models.Video) error {
+	ctx = WithAIWorkspace(ctx, video.WorkspaceID)
+	chunks := chunkVideo(video)
+
+	if len(chunks) > 0 {
+		texts := make([]string, len(chunks))
+		for i, c := range chunks {
+			texts[i] = c.Text
+		}
+
+		vectors, err := s.provider.Embed(ctx, texts)
+		if err != nil {
+			return fmt.Errorf("failed to embed chunks: %w", err)
+		}
+
+		for i, c := range chunks {
+			c.Vector = vectors[i]
+			c.Model = s.provider.Model()
+		}
+	}
+
+	if err := s.chunkRepo.ReplaceForVideo(ctx, video.ID, chunks); err != nil {
+		return err
+	}
+
+	s.index.ReplaceVideo(models.LibraryID(video.UserID, video.WorkspaceID), video.ID, chunks)
+	return nil
+}
+
+func (s *embeddingService) RemoveVideo(ctx context.Context, libraryID, videoID primitive.ObjectID) error {
+	if err := s.chunkRepo.DeleteByVideo(ctx, videoID); err != nil {
+		return err
+	}
+
+	s.index.RemoveVideo(libraryID, videoID)
+	return nil
+}
+
+func (s *embeddingService) SemanticSearch(ctx context.Context, userID primitive.ObjectID, query string, filter models.VideoFilter, limit int) (*models.SemanticSearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, models.ErrEmptySearchQuery
+	}
+	ctx = WithAIWorkspace(ctx, filter.WorkspaceID)
+
+	if limit <= 0 {
+		limit = models.DefaultSearchLimit
+	}
+	if limit > models.MaxSearchLimit {
+		limit = models.MaxSearchLimit
+	}
+
+	libraryID := models.LibraryID(userID, filter.WorkspaceID)
+	if err := s.ensureLoaded(ctx, userID, filter.WorkspaceID); err != nil {
+		return nil, err
+	}
+
+	// Фильтры по видео (статус, язык, даты, теги, коллекции) сужают поиск до списка разрешённых видео
+	var allowed map[primitive.ObjectID]bool
+	if filter.Status != "" || filter.Language != "" || filter.From != nil || filter.To != nil ||
+		filter.Tag != "" || filter.CollectionIDs != nil {
+		ids, err := s.videoRepo.ListIDs(ctx, userID, filter)
+		if err != nil {
+			return nil, err
+		}
+		if len(ids) == 0 {
+			return &models.SemanticSearchResult{Query: query, Items: []models.SemanticHit{}}, nil
+		}
+		allowed = make(map[primitive.ObjectID]bool, len(ids))
+		for _, id := range ids {
+			allowed[id] = true
+		}
+	}
+
+	vectors, err := s.provider.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+
+	model := s.provider.Model()
+	candidates := s.index.Search(libraryID, vectors[0], limit*maxHitsPerVideo*2, func(c *models.ContentChunk) bool {
+		return c.Model == model && (allowed == nil || allowed[c.VideoID])
+	})
+
+	titles := make(map[primitive.ObjectID]string)
+	perVideo := make(map[primitive.ObjectID]int)
+	hits := make([]models.SemanticHit, 0, limit)
+
+	for _, cand := range candidates {
+		if len(hits) >= limit {
+			break
+		}
+
+		c := cand.chunk
+		if perVideo[c.VideoID] >= maxHitsPerVideo {
+			continue
+		}
+
+		title, ok := titles[c.VideoID]
+		if !ok {
+			video, err := s.videoRepo.GetByID(ctx, c.VideoID)
+			if err != nil {
+				// Видео удалено, а чанки ещё нет — пропускаем
+				continue
+			}
+			title = video.Title
+			titles[c.VideoID] = title
+		}
+
+		perVideo[c.VideoID]++
+		hits = append(hits, models.SemanticHit{
+			VideoID: c.VideoID,
+			Title:   title,
+			ChunkID: c.ID,
+			Source:  c.Source,
+			Text:    c.Text,
+			Start:   c.Start,
+			End:     c.End,
+			Score:   cand.score,
+		})
+	}
+
+	return &models.SemanticSearchResult{
+		Query: query,
+		Items: hits,
+	}, nil
+}
+
+// RetrieveChunks ранжирует чанки транскрипта по BM25 и по близости эмбеддингов
+// и объединяет оба рейтинга через reciprocal rank fusion. Если эмбеддинги
+// недоступны, остаётся только BM25. У видео без сохранённых чанков транскрипт
+// режется на лету — у таких фрагментов нет ID.
+func (s *embeddingService) RetrieveChunks(ctx context.Context, video *models.Video, query string, limit int) ([]*models.ContentChunk, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || limit <= 0 {
+		return nil, nil
+	}
+	ctx = WithAIWorkspace(ctx, video.WorkspaceID)
+
+	stored, err := s.chunkRepo.GetByVideo(ctx, video.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*models.ContentChunk
+	for _, c := range stored {
+		if c.Source == models.ChunkSourceTranscript {
+			chunks = append(chunks, c)
+		}
+	}
+	if len(chunks) == 0 {
+		for _, c := range chunkVideo(video) {
+			if c.Source == models.ChunkSourceTranscript {
+				chunks = append(chunks, c)
+			}
+		}
+	}
+	if len(chunks) == 0 {
+		return nil, nil
+	}
+
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Text
+	}
+
+	fused := make([]float64, len(chunks))
+	addRanking(fused, bm25Scores(query, texts))
+
+	model := s.provider.Model()
+	if vectors, err := s.provider.Embed(ctx, []string{query}); err == nil {
+		similarity := make([]float64, len(chunks))
+		for i, c := range chunks {
+			if c.Model == model && len(c.Vector) == len(vectors[0]) {
+				similarity[i] = dot(vectors[0], c.Vector)
+			}
+		}
+		addRanking(fused, similarity)
+	} else {
+		fmt.Printf("Retrieval for video %s falls back to BM25: %v\n", video.ID.Hex(), err)
+	}
+
+	order := make([]int, 0, len(chunks))
+	for i, score := range fused {
+		if score > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return fused[order[a]] > fused[order[b]]
+	})
+	if len(order) > limit {
+		order = order[:limit]
+	}
+
+	result := make([]*models.ContentChunk, len(order))
+	for i, idx := range order {
+		result[i] = chunks[idx]
+	}
+	return result, nil
+}
+
+// addRanking добавляет к fused вклад рейтинга по scores; нулевые оценки не ранжируются
+func addRanking(fused, scores []float64) {
+	order := make([]int, 0, len(scores))
+	for i, score := range scores {
+		if score > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return scores[order[a]] > scores[order[b]]
+	})
+
+	for rank, idx := range order {
+		fused[idx] += 1 / float64(rrfK+rank+1)
+	}
+}
+
+// ensureLoaded лениво поднимает эмбеддинги библиотеки из хранилища в память
+func (s *embeddingService) ensureLoaded(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) error {
+	libraryID := models.LibraryID(userID, workspaceID)
+	for i := 0; i < indexLoadRetry; i++ {
+		loaded, version := s.index.State(libraryID)
+		if loaded {
+			return nil
+		}
+
+		videoIDs, err := s.videoRepo.ListIDs(ctx, userID, models.VideoFilter{WorkspaceID: workspaceID})
+		if err != nil {
+			return err
+		}
+		chunks, err := s.chunkRepo.GetByVideos(ctx, videoIDs)
+		if err != nil {
+			return err
+		}
+
+		if s.index.Load(libraryID, chunks, version) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("failed to load vector index: concurrent updates")
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeEmbedder struct{}
+
+func (fakeEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i := range texts {
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, nil
+}
+
+func (fakeEmbedder) Model() string { return "test-embed" }
+
+type fakeChunkRepo struct {
+	repository.ChunkRepository
+	chunks []*models.ContentChunk
+}
+
+func (f *fakeChunkRepo) GetByVideos(ctx context.Context, videoIDs []primitive.ObjectID) ([]*models.ContentChunk, error) {
+	return f.chunks, nil
+}
+
+// fakeFilterVideos применяет фильтры VideoFilter так же, как videoFilterQuery
+type fakeFilterVideos struct {
+	repository.VideoRepository
+	videos []*models.Video
+}
+
+func (f *fakeFilterVideos) ListIDs(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter) ([]primitive.ObjectID, error) {
+	var ids []primitive.ObjectID
+	for _, v := range f.videos {
+		if filter.Status != "" && v.Status != filter.Status ||
+			filter.Language != "" && v.Language != filter.Language ||
+			filter.From != nil && v.CreatedAt.Before(*filter.From) ||
+			filter.To != nil && !v.CreatedAt.Before(*filter.To) {
+			continue
+		}
+		ids = append(ids, v.ID)
+	}
+	return ids, nil
+}
+
+func (f *fakeFilterVideos) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Video, error) {
+	for _, v := range f.videos {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return nil, models.ErrVideoNotFound
+}
+
+func TestEmbeddingServiceSemanticSearchFilters(t *testing.T) {
+	userID := primitive.NewObjectID()
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	russian := &models.Video{ID: primitive.NewObjectID(), Title: "Планёрка", Status: "completed", Language: "ru", CreatedAt: day}
+	english := &models.Video{ID: primitive.NewObjectID(), Title: "Standup", Status: "completed", Language: "en", CreatedAt: day.AddDate(0, 1, 0)}
+	failed := &models.Video{ID: primitive.NewObjectID(), Title: "Broken", Status: "failed", Language: "ru", CreatedAt: day}
+	videos := []*models.Video{russian, english, failed}
+
+	var chunks []*models.ContentChunk
+	for _, v := range videos {
+		chunks = append(chunks, &models.ContentChunk{ID: primitive.NewObjectID(), UserID: userID, VideoID: v.ID, Text: v.Title, Vector: []float32{1, 0}, Model: "test-embed"})
+	}
+
+	from := day.AddDate(0, 0, 15)
+	tests := []struct {
+		name   string
+		filter models.VideoFilter
+		want   []string
+	}{
+		{name: "no filters", want: []string{"Планёрка", "Standup", "Broken"}},
+		{name: "status", filter: models.VideoFilter{Status: "completed"}, want: []string{"Планёрка", "Standup"}},
+		{name: "language", filter: models.VideoFilter{Language: "ru"}, want: []string{"Планёрка", "Broken"}},
+		{name: "date range", filter: models.VideoFilter{From: &from}, want: []string{"Standup"}},
+		{name: "nothing matches", filter: models.VideoFilter{Status: "failed", Language: "en"}, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &embeddingService{
+				provider:  fakeEmbedder{},
+				chunkRepo: &fakeChunkRepo{chunks: chunks},
+				videoRepo: &fakeFilterVideos{videos: videos},
+				index:     NewVectorIndex(),
+			}
+
+			result, err := s.SemanticSearch(context.Background(), userID, "встреча", tt.filter, 10)
+			if err != nil {
+				t.Fatalf("SemanticSearch() error = %v", err)
+			}
+
+			got := map[string]bool{}
+			for _, hit := range result.Items {
+				got[hit.Title] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("SemanticSearch() titles = %v, want %v", got, tt.want)
+			}
+			for _, title := range tt.want {
+				if !got[title] {
+					t.Errorf("SemanticSearch() missing %q, got %v", title, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// services/insights_service.go
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InsightsService извлекает из видео ключевые тезисы, задачи, решения и открытые
+// вопросы. Выводы собираются после обработки видео и хранятся в нём.
+type InsightsService interface {
+	Get(ctx context.Context, userID, videoID primitive.ObjectID) (*models.VideoInsights, error)
+	// Refresh пересобирает выводы по текущим транскрипту и саммари
+	Refresh(ctx context.Context, userID, videoID primitive.ObjectID) (*models.VideoInsights, error)
+	// Extract собирает выводы после обработки; расход записывается на загрузившего видео
+	Extract(ctx context.Context, video *models.Video) (*models.VideoInsights, error)
+}
+
+type insightsService struct {
+	videoRepo      repository.VideoRepository
+	summaryService SummaryService
+	aiService      AIService
+}
+
+func NewInsightsService(videoRepo repository.VideoRepository, summaryService SummaryService, aiService AIService) InsightsService {
+	return &insightsService{
+		videoRepo:      videoRepo,
+		summaryService: summaryService,
+		aiService:      aiService,
+	}
+}
+
+func (s *insightsService) Get(ctx context.Context, userID, videoID primitive.ObjectID) (*models.VideoInsights, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if video.Insights == nil {
+		return nil, models.ErrInsightsNotReady
+	}
+	return video.Insights, nil
+}
+
+func (s *insightsService) Refresh(ctx context.Context, userID, videoID primitive.ObjectID) (*models.VideoInsights, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	return s.extract(ctx, video, userID, &userID)
+}
+
+func (s *insightsService) Extract(ctx context.Context, video *models.Video) (*models.VideoInsights, error) {
+	return s.extract(ctx, video, video.UserID, nil)
+}
+
+func (s *insightsService) extract(ctx context.Context, video *models.Video, payerID primitive.ObjectID, authorID *primitive.ObjectID) (*models.VideoInsights, error) {
+	source, err := NewVideoSource(video)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithAIWorkspace(WithAIUsageScope(prompts.WithLocale(ctx, video.Language), payerID, nil), video.WorkspaceID)
+	insights, err := s.aiService.ExtractInsights(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ссылки на несуществующие сегменты отбрасываются, остальные получают таймкод
+	starts := newSegmentStarts(video)
+	for _, items := range [][]models.InsightItem{insights.KeyPoints, insights.Decisions, insights.OpenQuestions} {
+		for i := range items {
+			items[i].SegmentID, items[i].Start = starts.resolve(items[i].SegmentID)
+		}
+	}
+	for i := range insights.ActionItems {
+		insights.ActionItems[i].SegmentID, insights.ActionItems[i].Start = starts.resolve(insights.ActionItems[i].SegmentID)
+	}
+
+	insights.AuthorID = authorID
+	insights.GeneratedAt = time.Now()
+	if err := s.videoRepo.UpdateInsights(ctx, video.ID, insights); err != nil {
+		return nil, err
+	}
+	return insights, nil
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeInsightsAI struct {
+	AIService
+	insights *models.VideoInsights
+	scope    AIUsageScope
+}
+
+func (f *fakeInsightsAI) ExtractInsights(ctx context.Context, source VideoSource) (*models.VideoInsights, error) {
+	f.scope, _ = usageScopeFrom(ctx)
+	return f.insights, nil
+}
+
+type fakeInsightsRepo struct {
+	repository.VideoRepository
+	saved map[primitive.ObjectID]*models.VideoInsights
+}
+
+func (f *fakeInsightsRepo) UpdateInsights(ctx context.Context, id primitive.ObjectID, insights *models.VideoInsights) error {
+	f.saved[id] = insights
+	return nil
+}
+
+func TestInsightsServiceRefresh(t *testing.T) {
+	uploaderID, editorID := primitive.NewObjectID(), primitive.NewObjectID()
+	known, unknown := 1, 7
+	newVideo := func() *models.Video {
+		return &models.Video{
+			ID:         primitive.NewObjectID(),
+			UserID:     uploaderID,
+			Transcript: "текст",
+			Segments:   []models.TranscriptSegment{{ID: 1, Start: 30, End: 35, Text: "текст"}},
+		}
+	}
+	newInsights := func() *models.VideoInsights {
+		return &models.VideoInsights{
+			KeyPoints:   []models.InsightItem{{Text: "тезис", SegmentID: &known}},
+			ActionItems: []models.ActionItem{{Task: "задача", SegmentID: &unknown}},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		role       string
+		refresh    bool
+		wantErr    error
+		wantPayer  primitive.ObjectID
+		wantAuthor *primitive.ObjectID
+	}{
+		{name: "viewer cannot refresh", role: models.WorkspaceRoleViewer, refresh: true, wantErr: models.ErrWorkspaceAccessDenied},
+		{name: "editor refreshes and pays", role: models.WorkspaceRoleEditor, refresh: true, wantPayer: editorID, wantAuthor: &editorID},
+		{name: "after processing uploader pays", refresh: false, wantPayer: uploaderID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			video := newVideo()
+			ai := &fakeInsightsAI{insights: newInsights()}
+			repo := &fakeInsightsRepo{saved: make(map[primitive.ObjectID]*models.VideoInsights)}
+			s := NewInsightsService(repo, &fakeVideoAccess{video: video, role: tt.role}, ai)
+
+			var insights *models.VideoInsights
+			var err error
+			if tt.refresh {
+				insights, err = s.Refresh(context.Background(), editorID, video.ID)
+			} else {
+				insights, err = s.Extract(context.Background(), video)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.saved) != 0 {
+					t.Error("insights saved despite the error")
+				}
+				return
+			}
+
+			if ai.scope.UserID != tt.wantPayer {
+				t.Errorf("usage billed to %s, want %s", ai.scope.UserID.Hex(), tt.wantPayer.Hex())
+			}
+			if (insights.AuthorID == nil) != (tt.wantAuthor == nil) || (tt.wantAuthor != nil && *insights.AuthorID != *tt.wantAuthor) {
+				t.Errorf("author = %v, want %v", insights.AuthorID, tt.wantAuthor)
+			}
+			if repo.saved[video.ID] != insights {
+				t.Error("insights not saved to the video")
+			}
+			if p := insights.KeyPoints[0]; p.Start == nil || *p.Start != 30 {
+				t.Errorf("key point start = %v, want 30", p.Start)
+			}
+			if a := insights.ActionItems[0]; a.SegmentID != nil || a.Start != nil {
+				t.Errorf("unknown segment kept: %v", a.SegmentID)
+			}
+		})
+	}
+}
+
+func TestInsightsServiceGet(t *testing.T) {
+	ready := &models.Video{Insights: &models.VideoInsights{}}
+
+	tests := []struct {
+		name    string
+		video   *models.Video
+		role    string
+		wantErr error
+	}{
+		{name: "viewer reads", video: ready, role: models.WorkspaceRoleViewer},
+		{name: "not ready", video: &models.Video{}, role: models.WorkspaceRoleViewer, wantErr: models.ErrInsightsNotReady},
+		{name: "no access", video: nil, wantErr: models.ErrVideoNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewInsightsService(nil, &fakeVideoAccess{video: tt.video, role: tt.role}, nil)
+			if _, err := s.Get(context.Background(), primitive.NewObjectID(), primitive.NewObjectID()); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInsightsSchema(t *testing.T) {
+	schema := insightsSchema()
+	empty := `"key_points":[],"decisions":[],"open_questions":[]`
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "empty sections", content: `{"action_items":[],` + empty + `}`},
+		{name: "task with date", content: `{"action_items":[{"task":"Отчёт","owner":"Анна","due_date":"2025-03-14","segment_id":4}],` + empty + `}`},
+		{name: "bad date", content: `{"action_items":[{"task":"Отчёт","due_date":"к пятнице"}],` + empty + `}`, wantErr: "$.action_items[0].due_date"},
+		{name: "missing section", content: `{"action_items":[],"key_points":[],"decisions":[]}`, wantErr: `missing required property "open_questions"`},
+		{name: "empty point", content: `{"action_items":[],"key_points":[{"text":""}],"decisions":[],"open_questions":[]}`, wantErr: "$.key_points[0].text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var insights models.VideoInsights
+			err := schema.Decode([]byte(tt.content), &insights)
+			if err == nil {
+				err = checkInsights(&insights)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("insights rejected: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// services/json_schema.go
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// JSONSchema — подмножество JSON Schema для проверки структурированных ответов
+// модели: type, properties, required, additionalProperties, items, minItems,
+// maxItems, minLength, minimum и maximum. Та же схема подставляется в промпт.
+type JSONSchema struct {
+	Type                 string                 `json:"type"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+}
+
+// String — схема в JSON для промпта
+func (s *JSONSchema) String() string {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// Decode проверяет JSON по схеме и раскладывает его в out. Ошибка описывает
+// первое несоответствие с путём до поля — её можно вернуть модели для исправления.
+func (s *JSONSchema) Decode(data []byte, out any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return fmt.Errorf("invalid JSON: %v", err)
+	}
+	if decoder.More() {
+		return fmt.Errorf("invalid JSON: unexpected data after the top-level value")
+	}
+	if err := s.validate("$", value); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (s *JSONSchema) validate(path string, value any) error {
+	switch s.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		for _, name := range s.Required {
+			if _, ok := object[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, item := range object {
+			property, ok := s.Properties[name]
+			if !ok {
+				if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+					return fmt.Errorf("%s: unexpected property %q", path, name)
+				}
+				continue
+			}
+			if err := property.validate(path+"."+name, item); err != nil {
+				return err
+			}
+		}
+
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		if s.MinItems != nil && len(array) < *s.MinItems {
+			return fmt.Errorf("%s: expected at least %d items, got %d", path, *s.MinItems, len(array))
+		}
+		if s.MaxItems != nil && len(array) > *s.MaxItems {
+			return fmt.Errorf("%s: expected at most %d items, got %d", path, *s.MaxItems, len(array))
+		}
+		if s.Items != nil {
+			for i, item := range array {
+				if err := s.Items.validate(fmt.Sprintf("%s[%d]", path, i), item); err != nil {
+					return err
+				}
+			}
+		}
+
+	case "string":
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+		if s.MinLength != nil && utf8.RuneCountInString(str) < *s.MinLength {
+			return fmt.Errorf("%s: expected at least %d characters", path, *s.MinLength)
+		}
+
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return fmt.Errorf("%s: expected %s", path, s.Type)
+		}
+		f, err := number.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: invalid number", path)
+		}
+		if s.Type == "integer" && f != math.Trunc(f) {
+			return fmt.Errorf("%s: expected integer", path)
+		}
+		if s.Minimum != nil && f < *s.Minimum {
+			return fmt.Errorf("%s: must be >= %v", path, *s.Minimum)
+		}
+		if s.Maximum != nil && f > *s.Maximum {
+			return fmt.Errorf("%s: must be <= %v", path, *s.Maximum)
+		}
+
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	}
+
+	return nil
+}
+
+// jsonObject — объект с обязательными полями и без посторонних
+func jsonObject(properties map[string]*JSONSchema, required ...string) *JSONSchema {
+	closed := false
+	return &JSONSchema{Type: "object", Properties: properties, Required: required, AdditionalProperties: &closed}
+}
+
+// extractJSON вырезает JSON из ответа модели: без markdown-ограды и текста вокруг
+func extractJSON(content string, first, last byte) string {
+	start := strings.IndexByte(content, first)
+	end := strings.LastIndexByte(content, last)
+	if start < 0 || end <= start {
+		return content
+	}
+	return content[start : end+1]
+}
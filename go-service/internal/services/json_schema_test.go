@@ -0,0 +1,95 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONSchemaDecode(t *testing.T) {
+	one, three := 1, 3
+	zero, ten := 0.0, 10.0
+	schema := jsonObject(map[string]*JSONSchema{
+		"title": {Type: "string", MinLength: &one},
+		"score": {Type: "integer", Minimum: &zero, Maximum: &ten},
+		"ratio": {Type: "number"},
+		"done":  {Type: "boolean"},
+		"tags":  {Type: "array", MinItems: &one, MaxItems: &three, Items: &JSONSchema{Type: "string"}},
+	}, "title", "score")
+
+	type result struct {
+		Title string   `json:"title"`
+		Score int      `json:"score"`
+		Ratio float64  `json:"ratio"`
+		Done  bool     `json:"done"`
+		Tags  []string `json:"tags"`
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		want    result
+		wantErr string
+	}{
+		{name: "required only", data: `{"title":"Итоги","score":7}`, want: result{Title: "Итоги", Score: 7}},
+		{
+			name: "all fields",
+			data: `{"title":"x","score":0,"ratio":0.5,"done":true,"tags":["a","b"]}`,
+			want: result{Title: "x", Ratio: 0.5, Done: true, Tags: []string{"a", "b"}},
+		},
+		{name: "not json", data: `{"title":`, wantErr: "invalid JSON"},
+		{name: "trailing data", data: `{"title":"x","score":1} {}`, wantErr: "unexpected data after the top-level value"},
+		{name: "not an object", data: `[]`, wantErr: "$: expected object"},
+		{name: "missing required", data: `{"title":"x"}`, wantErr: `$: missing required property "score"`},
+		{name: "unexpected property", data: `{"title":"x","score":1,"extra":1}`, wantErr: `$: unexpected property "extra"`},
+		{name: "empty string", data: `{"title":"","score":1}`, wantErr: "$.title: expected at least 1 characters"},
+		{name: "wrong type", data: `{"title":5,"score":1}`, wantErr: "$.title: expected string"},
+		{name: "fraction for integer", data: `{"title":"x","score":1.5}`, wantErr: "$.score: expected integer"},
+		{name: "below minimum", data: `{"title":"x","score":-1}`, wantErr: "$.score: must be >= 0"},
+		{name: "above maximum", data: `{"title":"x","score":11}`, wantErr: "$.score: must be <= 10"},
+		{name: "boolean", data: `{"title":"x","score":1,"done":"yes"}`, wantErr: "$.done: expected boolean"},
+		{name: "too few items", data: `{"title":"x","score":1,"tags":[]}`, wantErr: "$.tags: expected at least 1 items, got 0"},
+		{name: "too many items", data: `{"title":"x","score":1,"tags":["a","b","c","d"]}`, wantErr: "$.tags: expected at most 3 items, got 4"},
+		{name: "bad item", data: `{"title":"x","score":1,"tags":["a",2]}`, wantErr: "$.tags[1]: expected string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got result
+			err := schema.Decode([]byte(tt.data), &got)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Decode() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Decode() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{name: "plain", content: `{"a":1}`, want: `{"a":1}`},
+		{name: "fenced", content: "```json\n{\"a\":1}\n```", want: `{"a":1}`},
+		{name: "with prose", content: `Вот ответ: {"a":{"b":2}} Готово.`, want: `{"a":{"b":2}}`},
+		{name: "no object", content: "нет JSON", want: "нет JSON"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractJSON(tt.content, '{', '}'); got != tt.want {
+				t.Errorf("extractJSON(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,440 @@
+// services/library_service.go
+package services
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LibraryService управляет тегами и коллекциями видео пользователя
+type LibraryService interface {
+	// ListTags и ListCollections считают видео личной библиотеки или воркспейса workspaceID
+	ListTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]models.TagCount, error)
+	// AssignTags и UnassignTags меняют видео личной библиотеки или воркспейса workspaceID
+	AssignTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error)
+	UnassignTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error)
+	SuggestTags(ctx context.Context, userID, videoID primitive.ObjectID) ([]string, error)
+
+	ListCollections(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]*models.CollectionNode, error)
+	CreateCollection(ctx context.Context, userID primitive.ObjectID, req models.CreateCollectionRequest) (*models.Collection, error)
+	UpdateCollection(ctx context.Context, userID, collectionID primitive.ObjectID, req models.UpdateCollectionRequest) (*models.Collection, error)
+	DeleteCollection(ctx context.Context, userID, collectionID primitive.ObjectID) error
+	AddVideosToCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, collectionID primitive.ObjectID, videoIDs []primitive.ObjectID) (*models.BulkResult, error)
+	RemoveVideosFromCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, collectionID primitive.ObjectID, videoIDs []primitive.ObjectID) (*models.BulkResult, error)
+	ResolveCollection(ctx context.Context, userID, collectionID primitive.ObjectID, includeNested bool) ([]primitive.ObjectID, error)
+}
+
+type libraryService struct {
+	videoRepo      repository.VideoRepository
+	collectionRepo repository.CollectionRepository
+	summaryService SummaryService
+	aiService      AIService
+}
+
+func NewLibraryService(videoRepo repository.VideoRepository, collectionRepo repository.CollectionRepository, summaryService SummaryService, aiService AIService) LibraryService {
+	return &libraryService{
+		videoRepo:      videoRepo,
+		collectionRepo: collectionRepo,
+		summaryService: summaryService,
+		aiService:      aiService,
+	}
+}
+
+func (s *libraryService) ListTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]models.TagCount, error) {
+	return s.videoRepo.ListTags(ctx, userID, workspaceID)
+}
+
+func (s *libraryService) AssignTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBulkVideos(videoIDs); err != nil {
+		return nil, err
+	}
+	return s.videoRepo.AddTags(ctx, userID, workspaceID, videoIDs, tags)
+}
+
+func (s *libraryService) UnassignTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, videoIDs []primitive.ObjectID, tags []string) (*models.BulkResult, error) {
+	tags, err := normalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkBulkVideos(videoIDs); err != nil {
+		return nil, err
+	}
+	return s.videoRepo.RemoveTags(ctx, userID, workspaceID, videoIDs, tags)
+}
+
+// SuggestTags генерирует теги по саммари видео, отдавая предпочтение уже используемым
+// в его библиотеке; видео воркспейса доступно редакторам и владельцу
+func (s *libraryService) SuggestTags(ctx context.Context, userID, videoID primitive.ObjectID) ([]string, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(video.Summary) == "" {
+		return []string{}, nil
+	}
+
+	existing, err := s.videoRepo.ListTags(ctx, video.UserID, video.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	existingTags := make([]string, 0, min(len(existing), 50))
+	for _, t := range existing[:min(len(existing), 50)] {
+		existingTags = append(existingTags, t.Tag)
+	}
+
+	ctx = WithAIWorkspace(WithAIUsageScope(prompts.WithLocale(ctx, video.Language), userID, nil), video.WorkspaceID)
+	raw, err := s.aiService.SuggestTags(ctx, video.Summary, existingTags)
+	if err != nil {
+		return nil, err
+	}
+
+	// Невалидные предложения модели просто отбрасываем
+	suggested := make([]string, 0, len(raw))
+	seen := make(map[string]bool)
+	for _, tag := range raw {
+		tag, err := normalizeTag(tag)
+		if err != nil || seen[tag] || containsString(video.Tags, tag) {
+			continue
+		}
+		seen[tag] = true
+		suggested = append(suggested, tag)
+	}
+	if len(suggested) > models.MaxTagsPerVideo {
+		suggested = suggested[:models.MaxTagsPerVideo]
+	}
+
+	if err := s.videoRepo.UpdateSuggestedTags(ctx, videoID, suggested); err != nil {
+		return nil, err
+	}
+	return suggested, nil
+}
+
+func (s *libraryService) ListCollections(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]*models.CollectionNode, error) {
+	collections, err := s.collectionRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := s.videoRepo.CountByCollection(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[primitive.ObjectID]*models.CollectionNode, len(collections))
+	for _, c := range collections {
+		nodes[c.ID] = &models.CollectionNode{
+			Collection: c,
+			VideoCount: counts[c.ID],
+			Children:   []*models.CollectionNode{},
+		}
+	}
+
+	roots := []*models.CollectionNode{}
+	for _, c := range collections {
+		node := nodes[c.ID]
+		if c.ParentID != nil {
+			if parent, ok := nodes[*c.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	return roots, nil
+}
+
+func (s *libraryService) CreateCollection(ctx context.Context, userID primitive.ObjectID, req models.CreateCollectionRequest) (*models.Collection, error) {
+	name, err := normalizeCollectionName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	collection := &models.Collection{
+		UserID:      userID,
+		Name:        name,
+		Description: strings.TrimSpace(req.Description),
+	}
+
+	if req.ParentID != "" {
+		parentID, err := primitive.ObjectIDFromHex(req.ParentID)
+		if err != nil {
+			return nil, models.ErrCollectionNotFound
+		}
+
+		all, err := s.collectionRepo.GetByUser(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		byID := indexCollections(all)
+		if _, ok := byID[parentID]; !ok {
+			return nil, models.ErrCollectionNotFound
+		}
+		if !fitsCollectionDepth(byID, parentID, 0) {
+			return nil, models.ErrCollectionTooDeep
+		}
+		collection.ParentID = &parentID
+	}
+
+	if _, err := s.collectionRepo.Create(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *libraryService) UpdateCollection(ctx context.Context, userID, collectionID primitive.ObjectID, req models.UpdateCollectionRequest) (*models.Collection, error) {
+	all, err := s.collectionRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := indexCollections(all)
+
+	collection, ok := byID[collectionID]
+	if !ok {
+		return nil, models.ErrCollectionNotFound
+	}
+
+	if req.Name != nil {
+		if collection.Name, err = normalizeCollectionName(*req.Name); err != nil {
+			return nil, err
+		}
+	}
+	if req.Description != nil {
+		collection.Description = strings.TrimSpace(*req.Description)
+	}
+
+	if req.ParentID != nil && *req.ParentID != "" {
+		if *req.ParentID == "root" {
+			collection.ParentID = nil
+		} else {
+			parentID, err := primitive.ObjectIDFromHex(*req.ParentID)
+			if err != nil {
+				return nil, models.ErrCollectionNotFound
+			}
+			if _, ok := byID[parentID]; !ok {
+				return nil, models.ErrCollectionNotFound
+			}
+			// Нельзя переместить коллекцию внутрь самой себя или своего потомка
+			for _, id := range collectSubtree(all, collectionID) {
+				if id == parentID {
+					return nil, models.ErrCollectionCycle
+				}
+			}
+			if !fitsCollectionDepth(byID, parentID, subtreeHeight(all, collectionID)) {
+				return nil, models.ErrCollectionTooDeep
+			}
+			collection.ParentID = &parentID
+		}
+	}
+
+	if err := s.collectionRepo.Update(ctx, collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+// DeleteCollection удаляет коллекцию вместе с вложенными; сами видео остаются в библиотеке
+func (s *libraryService) DeleteCollection(ctx context.Context, userID, collectionID primitive.ObjectID) error {
+	all, err := s.collectionRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, ok := indexCollections(all)[collectionID]; !ok {
+		return models.ErrCollectionNotFound
+	}
+
+	ids := collectSubtree(all, collectionID)
+	if err := s.videoRepo.DetachCollections(ctx, ids); err != nil {
+		return err
+	}
+	return s.collectionRepo.DeleteMany(ctx, ids)
+}
+
+func (s *libraryService) AddVideosToCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, collectionID primitive.ObjectID, videoIDs []primitive.ObjectID) (*models.BulkResult, error) {
+	if err := s.checkCollectionOwner(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	if err := checkBulkVideos(videoIDs); err != nil {
+		return nil, err
+	}
+	return s.videoRepo.AddToCollection(ctx, userID, workspaceID, videoIDs, collectionID)
+}
+
+func (s *libraryService) RemoveVideosFromCollection(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, collectionID primitive.ObjectID, videoIDs []primitive.ObjectID) (*models.BulkResult, error) {
+	if err := s.checkCollectionOwner(ctx, userID, collectionID); err != nil {
+		return nil, err
+	}
+	if err := checkBulkVideos(videoIDs); err != nil {
+		return nil, err
+	}
+	return s.videoRepo.RemoveFromCollections(ctx, userID, workspaceID, videoIDs, []primitive.ObjectID{collectionID})
+}
+
+// ResolveCollection возвращает ID коллекции и, при includeNested, всех её потомков —
+// для фильтрации списков и поиска
+func (s *libraryService) ResolveCollection(ctx context.Context, userID, collectionID primitive.ObjectID, includeNested bool) ([]primitive.ObjectID, error) {
+	if !includeNested {
+		if err := s.checkCollectionOwner(ctx, userID, collectionID); err != nil {
+			return nil, err
+		}
+		return []primitive.ObjectID{collectionID}, nil
+	}
+
+	all, err := s.collectionRepo.GetByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := indexCollections(all)[collectionID]; !ok {
+		return nil, models.ErrCollectionNotFound
+	}
+	return collectSubtree(all, collectionID), nil
+}
+
+func (s *libraryService) checkCollectionOwner(ctx context.Context, userID, collectionID primitive.ObjectID) error {
+	collection, err := s.collectionRepo.GetByID(ctx, collectionID)
+	if err != nil {
+		return err
+	}
+	if collection.UserID != userID {
+		return models.ErrCollectionNotFound
+	}
+	return nil
+}
+
+func indexCollections(collections []*models.Collection) map[primitive.ObjectID]*models.Collection {
+	byID := make(map[primitive.ObjectID]*models.Collection, len(collections))
+	for _, c := range collections {
+		byID[c.ID] = c
+	}
+	return byID
+}
+
+// collectionDepth — глубина коллекции, у корневой 0
+func collectionDepth(byID map[primitive.ObjectID]*models.Collection, id primitive.ObjectID) int {
+	depth := 0
+	for c := byID[id]; c != nil && c.ParentID != nil && depth <= models.MaxCollectionDepth; c = byID[*c.ParentID] {
+		depth++
+	}
+	return depth
+}
+
+// fitsCollectionDepth проверяет, что поддерево высотой height под parentID
+// не глубже MaxCollectionDepth уровней: у самой глубокой коллекции глубина
+// не больше MaxCollectionDepth-1
+func fitsCollectionDepth(byID map[primitive.ObjectID]*models.Collection, parentID primitive.ObjectID, height int) bool {
+	return collectionDepth(byID, parentID)+1+height < models.MaxCollectionDepth
+}
+
+// collectSubtree возвращает rootID и ID всех его потомков
+func collectSubtree(collections []*models.Collection, rootID primitive.ObjectID) []primitive.ObjectID {
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, c := range collections {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+
+	ids := []primitive.ObjectID{rootID}
+	seen := map[primitive.ObjectID]bool{rootID: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// subtreeHeight — число уровней под коллекцией (0 для листа)
+func subtreeHeight(collections []*models.Collection, rootID primitive.ObjectID) int {
+	children := make(map[primitive.ObjectID][]primitive.ObjectID)
+	for _, c := range collections {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c.ID)
+		}
+	}
+
+	height := 0
+	level := []primitive.ObjectID{rootID}
+	for len(level) > 0 && height <= models.MaxCollectionDepth {
+		var next []primitive.ObjectID
+		for _, id := range level {
+			next = append(next, children[id]...)
+		}
+		if len(next) == 0 {
+			break
+		}
+		height++
+		level = next
+	}
+	return height
+}
+
+func normalizeTags(tags []string) ([]string, error) {
+	if len(tags) == 0 {
+		return nil, models.ErrInvalidTag
+	}
+	if len(tags) > models.MaxTagsPerVideo {
+		return nil, models.ErrTooManyTags
+	}
+
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool)
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			out = append(out, tag)
+		}
+	}
+	return out, nil
+}
+
+// normalizeTag приводит тег к нижнему регистру и схлопывает пробелы
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), " "))
+	tag = strings.Trim(tag, "#\"'")
+	if tag == "" || utf8.RuneCountInString(tag) > models.MaxTagLength {
+		return "", models.ErrInvalidTag
+	}
+	return tag, nil
+}
+
+func normalizeCollectionName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return "", models.ErrInvalidCollectionName
+	}
+	return name, nil
+}
+
+func checkBulkVideos(videoIDs []primitive.ObjectID) error {
+	if len(videoIDs) > models.MaxBulkVideos {
+		return models.ErrTooManyVideos
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
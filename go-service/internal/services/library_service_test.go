@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeCollectionRepo struct {
+	repository.CollectionRepository
+	collections []*models.Collection
+}
+
+func (f *fakeCollectionRepo) Create(ctx context.Context, collection *models.Collection) (primitive.ObjectID, error) {
+	collection.ID = primitive.NewObjectID()
+	f.collections = append(f.collections, collection)
+	return collection.ID, nil
+}
+
+func (f *fakeCollectionRepo) GetByUser(ctx context.Context, userID primitive.ObjectID) ([]*models.Collection, error) {
+	var out []*models.Collection
+	for _, c := range f.collections {
+		if c.UserID == userID {
+			copied := *c
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeCollectionRepo) Update(ctx context.Context, collection *models.Collection) error {
+	for i, c := range f.collections {
+		if c.ID == collection.ID {
+			f.collections[i] = collection
+			return nil
+		}
+	}
+	return models.ErrCollectionNotFound
+}
+
+// createChain создаёт цепочку из n вложенных коллекций и возвращает их от корня
+func createChain(t *testing.T, s LibraryService, userID primitive.ObjectID, n int) []*models.Collection {
+	t.Helper()
+	var chain []*models.Collection
+	parentID := ""
+	for range n {
+		c, err := s.CreateCollection(context.Background(), userID, models.CreateCollectionRequest{Name: "папка", ParentID: parentID})
+		if err != nil {
+			t.Fatalf("CreateCollection() at depth %d error = %v", len(chain), err)
+		}
+		chain = append(chain, c)
+		parentID = c.ID.Hex()
+	}
+	return chain
+}
+
+func TestLibraryServiceCollectionDepth(t *testing.T) {
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+	s := &libraryService{collectionRepo: &fakeCollectionRepo{}}
+
+	chain := createChain(t, s, userID, models.MaxCollectionDepth)
+	deepest := chain[len(chain)-1]
+
+	// Создание ещё одного уровня под самой глубокой коллекцией
+	_, err := s.CreateCollection(ctx, userID, models.CreateCollectionRequest{Name: "глубже", ParentID: deepest.ID.Hex()})
+	if !errors.Is(err, models.ErrCollectionTooDeep) {
+		t.Fatalf("CreateCollection() below depth %d error = %v, want %v", models.MaxCollectionDepth-1, err, models.ErrCollectionTooDeep)
+	}
+
+	tests := []struct {
+		name    string
+		height  int // уровней под перемещаемой коллекцией
+		parent  int // индекс нового родителя в цепочке
+		wantErr error
+	}{
+		{name: "leaf to the deepest level", height: 0, parent: len(chain) - 2},
+		{name: "leaf below the deepest level", height: 0, parent: len(chain) - 1, wantErr: models.ErrCollectionTooDeep},
+		{name: "subtree to the limit", height: 2, parent: len(chain) - 4},
+		{name: "subtree over the limit", height: 2, parent: len(chain) - 3, wantErr: models.ErrCollectionTooDeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			moved := createChain(t, s, userID, tt.height+1)[0]
+			parentID := chain[tt.parent].ID.Hex()
+			_, err := s.UpdateCollection(ctx, userID, moved.ID, models.UpdateCollectionRequest{ParentID: &parentID})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateCollection() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+type fakeTagsRepo struct {
+	repository.VideoRepository
+	tags      map[primitive.ObjectID][]models.TagCount
+	libraryID primitive.ObjectID
+	suggested []string
+}
+
+func (f *fakeTagsRepo) ListTags(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID) ([]models.TagCount, error) {
+	f.libraryID = models.LibraryID(userID, workspaceID)
+	return f.tags[f.libraryID], nil
+}
+
+func (f *fakeTagsRepo) UpdateSuggestedTags(ctx context.Context, id primitive.ObjectID, tags []string) error {
+	f.suggested = tags
+	return nil
+}
+
+type fakeTagsAI struct {
+	AIService
+	existing []string
+}
+
+func (f *fakeTagsAI) SuggestTags(ctx context.Context, summary string, existingTags []string) ([]string, error) {
+	f.existing = existingTags
+	return []string{"Релиз", "бюджет"}, nil
+}
+
+func TestLibraryServiceSuggestTags(t *testing.T) {
+	workspaceID := primitive.NewObjectID()
+	video := &models.Video{
+		ID:          primitive.NewObjectID(),
+		UserID:      primitive.NewObjectID(),
+		WorkspaceID: &workspaceID,
+		Summary:     "Команда обсудила релиз",
+		Tags:        []string{"бюджет"},
+	}
+
+	tests := []struct {
+		name    string
+		role    string
+		wantErr error
+	}{
+		{name: "editor", role: models.WorkspaceRoleEditor},
+		{name: "viewer", role: models.WorkspaceRoleViewer, wantErr: models.ErrWorkspaceAccessDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeTagsRepo{tags: map[primitive.ObjectID][]models.TagCount{
+				workspaceID:  {{Tag: "общий", Count: 3}},
+				video.UserID: {{Tag: "личный", Count: 5}},
+			}}
+			ai := &fakeTagsAI{}
+			access := &fakeVideoAccess{video: video, role: tt.role}
+			s := &libraryService{videoRepo: repo, summaryService: access, aiService: ai}
+
+			got, err := s.SuggestTags(context.Background(), primitive.NewObjectID(), video.ID)
+			if access.asked != models.WorkspaceRoleEditor {
+				t.Errorf("AuthorizeVideo() asked for role %q, want editor", access.asked)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SuggestTags() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			// Подсказки опираются на теги библиотеки воркспейса, а не личной библиотеки автора
+			if repo.libraryID != workspaceID || len(ai.existing) != 1 || ai.existing[0] != "общий" {
+				t.Errorf("existing tags = %q from library %s, want workspace tags", ai.existing, repo.libraryID.Hex())
+			}
+			if len(got) != 1 || got[0] != "релиз" || len(repo.suggested) != 1 {
+				t.Errorf("SuggestTags() = %q, saved %q, want [релиз]", got, repo.suggested)
+			}
+		})
+	}
+}
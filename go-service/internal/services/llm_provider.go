@@ -0,0 +1,805 @@
+// services/llm_provider.go
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/config"
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// TokenUsage — расход токенов запроса по данным провайдера; нули — провайдер не сообщил
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	// Стоимость в USD, если провайдер её сообщает (OpenRouter)
+	Cost float64
+}
+
+// ChatResult — ответ модели и расход на него
+type ChatResult struct {
+	Content string
+	// Модель, фактически ответившая на запрос, если провайдер её сообщает
+	Model string
+	Usage TokenUsage
+}
+
+// LLMProvider выполняет chat completion у конкретного бэкенда.
+// Пустая модель означает модель провайдера по умолчанию.
+type LLMProvider interface {
+	Chat(ctx context.Context, model string, messages []ChatMessage) (ChatResult, error)
+	// ChatStream передаёт фрагменты ответа в onDelta и возвращает полный текст;
+	// ошибка из onDelta прерывает запрос, полученная часть остаётся в результате
+	ChatStream(ctx context.Context, model string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error)
+	DefaultModel() string
+}
+
+type llmOperationKey struct{}
+
+type responseSchemaKey struct{}
+
+// withLLMOperation сообщает провайдеру, для какой операции запрос
+func withLLMOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, llmOperationKey{}, operation)
+}
+
+func llmOperationFrom(ctx context.Context) string {
+	operation, _ := ctx.Value(llmOperationKey{}).(string)
+	return operation
+}
+
+// withResponseSchema сообщает провайдеру схему ожидаемого JSON-ответа
+func withResponseSchema(ctx context.Context, schema *JSONSchema) context.Context {
+	return context.WithValue(ctx, responseSchemaKey{}, schema)
+}
+
+func responseSchemaFrom(ctx context.Context) *JSONSchema {
+	schema, _ := ctx.Value(responseSchemaKey{}).(*JSONSchema)
+	return schema
+}
+
+func NewLLMProvider(name string, cfg *config.LLMConfig) (LLMProvider, error) {
+	switch name {
+	case "openrouter":
+		return NewOpenRouterProvider(cfg.OpenRouter), nil
+	case "openai":
+		return NewOpenAIProvider(cfg.OpenAI), nil
+	case "ollama":
+		return NewOllamaProvider(cfg.Ollama), nil
+	case "fake":
+		return NewFakeLLMProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown LLM provider: %s", name)
+	}
+}
+
+// llmProviderNames — провайдеры, которые можно указать префиксом запасной модели
+var llmProviderNames = map[string]bool{"openrouter": true, "openai": true, "ollama": true, "fake": true}
+
+type llmRoute struct {
+	provider LLMProvider
+	model    string
+}
+
+// LLMRouter выбирает цепочку моделей для операции (config.LLMOperationChat, ...):
+// основную и запасные по порядку; без настройки операции — провайдер по умолчанию.
+type LLMRouter struct {
+	config       *config.LLMConfig
+	defaultChain []llmRoute
+	chains       map[string][]llmRoute
+}
+
+func NewLLMRouter(cfg *config.LLMConfig) (*LLMRouter, error) {
+	providers := make(map[string]LLMProvider)
+	provider := func(name string) (LLMProvider, error) {
+		if p, ok := providers[name]; ok {
+			return p, nil
+		}
+		p, err := NewLLMProvider(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		providers[name] = p
+		return p, nil
+	}
+
+	defaultProvider, err := provider(cfg.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	// chain собирает основную модель и запасные; "provider:model" переключает провайдера,
+	// иначе двоеточие считается частью имени модели (llama3.1:8b, …:free)
+	chain := func(primary LLMProvider, model string, fallbacks []string) ([]llmRoute, error) {
+		routes := []llmRoute{{provider: primary, model: model}}
+		for _, entry := range fallbacks {
+			route := llmRoute{provider: primary, model: entry}
+			if name, m, ok := strings.Cut(entry, ":"); ok && llmProviderNames[name] {
+				p, err := provider(name)
+				if err != nil {
+					return nil, err
+				}
+				route = llmRoute{provider: p, model: m}
+			}
+			routes = append(routes, route)
+		}
+		for i := range routes {
+			if routes[i].model == "" {
+				routes[i].model = routes[i].provider.DefaultModel()
+			}
+		}
+		return routes, nil
+	}
+
+	defaultChain, err := chain(defaultProvider, "", cfg.FallbackModels)
+	if err != nil {
+		return nil, err
+	}
+
+	router := &LLMRouter{
+		config:       cfg,
+		defaultChain: defaultChain,
+		chains:       make(map[string][]llmRoute),
+	}
+
+	for op, route := range cfg.Operations {
+		if route.Provider == "" && route.Model == "" && len(route.Fallbacks) == 0 {
+			continue
+		}
+		p := defaultProvider
+		if route.Provider != "" {
+			if p, err = provider(route.Provider); err != nil {
+				return nil, fmt.Errorf("operation %s: %w", op, err)
+			}
+		}
+		fallbacks := route.Fallbacks
+		if len(fallbacks) == 0 {
+			fallbacks = cfg.FallbackModels
+		}
+		if router.chains[op], err = chain(p, route.Model, fallbacks); err != nil {
+			return nil, fmt.Errorf("operation %s: %w", op, err)
+		}
+	}
+
+	return router, nil
+}
+
+func (r *LLMRouter) chain(operation string) []llmRoute {
+	if chain, ok := r.chains[operation]; ok {
+		return chain
+	}
+	return r.defaultChain
+}
+
+// Budget возвращает бюджет контекста операции с учётом резерва под ответ —
+// по самому узкому окну цепочки, чтобы запрос поместился и в запасную модель
+func (r *LLMRouter) Budget(operation string) ContextBudget {
+	var budget ContextBudget
+	for i, route := range r.chain(operation) {
+		if b := newContextBudget(r.config, route.model); i == 0 || b.Input < budget.Input {
+			budget = b
+		}
+	}
+	return budget
+}
+
+// OpenAICompatibleProvider работает с любым OpenAI-совместимым /chat/completions
+type OpenAICompatibleProvider struct {
+	apiKey  string
+	baseURL string
+	model   string
+	headers map[string]string
+	// OpenRouter по запросу возвращает стоимость в usage.cost
+	reportCost bool
+	client     *http.Client
+	// Для стриминга общий таймаут не подходит: ограничиваем только ожидание заголовков,
+	// дальше запрос живёт, пока жив контекст
+	streamClient *http.Client
+}
+
+func NewOpenAIProvider(cfg *config.OpenAIConfig) LLMProvider {
+	return newOpenAICompatibleProvider(cfg.APIKey, cfg.BaseURL, cfg.Model, cfg.Timeout, nil)
+}
+
+// NewOpenRouterProvider — OpenAI-совместимый API с заголовками атрибуции OpenRouter
+func NewOpenRouterProvider(cfg *config.OpenRouterConfig) LLMProvider {
+	p := newOpenAICompatibleProvider(cfg.APIKey, cfg.BaseURL, cfg.Model, cfg.Timeout, map[string]string{
+		"HTTP-Referer": "https://vidnotes.app",
+		"X-Title":      "VidNotes AI",
+	})
+	p.reportCost = true
+	return p
+}
+
+func newOpenAICompatibleProvider(apiKey, baseURL, model string, timeout int, headers map[string]string) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		apiKey:  apiKey,
+		baseURL: baseURL,
+		model:   model,
+		headers: headers,
+		client: &http.Client{
+			Timeout: time.Duration(timeout) * time.Second,
+		},
+		streamClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: time.Duration(timeout) * time.Second,
+			},
+		},
+	}
+}
+
+type chatCompletionRequest struct {
+	Model         string         `json:"model"`
+	Messages      []ChatMessage  `json:"messages"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+	// Учёт стоимости OpenRouter
+	Usage *usageOptions `json:"usage,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+type usageOptions struct {
+	Include bool `json:"include"`
+}
+
+type chatCompletionUsage struct {
+	PromptTokens     int     `json:"prompt_tokens"`
+	CompletionTokens int     `json:"completion_tokens"`
+	Cost             float64 `json:"cost"`
+}
+
+func (u *chatCompletionUsage) tokenUsage() TokenUsage {
+	if u == nil {
+		return TokenUsage{}
+	}
+	return TokenUsage{PromptTokens: u.PromptTokens, CompletionTokens: u.CompletionTokens, Cost: u.Cost}
+}
+
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message ChatMessage `json:"message"`
+	} `json:"choices"`
+	Usage *chatCompletionUsage `json:"usage,omitempty"`
+	Error *openAIError         `json:"error,omitempty"`
+}
+
+// chatCompletionChunk — событие SSE-потока chat/completions; usage приходит
+// в последнем событии с пустым choices
+type chatCompletionChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta ChatMessage `json:"delta"`
+	} `json:"choices"`
+	Usage *chatCompletionUsage `json:"usage,omitempty"`
+	Error *openAIError         `json:"error,omitempty"`
+}
+
+type openAIError struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+func (p *OpenAICompatibleProvider) DefaultModel() string {
+	return p.model
+}
+
+func (p *OpenAICompatibleProvider) Chat(ctx context.Context, model string, messages []ChatMessage) (ChatResult, error) {
+	req, err := p.newRequest(ctx, model, messages, false)
+	if err != nil {
+		return ChatResult{}, err
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return ChatResult{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return ChatResult{}, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return ChatResult{}, chatStatusError(resp, body)
+	}
+
+	var completion chatCompletionResponse
+	if err := json.Unmarshal(body, &completion); err != nil {
+		return ChatResult{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if len(completion.Choices) == 0 {
+		return ChatResult{}, fmt.Errorf("no choices in response")
+	}
+
+	return ChatResult{
+		Content: completion.Choices[0].Message.Content,
+		Model:   completion.Model,
+		Usage:   completion.Usage.tokenUsage(),
+	}, nil
+}
+
+// ChatStream читает SSE-поток: строки "data: {...}" до "data: [DONE]",
+// строки-комментарии (": OPENROUTER PROCESSING") пропускаются
+func (p *OpenAICompatibleProvider) ChatStream(ctx context.Context, model string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	req, err := p.newRequest(ctx, model, messages, true)
+	if err != nil {
+		return ChatResult{}, err
+	}
+
+	resp, err := p.streamClient.Do(req)
+	if err != nil {
+		return ChatResult{}, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return ChatResult{}, fmt.Errorf("failed to read response: %w", err)
+		}
+		return ChatResult{}, chatStatusError(resp, body)
+	}
+
+	var content strings.Builder
+	var result ChatResult
+	partial := func(err error) (ChatResult, error) {
+		result.Content = content.String()
+		return result, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return partial(nil)
+		}
+
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return partial(fmt.Errorf("failed to parse stream chunk: %w", err))
+		}
+		if chunk.Error != nil {
+			// Ошибка внутри потока — сбой апстрима после ответа 200
+			return partial(&LLMStatusError{StatusCode: http.StatusBadGateway, Message: "LLM error: " + chunk.Error.Message})
+		}
+		if chunk.Model != "" {
+			result.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			result.Usage = chunk.Usage.tokenUsage()
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return partial(err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return partial(fmt.Errorf("failed to read stream: %w", err))
+	}
+
+	// Поток закрыт без [DONE] — считаем ответ полным, если что-то пришло
+	if content.Len() == 0 {
+		return ChatResult{}, fmt.Errorf("empty stream response")
+	}
+	return partial(nil)
+}
+
+func (p *OpenAICompatibleProvider) newRequest(ctx context.Context, model string, messages []ChatMessage, stream bool) (*http.Request, error) {
+	if p.apiKey == "" {
+		return nil, models.ErrAIServiceUnavailable
+	}
+	if model == "" {
+		model = p.model
+	}
+
+	body := chatCompletionRequest{Model: model, Messages: messages, Stream: stream}
+	if stream {
+		body.StreamOptions = &streamOptions{IncludeUsage: true}
+	}
+	if p.reportCost {
+		body.Usage = &usageOptions{Include: true}
+	}
+
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/chat/completions", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	for key, value := range p.headers {
+		req.Header.Set(key, value)
+	}
+	if stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+
+	return req, nil
+}
+
+func chatStatusError(resp *http.Response, body []byte) error {
+	statusErr := &LLMStatusError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+		Message:    "LLM API error: " + string(body),
+	}
+	var errorResp chatCompletionResponse
+	if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != nil {
+		statusErr.Message = "LLM error: " + errorResp.Error.Message
+	}
+	return statusErr
+}
+
+// OllamaProvider работает с локальным Ollama через /api/chat;
+// при стриминге ответ приходит NDJSON-строками до "done": true
+type OllamaProvider struct {
+	baseURL      string
+	model        string
+	client       *http.Client
+	streamClient *http.Client
+}
+
+func NewOllamaProvider(cfg *config.OllamaConfig) LLMProvider {
+	return &OllamaProvider{
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		model:   cfg.Model,
+		client: &http.Client{
+			Timeout: time.Duration(cfg.Timeout) * time.Second,
+		},
+		streamClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				ResponseHeaderTimeout: time.Duration(cfg.Timeout) * time.Second,
+			},
+		},
+	}
+}
+
+type ollamaChatRequest struct {
+	Model    string        `json:"model"`
+	Messages []ChatMessage `json:"messages"`
+	Stream   bool          `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Model   string      `json:"model"`
+	Message ChatMessage `json:"message"`
+	Done    bool        `json:"done"`
+	Error   string      `json:"error,omitempty"`
+	// Счётчики токенов приходят в финальном сообщении
+	PromptEvalCount int `json:"prompt_eval_count"`
+	EvalCount       int `json:"eval_count"`
+}
+
+func (r *ollamaChatResponse) tokenUsage() TokenUsage {
+	return TokenUsage{PromptTokens: r.PromptEvalCount, CompletionTokens: r.EvalCount}
+}
+
+func (p *OllamaProvider) DefaultModel() string {
+	return p.model
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, model string, messages []ChatMessage) (ChatResult, error) {
+	resp, err := p.send(ctx, p.client, model, messages, false)
+	if err != nil {
+		return ChatResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var chat ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&chat); err != nil {
+		return ChatResult{}, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if chat.Error != "" {
+		return ChatResult{}, fmt.Errorf("ollama error: %s", chat.Error)
+	}
+
+	return ChatResult{Content: chat.Message.Content, Model: chat.Model, Usage: chat.tokenUsage()}, nil
+}
+
+func (p *OllamaProvider) ChatStream(ctx context.Context, model string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	resp, err := p.send(ctx, p.streamClient, model, messages, true)
+	if err != nil {
+		return ChatResult{}, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var result ChatResult
+	partial := func(err error) (ChatResult, error) {
+		result.Content = content.String()
+		return result, err
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return partial(fmt.Errorf("failed to parse stream chunk: %w", err))
+		}
+		if chunk.Error != "" {
+			return partial(fmt.Errorf("ollama error: %s", chunk.Error))
+		}
+
+		if delta := chunk.Message.Content; delta != "" {
+			content.WriteString(delta)
+			if err := onDelta(delta); err != nil {
+				return partial(err)
+			}
+		}
+		if chunk.Done {
+			result.Model = chunk.Model
+			result.Usage = chunk.tokenUsage()
+			return partial(nil)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return partial(fmt.Errorf("failed to read stream: %w", err))
+	}
+	if content.Len() == 0 {
+		return ChatResult{}, fmt.Errorf("empty stream response")
+	}
+	return partial(nil)
+}
+
+func (p *OllamaProvider) send(ctx context.Context, client *http.Client, model string, messages []ChatMessage, stream bool) (*http.Response, error) {
+	if model == "" {
+		model = p.model
+	}
+
+	jsonData, err := json.Marshal(ollamaChatRequest{Model: model, Messages: messages, Stream: stream})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/api/chat", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// Локальный сервер не запущен
+		return nil, fmt.Errorf("%w: %v", models.ErrAIServiceUnavailable, err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		statusErr := &LLMStatusError{
+			StatusCode: resp.StatusCode,
+			RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+			Message:    "ollama API error: " + string(body),
+		}
+		var errorResp ollamaChatResponse
+		if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error != "" {
+			statusErr.Message = "ollama error: " + errorResp.Error
+		}
+		return nil, statusErr
+	}
+
+	return resp, nil
+}
+
+// FakeLLMProvider отвечает детерминированно и без сети — для тестов и офлайн-разработки.
+// Ответ выбирается по операции и схеме запроса, а не по тексту промпта: на подсказку
+// тегов — самые частые слова, на перевод сегментов — исходные сегменты с пометкой,
+// на запрос со схемой — минимальный подходящий под неё JSON, на остальные — начало
+// последнего сообщения пользователя.
+type FakeLLMProvider struct{}
+
+func NewFakeLLMProvider() LLMProvider {
+	return &FakeLLMProvider{}
+}
+
+func (p *FakeLLMProvider) DefaultModel() string {
+	return "fake"
+}
+
+func (p *FakeLLMProvider) Chat(ctx context.Context, model string, messages []ChatMessage) (ChatResult, error) {
+	return ChatResult{Content: fakeReply(ctx, messages)}, nil
+}
+
+func (p *FakeLLMProvider) ChatStream(ctx context.Context, model string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	reply := fakeReply(ctx, messages)
+
+	// Как у настоящих провайдеров: при обрыве возвращается уже отданная часть
+	var sent strings.Builder
+	for _, word := range strings.SplitAfter(reply, " ") {
+		if err := ctx.Err(); err != nil {
+			return ChatResult{Content: sent.String()}, err
+		}
+		sent.WriteString(word)
+		if err := onDelta(word); err != nil {
+			return ChatResult{Content: sent.String()}, err
+		}
+	}
+	return ChatResult{Content: reply}, nil
+}
+
+func fakeReply(ctx context.Context, messages []ChatMessage) string {
+	var system, first, last string
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = msg.Content
+		case "user":
+			if first == "" {
+				first = msg.Content
+			}
+			last = msg.Content
+		}
+	}
+
+	schema := responseSchemaFrom(ctx)
+	switch operation := llmOperationFrom(ctx); {
+	case operation == config.LLMOperationTags:
+		// Слова инструкций (системное сообщение и первая строка запроса) тегами не считаются
+		instruction, _, _ := strings.Cut(last, "\n")
+		tags, _ := json.Marshal(fakeTopWords(last, system+" "+instruction, 5))
+		return string(tags)
+	case operation == config.LLMOperationTranslate && schema != nil:
+		// Исправления после невалидного ответа не нужны: исходные сегменты — в первом запросе
+		if reply, ok := fakeTranslatedSegments(first); ok {
+			return reply
+		}
+	}
+
+	if utf8.RuneCountInString(last) > 500 {
+		last = string([]rune(last)[:500])
+	}
+	if schema != nil {
+		reply, _ := json.Marshal(fakeSchemaValue(schema, "[fake] "+strings.TrimSpace(last)))
+		return string(reply)
+	}
+	return "[fake] " + last
+}
+
+// fakeTranslatedSegments возвращает сегменты из запроса перевода с пометкой [fake]:
+// номера сегментов должны совпасть с исходными
+func fakeTranslatedSegments(prompt string) (string, bool) {
+	start := strings.IndexByte(prompt, '{')
+	if start < 0 {
+		return "", false
+	}
+
+	var source translatedSegments
+	if err := json.NewDecoder(strings.NewReader(prompt[start:])).Decode(&source); err != nil || len(source.Segments) == 0 {
+		return "", false
+	}
+	for i := range source.Segments {
+		source.Segments[i].Text = "[fake] " + source.Segments[i].Text
+	}
+
+	reply, err := json.Marshal(source)
+	if err != nil {
+		return "", false
+	}
+	return string(reply), true
+}
+
+// fakeSchemaValue строит минимальное значение, проходящее схему: только обязательные
+// поля, по одному элементу в массивах, если схема позволяет, строки — text
+func fakeSchemaValue(schema *JSONSchema, text string) any {
+	if schema == nil {
+		return nil
+	}
+	switch schema.Type {
+	case "object":
+		object := make(map[string]any, len(schema.Required))
+		for _, name := range schema.Required {
+			if property, ok := schema.Properties[name]; ok {
+				object[name] = fakeSchemaValue(property, text)
+			}
+		}
+		return object
+	case "array":
+		count := 1
+		if schema.MinItems != nil && *schema.MinItems > count {
+			count = *schema.MinItems
+		}
+		if schema.MaxItems != nil && *schema.MaxItems < count {
+			count = *schema.MaxItems
+		}
+		items := make([]any, count)
+		for i := range items {
+			items[i] = fakeSchemaValue(schema.Items, text)
+		}
+		return items
+	case "integer", "number":
+		if schema.Minimum != nil {
+			return math.Ceil(*schema.Minimum)
+		}
+		return 0
+	case "boolean":
+		return false
+	default:
+		return text
+	}
+}
+
+func fakeTopWords(text, exclude string, limit int) []string {
+	splitWords := func(s string) []string {
+		return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r)
+		})
+	}
+
+	skip := make(map[string]bool)
+	for _, word := range splitWords(exclude) {
+		skip[word] = true
+	}
+
+	counts := make(map[string]int)
+	for _, word := range splitWords(text) {
+		if utf8.RuneCountInString(word) >= 5 && !skip[word] {
+			counts[word]++
+		}
+	}
+
+	words := make([]string, 0, len(counts))
+	for word := range counts {
+		words = append(words, word)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+
+	if len(words) > limit {
+		words = words[:limit]
+	}
+	return words
+}
@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/code-zt/vidnotes/config"
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+)
+
+// newFakeAIService — AI-сервис с LLM_PROVIDER=fake, как в офлайн-разработке
+func newFakeAIService(t *testing.T) AIService {
+	t.Helper()
+	router, err := NewLLMRouter(&config.LLMConfig{Provider: "fake", MaxInputTokens: 16000, ResponseTokens: 1024})
+	if err != nil {
+		t.Fatalf("NewLLMRouter: %v", err)
+	}
+	registry, err := prompts.NewRegistry("")
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	return NewAIService(router, registry, nil, nil)
+}
+
+func TestFakeLLMProviderOperations(t *testing.T) {
+	ai := newFakeAIService(t)
+	ctx := context.Background()
+	source := VideoSource{
+		Title:      "Планёрка",
+		Summary:    "Команда обсудила релиз и бюджет",
+		Transcript: "[00:00] Обсуждаем релиз\n[00:30] Бюджет утвердили",
+	}
+
+	t.Run("tags", func(t *testing.T) {
+		tags, err := ai.SuggestTags(ctx, "Релиз релиз бюджет бюджет бюджет", nil)
+		if err != nil || len(tags) == 0 {
+			t.Fatalf("SuggestTags() = %q, %v", tags, err)
+		}
+	})
+
+	t.Run("quiz", func(t *testing.T) {
+		quiz, err := ai.GenerateQuiz(ctx, source, 3, 2)
+		if err != nil {
+			t.Fatalf("GenerateQuiz() error = %v", err)
+		}
+		if len(quiz.Questions) == 0 || len(quiz.Flashcards) == 0 || quiz.Model != "fake" {
+			t.Errorf("GenerateQuiz() = %d questions, %d flashcards, model %q",
+				len(quiz.Questions), len(quiz.Flashcards), quiz.Model)
+		}
+	})
+
+	t.Run("insights", func(t *testing.T) {
+		insights, err := ai.ExtractInsights(ctx, source)
+		if err != nil {
+			t.Fatalf("ExtractInsights() error = %v", err)
+		}
+		if len(insights.KeyPoints) == 0 {
+			t.Error("ExtractInsights() returned no key points")
+		}
+	})
+
+	t.Run("translate segments", func(t *testing.T) {
+		segments := []models.TranscriptSegment{
+			{ID: 4, Start: 0, End: 30, Text: "Обсуждаем релиз"},
+			{ID: 7, Start: 30, End: 42, Text: "Бюджет утвердили"},
+		}
+		translated, err := ai.TranslateSegments(ctx, segments, "English")
+		if err != nil {
+			t.Fatalf("TranslateSegments() error = %v", err)
+		}
+		if len(translated) != len(segments) {
+			t.Fatalf("TranslateSegments() returned %d segments, want %d", len(translated), len(segments))
+		}
+		for i, seg := range translated {
+			if seg.ID != segments[i].ID || !strings.Contains(seg.Text, segments[i].Text) {
+				t.Errorf("segment %d = %d %q, want id %d with %q", i, seg.ID, seg.Text, segments[i].ID, segments[i].Text)
+			}
+		}
+	})
+
+	t.Run("translate text", func(t *testing.T) {
+		text, err := ai.TranslateText(ctx, "Бюджет утвердили", "English")
+		if err != nil || !strings.HasPrefix(text, "[fake]") {
+			t.Errorf("TranslateText() = %q, %v", text, err)
+		}
+	})
+}
+
+func TestFakeSchemaValue(t *testing.T) {
+	schemas := map[string]*JSONSchema{
+		"quiz":     quizSchema(3, 2),
+		"insights": insightsSchema(),
+	}
+
+	for name, schema := range schemas {
+		t.Run(name, func(t *testing.T) {
+			reply := fakeReply(withResponseSchema(context.Background(), schema), []ChatMessage{{Role: "user", Content: "текст"}})
+			var out map[string]any
+			if err := schema.Decode([]byte(reply), &out); err != nil {
+				t.Errorf("fake reply %s does not match schema: %v", reply, err)
+			}
+		})
+	}
+}
+
+func TestFakeLLMProviderStreamInterrupted(t *testing.T) {
+	messages := []ChatMessage{{Role: "user", Content: "раз два три четыре"}}
+	stop := errors.New("client gone")
+
+	tests := []struct {
+		name    string
+		cancel  bool
+		wantErr error
+		want    string
+	}{
+		{name: "onDelta error", wantErr: stop, want: "[fake] раз "},
+		{name: "context canceled", cancel: true, wantErr: context.Canceled, want: "[fake] раз "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			deltas := 0
+			result, err := NewFakeLLMProvider().ChatStream(ctx, "fake", messages, func(delta string) error {
+				deltas++
+				if deltas < 2 {
+					return nil
+				}
+				if tt.cancel {
+					cancel()
+					return nil
+				}
+				return stop
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChatStream() error = %v, want %v", err, tt.wantErr)
+			}
+			if result.Content != tt.want {
+				t.Errorf("ChatStream() content = %q, want %q", result.Content, tt.want)
+			}
+		})
+	}
+}
+
+// scriptedLLMProvider отвечает репликами по порядку и запоминает последний запрос
+type scriptedLLMProvider struct {
+	replies []string
+	last    []ChatMessage
+}
+
+func (p *scriptedLLMProvider) Chat(ctx context.Context, model string, messages []ChatMessage) (ChatResult, error) {
+	p.last = messages
+	reply := p.replies[0]
+	p.replies = p.replies[1:]
+	return ChatResult{Content: reply, Model: model}, nil
+}
+
+func (p *scriptedLLMProvider) ChatStream(ctx context.Context, model string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	return p.Chat(ctx, model, messages)
+}
+
+func (p *scriptedLLMProvider) DefaultModel() string { return "scripted" }
+
+func TestCompleteJSONCorrection(t *testing.T) {
+	provider := &scriptedLLMProvider{replies: []string{`{"title": 1}`, `{"title": "Планёрка"}`}}
+	registry, err := prompts.NewRegistry("")
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	s := &aiService{
+		router:  &LLMRouter{config: &config.LLMConfig{}, defaultChain: []llmRoute{{provider: provider, model: "scripted"}}},
+		prompts: registry,
+	}
+	schema := &JSONSchema{Type: "object", Properties: map[string]*JSONSchema{"title": {Type: "string"}}, Required: []string{"title"}}
+
+	out, _, err := completeJSON[struct{ Title string }](context.Background(), s, config.LLMOperationInsights, prompts.Prompt{User: "Назови встречу"}, schema, nil)
+	if err != nil {
+		t.Fatalf("completeJSON() error = %v", err)
+	}
+	if out.Title != "Планёрка" {
+		t.Errorf("completeJSON() title = %q, want corrected reply", out.Title)
+	}
+
+	// Запрос на исправление рендерится из шаблона structured_correction
+	if len(provider.last) != 3 {
+		t.Fatalf("retry sent %d messages, want prompt, invalid reply and correction", len(provider.last))
+	}
+	correction := provider.last[2]
+	if correction.Role != "user" || !strings.HasPrefix(correction.Content, "Your reply does not match the JSON schema: ") ||
+		!strings.HasSuffix(correction.Content, "Reply again with only the corrected JSON object.") {
+		t.Errorf("correction message = %s %q", correction.Role, correction.Content)
+	}
+}
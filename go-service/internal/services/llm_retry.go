@@ -0,0 +1,144 @@
+// services/llm_retry.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+// LLMStatusError — ответ API модели с кодом ошибки
+type LLMStatusError struct {
+	StatusCode int
+	// Пауза из заголовка Retry-After; 0 — не указана
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *LLMStatusError) Error() string {
+	return e.Message
+}
+
+// parseRetryAfter разбирает Retry-After в секундах или в виде HTTP-даты
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(0, time.Duration(seconds)*time.Second)
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(at))
+	}
+	return 0
+}
+
+// retryableLLMError — временный сбой, который имеет смысл повторить на той же модели
+func retryableLLMError(err error) bool {
+	var statusErr *LLMStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusTooManyRequests ||
+			statusErr.StatusCode == http.StatusRequestTimeout ||
+			statusErr.StatusCode >= http.StatusInternalServerError
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// fallbackLLMError — ошибка, после которой стоит попробовать следующую модель:
+// временные сбои, исчерпавшие повторы, недоступный провайдер и неизвестная модель
+func fallbackLLMError(err error) bool {
+	if retryableLLMError(err) || errors.Is(err, models.ErrAIServiceUnavailable) {
+		return true
+	}
+	var statusErr *LLMStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
+
+// Chat отправляет запрос по цепочке моделей операции, потоково при onDelta != nil.
+// Временные сбои повторяются с растущей паузой и разбросом, с учётом Retry-After;
+// если модель так и не ответила, запрос уходит следующей. Начатый поток не
+// повторяется: клиент уже получил часть ответа. В результате всегда указана
+// модель, которая ответила.
+func (r *LLMRouter) Chat(ctx context.Context, operation string, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	chain := r.chain(operation)
+	ctx = withLLMOperation(ctx, operation)
+
+	var lastErr error
+	for i, route := range chain {
+		result, err := r.attempt(ctx, route, messages, onDelta)
+		if result.Model == "" {
+			result.Model = route.model
+		}
+		if err == nil || result.Content != "" || ctx.Err() != nil || !fallbackLLMError(err) {
+			return result, err
+		}
+
+		lastErr = err
+		if i+1 < len(chain) {
+			fmt.Printf("LLM model %s failed, falling back to %s: %v\n", route.model, chain[i+1].model, err)
+		}
+	}
+
+	return ChatResult{}, lastErr
+}
+
+// attempt вызывает одну модель, повторяя временные сбои до MaxRetries раз
+func (r *LLMRouter) attempt(ctx context.Context, route llmRoute, messages []ChatMessage, onDelta func(delta string) error) (ChatResult, error) {
+	for retry := 0; ; retry++ {
+		var result ChatResult
+		var err error
+		if onDelta != nil {
+			result, err = route.provider.ChatStream(ctx, route.model, messages, onDelta)
+		} else {
+			result, err = route.provider.Chat(ctx, route.model, messages)
+		}
+
+		if err == nil || result.Content != "" || ctx.Err() != nil ||
+			retry >= r.config.Retry.MaxRetries || !retryableLLMError(err) {
+			return result, err
+		}
+
+		delay, ok := r.retryDelay(err, retry)
+		if !ok {
+			return result, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+	}
+}
+
+// retryDelay — пауза перед повтором: экспоненциальная с разбросом в половину,
+// но не меньше Retry-After. Если сервер просит ждать дольше MaxDelayMs,
+// повтор не имеет смысла — лучше перейти к следующей модели.
+func (r *LLMRouter) retryDelay(err error, retry int) (time.Duration, bool) {
+	base := time.Duration(r.config.Retry.BaseDelayMs) * time.Millisecond
+	maxDelay := time.Duration(r.config.Retry.MaxDelayMs) * time.Millisecond
+
+	delay := min(base<<min(retry, 16), maxDelay)
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+
+	var statusErr *LLMStatusError
+	if errors.As(err, &statusErr) && statusErr.RetryAfter > 0 {
+		if statusErr.RetryAfter > maxDelay {
+			return 0, false
+		}
+		delay = max(delay, statusErr.RetryAfter)
+	}
+	return delay, true
+}
@@ -0,0 +1,414 @@
+// services/pii_redaction.go
+package services
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/config"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Плейсхолдер вида [EMAIL_1]; длиннее maxPlaceholderLen не бывает
+var placeholderPattern = regexp.MustCompile(`\[[A-Z][A-Z0-9_]*_[0-9]+\]`)
+
+const maxPlaceholderLen = 48
+
+// Настройка воркспейса кэшируется ненадолго: запросов к LLM на одно действие бывает много
+const redactionPolicyTTL = time.Minute
+
+// Подсказка модели, добавляется к системному сообщению, если что-то заменено
+const redactionNotice = "Some personal data was replaced with placeholders like [EMAIL_1] or [NAME_2]. Keep placeholders exactly as written when you refer to them; do not guess their values."
+
+type redactionRule struct {
+	category string
+	pattern  *regexp.Regexp
+	// valid отсеивает ложные совпадения (номера без нужного числа цифр, карты без контрольной суммы)
+	valid func(match string) bool
+	// wholeWord — совпадение должно стоять отдельным словом; \b в regexp знает только ASCII
+	wholeWord bool
+}
+
+// Правила по порядку: email раньше телефона, карта раньше телефона
+var builtinRedactionRules = []redactionRule{
+	{category: "EMAIL", pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{category: "CARD", pattern: regexp.MustCompile(`\d(?:[ -]?\d){12,18}`), valid: luhnValid},
+	{category: "PHONE", pattern: regexp.MustCompile(`\+?\d[\d ().-]{7,}\d`), valid: phoneValid},
+}
+
+var (
+	// Дата с четырёхзначным годом: 2024-05-12, 12.05.2024
+	datePattern = regexp.MustCompile(`(?:^|\D)(?:(?:19|20)\d\d[-./](?:0?[1-9]|1[0-2])[-./](?:0?[1-9]|[12]\d|3[01])|(?:0?[1-9]|[12]\d|3[01])[-./](?:0?[1-9]|1[0-2])[-./](?:19|20)\d\d)(?:\D|$)`)
+	// Время через точку: 10.30.15
+	timePattern = regexp.MustCompile(`(?:^|\D)(?:[01]?\d|2[0-3])\.[0-5]\d\.[0-5]\d(?:\D|$)`)
+	// Разделитель между группами цифр
+	digitGroupPattern = regexp.MustCompile(`\d[ .-]+\d`)
+)
+
+// phoneValid отсеивает даты, время и длинные ID. Номер без кода страны и
+// скобок должен быть разбит хотя бы на три группы цифр.
+func phoneValid(match string) bool {
+	if digits := countDigits(match); digits < 10 || digits > 15 {
+		return false
+	}
+	if datePattern.MatchString(match) || timePattern.MatchString(match) {
+		return false
+	}
+	if strings.HasPrefix(match, "+") || strings.Contains(match, "(") {
+		return true
+	}
+	return len(digitGroupPattern.FindAllStringIndex(match, -1)) >= 2
+}
+
+// PIIRedactor заменяет персональные данные в запросах к LLM плейсхолдерами и
+// возвращает исходные значения в ответах. Замены обратимы в пределах одного запроса.
+// Включено ли удаление, решает воркспейс из контекста (WithAIWorkspace), а без
+// воркспейса или его настройки — конфиг.
+type PIIRedactor struct {
+	enabled       bool
+	rules         []redactionRule
+	workspaceRepo repository.WorkspaceRepository
+
+	mu       sync.Mutex
+	policies map[primitive.ObjectID]redactionPolicy
+}
+
+// redactionPolicy — настройка воркспейса; nil — по конфигу
+type redactionPolicy struct {
+	redact  *bool
+	expires time.Time
+}
+
+// NewPIIRedactor загружает словари из конфига; пустые словари пропускаются
+func NewPIIRedactor(cfg *config.RedactionConfig, workspaceRepo repository.WorkspaceRepository) (*PIIRedactor, error) {
+	r := &PIIRedactor{
+		enabled:       cfg.Enabled,
+		rules:         append([]redactionRule(nil), builtinRedactionRules...),
+		workspaceRepo: workspaceRepo,
+		policies:      make(map[primitive.ObjectID]redactionPolicy),
+	}
+
+	for _, path := range cfg.Dictionaries {
+		terms, err := readDictionary(path)
+		if err != nil {
+			return nil, err
+		}
+		r.addDictionary(dictionaryCategory(path), terms)
+	}
+	r.addDictionary("TERM", cfg.Terms)
+
+	return r, nil
+}
+
+// dictionaryCategory — имя файла словаря в верхнем регистре: names.txt → NAMES
+func dictionaryCategory(path string) string {
+	name := strings.ToUpper(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+	category := strings.Map(func(r rune) rune {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+	if category == "" || category[0] < 'A' || category[0] > 'Z' {
+		return "TERM"
+	}
+	return category
+}
+
+func readDictionary(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open redaction dictionary: %w", err)
+	}
+	defer file.Close()
+
+	var terms []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		terms = append(terms, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read redaction dictionary %s: %w", path, err)
+	}
+	return terms, nil
+}
+
+// addDictionary добавляет правило по словарю без учёта регистра; длинные термины
+// проверяются раньше, чтобы «Анна Петрова» не разбилась на «Анна»
+func (r *PIIRedactor) addDictionary(category string, terms []string) {
+	if len(terms) == 0 {
+		return
+	}
+	terms = append([]string(nil), terms...)
+	sort.Slice(terms, func(i, j int) bool { return len(terms[i]) > len(terms[j]) })
+
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	r.rules = append(r.rules, redactionRule{
+		category:  category,
+		pattern:   regexp.MustCompile(`(?i)(?:` + strings.Join(quoted, "|") + `)`),
+		wholeWord: true,
+	})
+}
+
+type aiWorkspaceKey struct{}
+
+// WithAIWorkspace помечает контекст воркспейсом, чья политика данных действует
+// для запросов к LLM; nil — личный контент
+func WithAIWorkspace(ctx context.Context, workspaceID *primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, aiWorkspaceKey{}, workspaceID)
+}
+
+// begin возвращает замены для одного запроса или nil, если удаление выключено.
+// Если настройку воркспейса прочитать не удалось, данные удаляются.
+func (r *PIIRedactor) begin(ctx context.Context) *redaction {
+	if r == nil {
+		return nil
+	}
+
+	if !r.enabledFor(ctx) {
+		return nil
+	}
+
+	return &redaction{
+		rules:        r.rules,
+		placeholders: make(map[string]string),
+		originals:    make(map[string]string),
+		counts:       make(map[string]int),
+	}
+}
+
+func (r *PIIRedactor) enabledFor(ctx context.Context) bool {
+	workspaceID, _ := ctx.Value(aiWorkspaceKey{}).(*primitive.ObjectID)
+	if workspaceID == nil {
+		return r.enabled
+	}
+
+	r.mu.Lock()
+	policy, ok := r.policies[*workspaceID]
+	r.mu.Unlock()
+
+	if !ok || time.Now().After(policy.expires) {
+		workspace, err := r.workspaceRepo.GetByID(ctx, *workspaceID)
+		if err != nil {
+			fmt.Printf("Failed to load redaction policy for workspace %s: %v\n", workspaceID.Hex(), err)
+			return true
+		}
+		policy = redactionPolicy{redact: workspace.RedactPII, expires: time.Now().Add(redactionPolicyTTL)}
+
+		r.mu.Lock()
+		r.policies[*workspaceID] = policy
+		r.mu.Unlock()
+	}
+
+	if policy.redact != nil {
+		return *policy.redact
+	}
+	return r.enabled
+}
+
+// ForgetWorkspace сбрасывает закэшированную настройку воркспейса после её изменения
+func (r *PIIRedactor) ForgetWorkspace(workspaceID primitive.ObjectID) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.policies, workspaceID)
+	r.mu.Unlock()
+}
+
+// Embeddings оборачивает провайдер эмбеддингов: тексты уходят к нему без персональных данных
+func (r *PIIRedactor) Embeddings(provider EmbeddingProvider) EmbeddingProvider {
+	if r == nil {
+		return provider
+	}
+	return &redactingEmbeddingProvider{EmbeddingProvider: provider, redactor: r}
+}
+
+type redactingEmbeddingProvider struct {
+	EmbeddingProvider
+	redactor *PIIRedactor
+}
+
+// Embed заменяет персональные данные плейсхолдерами; вектору исходные значения не нужны
+func (p *redactingEmbeddingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if x := p.redactor.begin(ctx); x != nil {
+		redacted := make([]string, len(texts))
+		for i, text := range texts {
+			redacted[i] = x.text(text)
+		}
+		texts = redacted
+	}
+	return p.EmbeddingProvider.Embed(ctx, texts)
+}
+
+// redaction — замены одного запроса: одно и то же значение получает один плейсхолдер
+type redaction struct {
+	rules        []redactionRule
+	placeholders map[string]string // категория и значение → плейсхолдер
+	originals    map[string]string // плейсхолдер → значение
+	counts       map[string]int
+}
+
+func (x *redaction) messages(messages []ChatMessage) []ChatMessage {
+	redacted := make([]ChatMessage, len(messages))
+	for i, msg := range messages {
+		msg.Content = x.text(msg.Content)
+		redacted[i] = msg
+	}
+
+	if len(x.originals) > 0 {
+		if len(redacted) > 0 && redacted[0].Role == "system" {
+			redacted[0].Content += "\n\n" + redactionNotice
+		} else {
+			redacted = append([]ChatMessage{{Role: "system", Content: redactionNotice}}, redacted...)
+		}
+	}
+	return redacted
+}
+
+func (x *redaction) text(s string) string {
+	for _, rule := range x.rules {
+		s = x.apply(s, rule)
+	}
+	return s
+}
+
+func (x *redaction) apply(s string, rule redactionRule) string {
+	matches := rule.pattern.FindAllStringIndex(s, -1)
+	if len(matches) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		match := s[m[0]:m[1]]
+		if rule.valid != nil && !rule.valid(match) {
+			continue
+		}
+		if rule.wholeWord && !wordBoundary(s, m[0], m[1]) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(x.placeholder(rule.category, match))
+		last = m[1]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func (x *redaction) placeholder(category, value string) string {
+	key := category + "\x00" + strings.ToLower(value)
+	if placeholder, ok := x.placeholders[key]; ok {
+		return placeholder
+	}
+	x.counts[category]++
+	placeholder := fmt.Sprintf("[%s_%d]", category, x.counts[category])
+	x.placeholders[key] = placeholder
+	x.originals[placeholder] = value
+	return placeholder
+}
+
+// restore возвращает исходные значения вместо плейсхолдеров
+func (x *redaction) restore(s string) string {
+	if len(x.originals) == 0 {
+		return s
+	}
+	return placeholderPattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		if value, ok := x.originals[placeholder]; ok {
+			return value
+		}
+		return placeholder
+	})
+}
+
+// stream оборачивает onDelta: плейсхолдер может прийти по частям, поэтому
+// незакрытый хвост с «[» придерживается до следующего фрагмента. flush
+// отправляет остаток после конца потока.
+func (x *redaction) stream(onDelta func(delta string) error) (wrapped func(delta string) error, flush func() error) {
+	var pending string
+	wrapped = func(delta string) error {
+		pending += delta
+		ready := pending
+		if open := strings.LastIndexByte(pending, '['); open >= 0 &&
+			!strings.Contains(pending[open:], "]") && len(pending)-open < maxPlaceholderLen {
+			ready, pending = pending[:open], pending[open:]
+		} else {
+			pending = ""
+		}
+		if ready == "" {
+			return nil
+		}
+		return onDelta(x.restore(ready))
+	}
+	flush = func() error {
+		if pending == "" {
+			return nil
+		}
+		rest := pending
+		pending = ""
+		return onDelta(x.restore(rest))
+	}
+	return wrapped, flush
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhnValid — контрольная сумма номера карты
+func luhnValid(number string) bool {
+	sum, double := 0, false
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// wordBoundary проверяет, что s[start:end] — отдельное слово, а не часть
+// другого («Ира» в «Бирюза»)
+func wordBoundary(s string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:start])
+	after, _ := utf8.DecodeRuneInString(s[end:])
+	return (start == 0 || !isWordRune(before)) && (end == len(s) || !isWordRune(after))
+}
+
+// isWordRune — буква, цифра или _, как в \w, но для любых алфавитов
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
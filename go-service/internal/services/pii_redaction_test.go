@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/code-zt/vidnotes/config"
+)
+
+func newTestRedaction(t *testing.T) *redaction {
+	t.Helper()
+	r, err := NewPIIRedactor(&config.RedactionConfig{Enabled: true, Terms: []string{"Анна", "Анна Петрова"}}, nil)
+	if err != nil {
+		t.Fatalf("NewPIIRedactor: %v", err)
+	}
+	x := r.begin(context.Background())
+	if x == nil {
+		t.Fatal("begin() = nil, want redaction")
+	}
+	return x
+}
+
+func TestRedactionText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "nothing to redact", text: "Встреча в 10.30, бюджет 150 000", want: "Встреча в 10.30, бюджет 150 000"},
+		{name: "email", text: "Пишите на anna@example.com", want: "Пишите на [EMAIL_1]"},
+		{name: "card", text: "Карта 4111 1111 1111 1111", want: "Карта [CARD_1]"},
+		{name: "card without checksum", text: "Заказ 4111 1111 1111 1112", want: "Заказ 4111 1111 1111 1112"},
+		{name: "phone", text: "Звоните +7 912 345-67-89", want: "Звоните [PHONE_1]"},
+		{name: "year and amount", text: "Выручка в 2023 году 150 000 000", want: "Выручка в 2023 году 150 000 000"},
+		{name: "longest term first", text: "Анна Петрова и Анна", want: "[TERM_1] и [TERM_2]"},
+		{name: "term inside word", text: "Саванна", want: "Саванна"},
+		{
+			name: "same value same placeholder",
+			text: "anna@example.com, ANNA@example.com, boss@example.com",
+			want: "[EMAIL_1], [EMAIL_1], [EMAIL_2]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := newTestRedaction(t)
+			got := x.text(tt.text)
+			if got != tt.want {
+				t.Errorf("text(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+			if restored := x.restore(got); !strings.EqualFold(restored, tt.text) {
+				t.Errorf("restore(%q) = %q, want %q", got, restored, tt.text)
+			}
+		})
+	}
+}
+
+func TestRedactionRestore(t *testing.T) {
+	x := newTestRedaction(t)
+	x.text("Пишите Анна на anna@example.com")
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "known placeholders", text: "[TERM_1] ждёт письма на [EMAIL_1]", want: "Анна ждёт письма на anna@example.com"},
+		{name: "unknown placeholder kept", text: "Свяжитесь с [EMAIL_2]", want: "Свяжитесь с [EMAIL_2]"},
+		{name: "not a placeholder", text: "Ссылка [S1] и [email_1]", want: "Ссылка [S1] и [email_1]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := x.restore(tt.text); got != tt.want {
+				t.Errorf("restore(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactionStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		deltas []string
+		// Фрагменты, отданные onDelta, включая остаток из flush
+		want []string
+	}{
+		{name: "plain text", deltas: []string{"Привет, ", "мир"}, want: []string{"Привет, ", "мир"}},
+		{name: "whole placeholder", deltas: []string{"Пишите [EMAIL_1]."}, want: []string{"Пишите anna@example.com."}},
+		{
+			name:   "placeholder split",
+			deltas: []string{"Пишите [EMA", "IL_", "1] сегодня"},
+			want:   []string{"Пишите ", "anna@example.com сегодня"},
+		},
+		{name: "unclosed bracket flushed", deltas: []string{"Список [", "TODO"}, want: []string{"Список ", "[TODO"}},
+		{
+			name:   "long bracket not held",
+			deltas: []string{"[" + strings.Repeat("x", maxPlaceholderLen)},
+			want:   []string{"[" + strings.Repeat("x", maxPlaceholderLen)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := newTestRedaction(t)
+			x.text("anna@example.com")
+
+			var got []string
+			wrapped, flush := x.stream(func(delta string) error {
+				got = append(got, delta)
+				return nil
+			})
+			for _, delta := range tt.deltas {
+				if err := wrapped(delta); err != nil {
+					t.Fatalf("wrapped(%q): %v", delta, err)
+				}
+			}
+			if err := flush(); err != nil {
+				t.Fatalf("flush: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("deltas = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPIIRedactorDisabled(t *testing.T) {
+	var nilRedactor *PIIRedactor
+	if x := nilRedactor.begin(context.Background()); x != nil {
+		t.Error("nil redactor: begin() != nil")
+	}
+
+	r, err := NewPIIRedactor(&config.RedactionConfig{}, nil)
+	if err != nil {
+		t.Fatalf("NewPIIRedactor: %v", err)
+	}
+	if x := r.begin(context.Background()); x != nil {
+		t.Error("disabled redactor: begin() != nil")
+	}
+}
+
+func TestPhoneValid(t *testing.T) {
+	tests := []struct {
+		match string
+		want  bool
+	}{
+		{match: "+7 912 345-67-89", want: true},
+		{match: "8 (912) 345-67-89", want: true},
+		{match: "415-555-2671 00", want: true},
+		{match: "+14155552671", want: true},
+		{match: "89123456789", want: false},
+		{match: "2024-05-12 10:30", want: false},
+		{match: "12.05.2024 1030", want: false},
+		{match: "10.30.15 12345", want: false},
+		{match: "150 000 000", want: false},
+		{match: "+1234567890123456", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.match, func(t *testing.T) {
+			if got := phoneValid(tt.match); got != tt.want {
+				t.Errorf("phoneValid(%q) = %v, want %v", tt.match, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLuhnValid(t *testing.T) {
+	tests := []struct {
+		number string
+		want   bool
+	}{
+		{number: "4111111111111111", want: true},
+		{number: "4111 1111 1111 1111", want: true},
+		{number: "5500-0000-0000-0004", want: true},
+		{number: "4111111111111112", want: false},
+		{number: "1234567812345678", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			if got := luhnValid(tt.number); got != tt.want {
+				t.Errorf("luhnValid(%q) = %v, want %v", tt.number, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordBoundary(t *testing.T) {
+	tests := []struct {
+		name  string
+		s     string
+		match string
+		want  bool
+	}{
+		{name: "whole string", s: "Ира", match: "Ира", want: true},
+		{name: "between spaces", s: "и Ира тоже", match: "Ира", want: true},
+		{name: "punctuation", s: "(Ира)", match: "Ира", want: true},
+		{name: "inside cyrillic word", s: "Бирюза", match: "ир", want: false},
+		{name: "prefix", s: "Ирада", match: "Ира", want: false},
+		{name: "underscore", s: "_Ира", match: "Ира", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := strings.Index(tt.s, tt.match)
+			if got := wordBoundary(tt.s, start, start+len(tt.match)); got != tt.want {
+				t.Errorf("wordBoundary(%q, %q) = %v, want %v", tt.s, tt.match, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDictionaryCategory(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/etc/vidnotes/names.txt", want: "NAMES"},
+		{path: "client-list.csv", want: "CLIENT_LIST"},
+		{path: "2024.txt", want: "TERM"},
+		{path: "имена.txt", want: "TERM"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := dictionaryCategory(tt.path); got != tt.want {
+				t.Errorf("dictionaryCategory(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
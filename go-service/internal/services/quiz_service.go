@@ -0,0 +1,176 @@
+// services/quiz_service.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuizService генерирует по видео тесты с вариантами ответа и карточки
+// для повторения. Тесты хранятся при видео и выгружаются в Anki.
+type QuizService interface {
+	Generate(ctx context.Context, userID, videoID primitive.ObjectID, req models.GenerateQuizRequest) (*models.Quiz, error)
+	List(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.Quiz, error)
+	Get(ctx context.Context, userID, videoID, quizID primitive.ObjectID) (*models.Quiz, error)
+	// ExportAnki возвращает вопросы и карточки теста в CSV для импорта в Anki
+	ExportAnki(ctx context.Context, userID, videoID, quizID primitive.ObjectID) ([]byte, error)
+	DeleteQuizzes(ctx context.Context, videoID primitive.ObjectID) error
+}
+
+type quizService struct {
+	quizRepo       repository.QuizRepository
+	summaryService SummaryService
+	aiService      AIService
+}
+
+func NewQuizService(quizRepo repository.QuizRepository, summaryService SummaryService, aiService AIService) QuizService {
+	return &quizService{
+		quizRepo:       quizRepo,
+		summaryService: summaryService,
+		aiService:      aiService,
+	}
+}
+
+// Generate создаёт тест; это новый материал при видео, поэтому нужна роль редактора
+func (s *quizService) Generate(ctx context.Context, userID, videoID primitive.ObjectID, req models.GenerateQuizRequest) (*models.Quiz, error) {
+	questions, err := quizSize(req.Questions, models.DefaultQuizQuestions, models.MaxQuizQuestions)
+	if err != nil {
+		return nil, err
+	}
+	flashcards, err := quizSize(req.Flashcards, models.DefaultQuizFlashcards, models.MaxQuizFlashcards)
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	source, err := NewVideoSource(video)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithAIWorkspace(WithAIUsageScope(prompts.WithLocale(ctx, video.Language), userID, nil), video.WorkspaceID)
+	quiz, err := s.aiService.GenerateQuiz(ctx, source, questions, flashcards)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ссылки на несуществующие сегменты отбрасываются, остальные получают таймкод
+	starts := newSegmentStarts(video)
+	for i := range quiz.Questions {
+		quiz.Questions[i].SegmentID, quiz.Questions[i].Start = starts.resolve(quiz.Questions[i].SegmentID)
+	}
+	for i := range quiz.Flashcards {
+		quiz.Flashcards[i].SegmentID, quiz.Flashcards[i].Start = starts.resolve(quiz.Flashcards[i].SegmentID)
+	}
+
+	quiz.VideoID = videoID
+	quiz.AuthorID = userID
+	if err := s.quizRepo.Create(ctx, quiz); err != nil {
+		return nil, err
+	}
+	return quiz, nil
+}
+
+func quizSize(requested, def, limit int) (int, error) {
+	if requested == 0 {
+		return def, nil
+	}
+	if requested < 0 || requested > limit {
+		return 0, models.ErrInvalidQuizRequest
+	}
+	return requested, nil
+}
+
+func (s *quizService) List(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.Quiz, error) {
+	if _, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.quizRepo.GetByVideo(ctx, videoID)
+}
+
+func (s *quizService) Get(ctx context.Context, userID, videoID, quizID primitive.ObjectID) (*models.Quiz, error) {
+	if _, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+	return s.quizRepo.GetByID(ctx, videoID, quizID)
+}
+
+func (s *quizService) ExportAnki(ctx context.Context, userID, videoID, quizID primitive.ObjectID) ([]byte, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	quiz, err := s.quizRepo.GetByID(ctx, videoID, quizID)
+	if err != nil {
+		return nil, err
+	}
+	return ankiCSV(quiz, video.Tags)
+}
+
+func (s *quizService) DeleteQuizzes(ctx context.Context, videoID primitive.ObjectID) error {
+	return s.quizRepo.DeleteByVideo(ctx, videoID)
+}
+
+// ankiCSV печатает заметки Front, Back, Tags. Заголовки с # Anki читает как
+// настройки импорта, поэтому файл импортируется без ручного сопоставления полей.
+// Поля в HTML: варианты ответа идут отдельными строками.
+func ankiCSV(quiz *models.Quiz, videoTags []string) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteString("#separator:Comma\n#html:true\n#columns:Front,Back,Tags\n#tags column:3\n")
+
+	// Теги Anki разделяются пробелами
+	tags := []string{"vidnotes"}
+	for _, tag := range videoTags {
+		tags = append(tags, strings.ReplaceAll(tag, " ", "_"))
+	}
+	questionTags := strings.Join(append(tags, "quiz"), " ")
+	flashcardTags := strings.Join(append(tags, "flashcard"), " ")
+
+	w := csv.NewWriter(&buf)
+	for _, q := range quiz.Questions {
+		options := make([]string, len(q.Options))
+		for i, option := range q.Options {
+			options[i] = fmt.Sprintf("%c. %s", 'A'+i, html.EscapeString(option))
+		}
+		front := html.EscapeString(q.Question) + "<br><br>" + strings.Join(options, "<br>")
+
+		back := "<b>" + options[q.Answer] + "</b>"
+		if q.Explanation != "" {
+			back += "<br><br>" + html.EscapeString(q.Explanation)
+		}
+		if err := w.Write([]string{front, back + ankiTimestamp(q.Start), questionTags}); err != nil {
+			return nil, err
+		}
+	}
+	for _, card := range quiz.Flashcards {
+		back := html.EscapeString(card.Back) + ankiTimestamp(card.Start)
+		if err := w.Write([]string{html.EscapeString(card.Front), back, flashcardTags}); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func ankiTimestamp(start *float64) string {
+	if start == nil {
+		return ""
+	}
+	return "<br><br><small>" + formatTimestamp(*start) + "</small>"
+}
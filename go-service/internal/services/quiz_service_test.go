@@ -0,0 +1,223 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeVideoAccess отдаёт видео, если запрошенная роль не выше выданной
+type fakeVideoAccess struct {
+	SummaryService
+	video *models.Video
+	role  string
+	asked string
+}
+
+func (f *fakeVideoAccess) AuthorizeVideo(ctx context.Context, userID, videoID primitive.ObjectID, minRole string) (*models.Video, error) {
+	f.asked = minRole
+	if f.video == nil {
+		return nil, models.ErrVideoNotFound
+	}
+	if workspaceRoleRank[f.role] < workspaceRoleRank[minRole] {
+		return nil, models.ErrWorkspaceAccessDenied
+	}
+	return f.video, nil
+}
+
+type fakeQuizAI struct {
+	AIService
+	quiz   *models.Quiz
+	source VideoSource
+}
+
+func (f *fakeQuizAI) GenerateQuiz(ctx context.Context, source VideoSource, questions, flashcards int) (*models.Quiz, error) {
+	f.source = source
+	return f.quiz, nil
+}
+
+type fakeQuizRepo struct {
+	repository.QuizRepository
+	created []*models.Quiz
+}
+
+func (f *fakeQuizRepo) Create(ctx context.Context, quiz *models.Quiz) error {
+	f.created = append(f.created, quiz)
+	return nil
+}
+
+func TestQuizServiceGenerate(t *testing.T) {
+	segID, missingID := 2, 9
+	video := &models.Video{
+		Title:      "Планёрка",
+		Transcript: "текст",
+		Segments:   []models.TranscriptSegment{{ID: 2, Start: 12.5, End: 15, Text: "текст"}},
+	}
+
+	tests := []struct {
+		name    string
+		video   *models.Video
+		role    string
+		req     models.GenerateQuizRequest
+		wantErr error
+	}{
+		{name: "too many questions", video: video, role: models.WorkspaceRoleOwner, req: models.GenerateQuizRequest{Questions: models.MaxQuizQuestions + 1}, wantErr: models.ErrInvalidQuizRequest},
+		{name: "negative flashcards", video: video, role: models.WorkspaceRoleOwner, req: models.GenerateQuizRequest{Flashcards: -1}, wantErr: models.ErrInvalidQuizRequest},
+		{name: "video not found", video: nil, role: models.WorkspaceRoleOwner, wantErr: models.ErrVideoNotFound},
+		{name: "viewer cannot generate", video: video, role: models.WorkspaceRoleViewer, wantErr: models.ErrWorkspaceAccessDenied},
+		{name: "no content", video: &models.Video{Title: "пусто"}, role: models.WorkspaceRoleEditor, wantErr: models.ErrNoVideoContent},
+		{name: "editor generates", video: video, role: models.WorkspaceRoleEditor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			access := &fakeVideoAccess{video: tt.video, role: tt.role}
+			ai := &fakeQuizAI{quiz: &models.Quiz{
+				Questions:  []models.QuizQuestion{{Question: "?", Options: []string{"a", "b", "c"}, SegmentID: &segID}},
+				Flashcards: []models.Flashcard{{Front: "f", Back: "b", SegmentID: &missingID}},
+			}}
+			repo := &fakeQuizRepo{}
+			s := NewQuizService(repo, access, ai)
+
+			userID, videoID := primitive.NewObjectID(), primitive.NewObjectID()
+			quiz, err := s.Generate(context.Background(), userID, videoID, tt.req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Generate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(repo.created) != 0 {
+					t.Error("quiz saved despite the error")
+				}
+				return
+			}
+
+			if access.asked != models.WorkspaceRoleEditor {
+				t.Errorf("authorized with role %q, want %q", access.asked, models.WorkspaceRoleEditor)
+			}
+			if len(repo.created) != 1 || quiz.VideoID != videoID || quiz.AuthorID != userID {
+				t.Fatalf("quiz not saved for the video and author: %+v", quiz)
+			}
+			if !strings.Contains(ai.source.Transcript, "[S2 ") {
+				t.Errorf("transcript without segment numbers: %q", ai.source.Transcript)
+			}
+			if q := quiz.Questions[0]; q.Start == nil || *q.Start != 12.5 {
+				t.Errorf("question start = %v, want 12.5", q.Start)
+			}
+			if card := quiz.Flashcards[0]; card.SegmentID != nil || card.Start != nil {
+				t.Errorf("unknown segment kept: %v", card.SegmentID)
+			}
+		})
+	}
+}
+
+func TestQuizSchema(t *testing.T) {
+	schema := quizSchema(2, 1)
+	card := `"flashcards":[{"front":"Что?","back":"Это"}]`
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{name: "valid", content: `{"questions":[{"question":"?","options":["a","b","c"],"answer":2}],` + card + `}`},
+		{name: "fenced", content: "```json\n{\"questions\":[{\"question\":\"?\",\"options\":[\"a\",\"b\",\"c\"],\"answer\":0}]," + card + "}\n```"},
+		{name: "answer out of range", content: `{"questions":[{"question":"?","options":["a","b","c"],"answer":3}],` + card + `}`, wantErr: "$.questions[0].answer"},
+		{name: "too few options", content: `{"questions":[{"question":"?","options":["a","b"],"answer":0}],` + card + `}`, wantErr: "$.questions[0].options"},
+		{name: "more than requested", content: `{"questions":[{"question":"?","options":["a","b","c"],"answer":0},{"question":"?","options":["a","b","c"],"answer":0},{"question":"?","options":["a","b","c"],"answer":0}],` + card + `}`, wantErr: "$.questions: expected at most 2 items"},
+		{name: "no flashcards", content: `{"questions":[{"question":"?","options":["a","b","c"],"answer":0}]}`, wantErr: `missing required property "flashcards"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quiz models.Quiz
+			err := schema.Decode([]byte(extractJSON(tt.content, '{', '}')), &quiz)
+			if err == nil {
+				err = checkQuiz(&quiz)
+			}
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("quiz rejected: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAnkiCSV(t *testing.T) {
+	start := 75.0
+	quiz := &models.Quiz{
+		Questions: []models.QuizQuestion{{
+			Question:    "Когда <релиз>?",
+			Options:     []string{"В марте", "В мае", "Никогда"},
+			Answer:      1,
+			Explanation: "Перенесли",
+			Start:       &start,
+		}},
+		Flashcards: []models.Flashcard{{Front: "Бюджет", Back: "Утверждён, с оговорками"}},
+	}
+
+	data, err := ankiCSV(quiz, []string{"планёрка", "отдел продаж"})
+	if err != nil {
+		t.Fatalf("ankiCSV() error = %v", err)
+	}
+
+	text := string(data)
+	header := "#separator:Comma\n#html:true\n#columns:Front,Back,Tags\n#tags column:3\n"
+	if !strings.HasPrefix(text, header) {
+		t.Fatalf("missing Anki header: %q", text)
+	}
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(text, header))).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+
+	want := [][]string{
+		{
+			"Когда &lt;релиз&gt;?<br><br>A. В марте<br>B. В мае<br>C. Никогда",
+			"<b>B. В мае</b><br><br>Перенесли<br><br><small>01:15</small>",
+			"vidnotes планёрка отдел_продаж quiz",
+		},
+		{"Бюджет", "Утверждён, с оговорками", "vidnotes планёрка отдел_продаж flashcard"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d", len(records), len(want))
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("record %d field %d = %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+func TestQuizSize(t *testing.T) {
+	tests := []struct {
+		requested int
+		want      int
+		wantErr   error
+	}{
+		{requested: 0, want: 8},
+		{requested: 5, want: 5},
+		{requested: 20, want: 20},
+		{requested: 21, wantErr: models.ErrInvalidQuizRequest},
+		{requested: -1, wantErr: models.ErrInvalidQuizRequest},
+	}
+
+	for _, tt := range tests {
+		got, err := quizSize(tt.requested, 8, 20)
+		if got != tt.want || !errors.Is(err, tt.wantErr) {
+			t.Errorf("quizSize(%d) = (%d, %v), want (%d, %v)", tt.requested, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// services/search_service.go
+package services
+
+import (
+	"context"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	snippetRadius      = 80 // рун контекста вокруг совпадения
+	maxSnippetsPerHit  = 3
+	maxSegmentsPerHit  = 5
+	minStemPrefixRunes = 4
+)
+
+type SearchService interface {
+	Search(ctx context.Context, userID primitive.ObjectID, query, language string, filter models.VideoFilter, limit int) (*models.SearchResult, error)
+}
+
+type searchService struct {
+	videoRepo repository.VideoRepository
+}
+
+func NewSearchService(videoRepo repository.VideoRepository) SearchService {
+	return &searchService{
+		videoRepo: videoRepo,
+	}
+}
+
+func (s *searchService) Search(ctx context.Context, userID primitive.ObjectID, query, language string, filter models.VideoFilter, limit int) (*models.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, models.ErrEmptySearchQuery
+	}
+
+	if limit <= 0 {
+		limit = models.DefaultSearchLimit
+	}
+	if limit > models.MaxSearchLimit {
+		limit = models.MaxSearchLimit
+	}
+
+	if language == "" {
+		language = detectQueryLanguage(query)
+	}
+
+	videos, err := s.videoRepo.Search(ctx, userID, query, language, filter, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := highlightTerms(query)
+	hits := make([]models.SearchHit, 0, len(videos))
+	for _, v := range videos {
+		hits = append(hits, buildSearchHit(v, terms))
+	}
+
+	return &models.SearchResult{
+		Query: query,
+		Items: hits,
+		Total: len(hits),
+	}, nil
+}
+
+type snippetSource struct {
+	name string
+	text string
+}
+
+func buildSearchHit(v *models.ScoredVideo, terms []string) models.SearchHit {
+	hit := models.SearchHit{
+		VideoID:   v.ID,
+		Title:     v.Title,
+		Status:    v.Status,
+		Language:  v.Language,
+		Score:     v.Score,
+		Snippets:  []models.SearchSnippet{},
+		CreatedAt: v.CreatedAt,
+	}
+
+	sources := []snippetSource{
+		{"title", v.Title},
+		{"summary", v.Summary},
+	}
+	// Если есть сегменты, совпадения в транскрипте отдаём через них — с таймкодами
+	if len(v.Segments) == 0 {
+		sources = append(sources, snippetSource{"transcript", v.Transcript})
+	}
+
+	for _, f := range sources {
+		if len(hit.Snippets) >= maxSnippetsPerHit {
+			break
+		}
+		if snippet, ok := buildSnippet(f.text, terms); ok {
+			snippet.Field = f.name
+			hit.Snippets = append(hit.Snippets, snippet)
+		}
+	}
+
+	for _, seg := range v.Segments {
+		if len(hit.Segments) >= maxSegmentsPerHit {
+			break
+		}
+		ranges := findMatches(seg.Text, terms)
+		if len(ranges) == 0 {
+			continue
+		}
+		hit.Segments = append(hit.Segments, models.SegmentMatch{
+			SegmentID:  seg.ID,
+			Start:      seg.Start,
+			End:        seg.End,
+			Text:       seg.Text,
+			Highlights: ranges,
+		})
+	}
+
+	return hit
+}
+
+// detectQueryLanguage выбирает язык стемминга запроса по алфавиту
+func detectQueryLanguage(query string) string {
+	for _, r := range query {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return "ru"
+		}
+	}
+	return "en"
+}
+
+// highlightTerms извлекает из запроса слова для подсветки, пропуская исключения (-word).
+// Слова сокращаются до префикса, чтобы подсветка примерно соответствовала стеммингу MongoDB.
+func highlightTerms(query string) []string {
+	var terms []string
+	for _, field := range strings.Fields(query) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+		for _, word := range splitWords(field) {
+			terms = append(terms, stemPrefix(strings.ToLower(word)))
+		}
+	}
+	return terms
+}
+
+func splitWords(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func stemPrefix(word string) string {
+	runes := []rune(word)
+	n := len(runes) - 2
+	if n < minStemPrefixRunes {
+		n = min(len(runes), minStemPrefixRunes)
+	}
+	return string(runes[:n])
+}
+
+// findMatches возвращает позиции (в рунах) слов текста, начинающихся с одного из термов
+func findMatches(text string, terms []string) []models.TextRange {
+	if len(terms) == 0 || text == "" {
+		return nil
+	}
+
+	var ranges []models.TextRange
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(runes) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+			j++
+		}
+
+		word := strings.ToLower(string(runes[i:j]))
+		for _, term := range terms {
+			if strings.HasPrefix(word, term) {
+				ranges = append(ranges, models.TextRange{Start: i, End: j})
+				break
+			}
+		}
+		i = j
+	}
+
+	return ranges
+}
+
+// buildSnippet вырезает фрагмент вокруг первого совпадения и пересчитывает подсветку
+func buildSnippet(text string, terms []string) (models.SearchSnippet, bool) {
+	ranges := findMatches(text, terms)
+	if len(ranges) == 0 {
+		return models.SearchSnippet{}, false
+	}
+
+	runes := []rune(text)
+	start := max(ranges[0].Start-snippetRadius, 0)
+	end := min(ranges[0].End+snippetRadius, len(runes))
+
+	// Не режем слова на границах сниппета
+	for limit := start - 20; start > 0 && start > limit && !unicode.IsSpace(runes[start-1]); {
+		start--
+	}
+	for limit := end + 20; end < len(runes) && end < limit && !unicode.IsSpace(runes[end]); {
+		end++
+	}
+
+	prefix, suffix := "", ""
+	if start > 0 {
+		prefix = "…"
+	}
+	if end < len(runes) {
+		suffix = "…"
+	}
+	offset := utf8.RuneCountInString(prefix) - start
+
+	highlights := make([]models.TextRange, 0, len(ranges))
+	for _, r := range ranges {
+		if r.Start < start || r.End > end {
+			continue
+		}
+		highlights = append(highlights, models.TextRange{Start: r.Start + offset, End: r.End + offset})
+	}
+
+	return models.SearchSnippet{
+		Text:       prefix + string(runes[start:end]) + suffix,
+		Highlights: highlights,
+	}, true
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func TestStemPrefix(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "", want: ""},
+		{word: "ai", want: "ai"},
+		{word: "план", want: "план"},
+		{word: "релиз", want: "рели"},
+		{word: "бюджет", want: "бюдж"},
+		{word: "бюджетом", want: "бюджет"},
+		{word: "release", want: "relea"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := stemPrefix(tt.word); got != tt.want {
+				t.Errorf("stemPrefix(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{name: "empty", query: "  ", want: nil},
+		{name: "lowercased", query: "Бюджет РЕЛИЗ", want: []string{"бюдж", "рели"}},
+		{name: "excluded word", query: "бюджет -релиз", want: []string{"бюдж"}},
+		{name: "punctuation splits words", query: "Q3/отчёт, итоги!", want: []string{"q3", "отчё", "итог"}},
+		{name: "digits stay in the word", query: "план2026", want: []string{"план20"}},
+		{name: "quoted phrase", query: `"release notes"`, want: []string{"relea", "note"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := highlightTerms(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("highlightTerms(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindMatches(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		terms []string
+		want  []models.TextRange
+	}{
+		{name: "no terms", text: "Бюджет утвердили", want: nil},
+		{name: "empty text", text: "", terms: []string{"бюдж"}, want: nil},
+		{name: "rune offsets", text: "Бюджет утвердили, бюджетный план", terms: []string{"бюдж"},
+			want: []models.TextRange{{Start: 0, End: 6}, {Start: 18, End: 27}}},
+		{name: "prefix only at word start", text: "субюджет", terms: []string{"бюдж"}, want: nil},
+		{name: "several terms", text: "План и релиз", terms: []string{"рели", "план"},
+			want: []models.TextRange{{Start: 0, End: 4}, {Start: 7, End: 12}}},
+		{name: "digits", text: "Итоги Q3.", terms: []string{"q3"}, want: []models.TextRange{{Start: 6, End: 8}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := findMatches(tt.text, tt.terms); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findMatches(%q, %q) = %v, want %v", tt.text, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildSnippet(t *testing.T) {
+	filler := strings.Repeat("слово ", 30)
+	long := filler + "бюджет" + strings.Repeat(" слово", 30) + " бюджетный"
+
+	tests := []struct {
+		name       string
+		text       string
+		terms      []string
+		wantOK     bool
+		wantPrefix string
+		wantSuffix string
+		// Подсвеченные слова сниппета по порядку
+		wantWords []string
+	}{
+		{name: "no match", text: "Бюджет утвердили", terms: []string{"рели"}},
+		{name: "short text", text: "Итоги: бюджет утвердили", terms: []string{"бюдж"},
+			wantOK: true, wantPrefix: "Итоги", wantSuffix: "утвердили", wantWords: []string{"бюджет"}},
+		{name: "cut on word boundaries", text: long, terms: []string{"бюдж"},
+			wantOK: true, wantPrefix: "…слово ", wantSuffix: " слово…", wantWords: []string{"бюджет"}},
+		{name: "match at the end", text: filler + "бюджет", terms: []string{"бюдж"},
+			wantOK: true, wantPrefix: "…слово ", wantSuffix: "бюджет", wantWords: []string{"бюджет"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := buildSnippet(tt.text, tt.terms)
+			if ok != tt.wantOK {
+				t.Fatalf("buildSnippet() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if !strings.HasPrefix(got.Text, tt.wantPrefix) || !strings.HasSuffix(got.Text, tt.wantSuffix) {
+				t.Errorf("snippet = %q, want %q…%q", got.Text, tt.wantPrefix, tt.wantSuffix)
+			}
+
+			runes := []rune(got.Text)
+			var words []string
+			for _, r := range got.Highlights {
+				if r.Start < 0 || r.End > len(runes) {
+					t.Fatalf("highlight %v out of snippet of %d runes", r, len(runes))
+				}
+				words = append(words, string(runes[r.Start:r.End]))
+			}
+			if !reflect.DeepEqual(words, tt.wantWords) {
+				t.Errorf("highlighted %q, want %q", words, tt.wantWords)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+// services/session_export.go
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Форматы экспорта сессии
+const (
+	ExportFormatMarkdown = "md"
+	ExportFormatJSON     = "json"
+	ExportFormatHTML     = "html"
+)
+
+// SessionExport — стенограмма активной ветки диалога для сохранения вне приложения
+type SessionExport struct {
+	ID         string    `json:"id"`
+	Title      string    `json:"title"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Язык переведённых саммари видео; пустой — язык оригинала
+	Language string          `json:"language,omitempty"`
+	Videos   []ExportVideo   `json:"videos"`
+	Summary  string          `json:"summary,omitempty"`
+	Messages []ExportMessage `json:"messages"`
+}
+
+// ExportVideo — видео сессии; Label (V1, V2…) задан только в сессиях по нескольким видео
+type ExportVideo struct {
+	ID      string `json:"id"`
+	Label   string `json:"label,omitempty"`
+	Title   string `json:"title"`
+	URL     string `json:"url,omitempty"`
+	Summary string `json:"summary,omitempty"`
+}
+
+type ExportMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Time      time.Time        `json:"time"`
+	Model     string           `json:"model,omitempty"`
+	Citations []ExportCitation `json:"citations,omitempty"`
+}
+
+// ExportCitation — ссылка ответа в том виде, в каком она стоит в тексте, с таймкодами
+type ExportCitation struct {
+	Ref        string  `json:"ref"`
+	VideoTitle string  `json:"video_title,omitempty"`
+	Start      float64 `json:"start"`
+	End        float64 `json:"end"`
+	Timestamp  string  `json:"timestamp"`
+	Quote      string  `json:"quote"`
+}
+
+// BuildSessionExport собирает стенограмму активной ветки. videos — видео сессии
+// по порядку; удалённые видео передаются как nil и в список видео не попадают.
+// Метки V1, V2… берутся из порядка видео в сессии, поэтому ссылки ответов на
+// удалённое видео сохраняют свой номер.
+func BuildSessionExport(session *models.AISession, videos []*models.Video) *SessionExport {
+	export := &SessionExport{
+		ID:         session.ID.Hex(),
+		Title:      session.Title,
+		CreatedAt:  session.CreatedAt,
+		ExportedAt: time.Now().UTC(),
+		Summary:    session.Summary,
+		Videos:     []ExportVideo{},
+		Messages:   []ExportMessage{},
+	}
+
+	videoIDs := session.Videos()
+	multi := len(videoIDs) > 1
+	labels := make(map[primitive.ObjectID]string, len(videoIDs))
+	if multi {
+		for i, videoID := range videoIDs {
+			labels[videoID] = fmt.Sprintf("V%d", i+1)
+		}
+	}
+
+	byID := make(map[primitive.ObjectID]ExportVideo, len(videos))
+	for _, video := range videos {
+		if video == nil {
+			continue
+		}
+		item := ExportVideo{ID: video.ID.Hex(), Label: labels[video.ID], Title: video.Title, URL: video.URL}
+		// В сессии по нескольким видео общего саммари нет — у каждого видео своё
+		if multi {
+			item.Summary = video.Summary
+		}
+		byID[video.ID] = item
+		export.Videos = append(export.Videos, item)
+	}
+	if export.Title == "" && len(export.Videos) > 0 {
+		export.Title = export.Videos[0].Title
+	}
+
+	for _, msg := range session.Branch() {
+		item := ExportMessage{Role: msg.Role, Content: msg.Content, Time: msg.Time, Model: msg.Model}
+		for _, citation := range msg.Citations {
+			ref := fmt.Sprintf("S%d", citation.SegmentID)
+			if label := labels[citation.VideoID]; label != "" {
+				ref = label + " " + ref
+			}
+			item.Citations = append(item.Citations, ExportCitation{
+				Ref:        ref,
+				VideoTitle: byID[citation.VideoID].Title,
+				Start:      citation.Start,
+				End:        citation.End,
+				Timestamp:  formatTimestamp(citation.Start) + "–" + formatTimestamp(citation.End),
+				Quote:      citation.Quote,
+			})
+		}
+		export.Messages = append(export.Messages, item)
+	}
+
+	return export
+}
+
+// UseTranslations подставляет переведённые саммари видео (по ID видео). Переведённое
+// саммари выводится и в сессии по одному видео; видео без перевода остаются как есть.
+func (e *SessionExport) UseTranslations(language string, summaries map[string]string) {
+	e.Language = language
+	for i, video := range e.Videos {
+		if summary, ok := summaries[video.ID]; ok && summary != "" {
+			e.Videos[i].Summary = summary
+		}
+	}
+}
+
+// Render печатает стенограмму в формате format и возвращает её с Content-Type
+func (e *SessionExport) Render(format string) ([]byte, string, error) {
+	switch format {
+	case ExportFormatMarkdown:
+		return []byte(e.markdown()), "text/markdown; charset=utf-8", nil
+	case ExportFormatJSON:
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return nil, "", err
+		}
+		return data, "application/json; charset=utf-8", nil
+	case ExportFormatHTML:
+		var buf bytes.Buffer
+		if err := exportHTML.Execute(&buf, e); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "text/html; charset=utf-8", nil
+	}
+	return nil, "", models.ErrInvalidExportFormat
+}
+
+func (e *SessionExport) markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", e.Title)
+	for _, video := range e.Videos {
+		b.WriteString("- ")
+		if video.Label != "" {
+			fmt.Fprintf(&b, "**%s** ", video.Label)
+		}
+		if video.URL != "" {
+			fmt.Fprintf(&b, "[%s](%s)\n", video.Title, video.URL)
+		} else {
+			fmt.Fprintf(&b, "%s\n", video.Title)
+		}
+	}
+	fmt.Fprintf(&b, "\n_%s_\n", e.CreatedAt.UTC().Format("2006-01-02 15:04 MST"))
+
+	if e.Summary != "" {
+		fmt.Fprintf(&b, "\n## Summary\n\n%s\n", strings.TrimSpace(e.Summary))
+	}
+	for _, video := range e.Videos {
+		if video.Summary != "" {
+			fmt.Fprintf(&b, "\n## Summary: %s\n\n%s\n", strings.TrimSpace(video.Label+" "+video.Title), strings.TrimSpace(video.Summary))
+		}
+	}
+
+	b.WriteString("\n## Conversation\n")
+	for _, msg := range e.Messages {
+		fmt.Fprintf(&b, "\n### %s · %s\n\n%s\n", exportRole(msg.Role), msg.Time.UTC().Format("2006-01-02 15:04"), strings.TrimSpace(msg.Content))
+		if len(msg.Citations) > 0 {
+			b.WriteString("\nSources:\n")
+			for _, citation := range msg.Citations {
+				fmt.Fprintf(&b, "- [%s] %s", citation.Ref, citation.Timestamp)
+				if citation.VideoTitle != "" {
+					fmt.Fprintf(&b, " (%s)", citation.VideoTitle)
+				}
+				fmt.Fprintf(&b, ": %q\n", citation.Quote)
+			}
+		}
+	}
+
+	return b.String()
+}
+
+func exportRole(role string) string {
+	if role == "assistant" {
+		return "Assistant"
+	}
+	return "You"
+}
+
+var exportHTML = template.Must(template.New("session").Funcs(template.FuncMap{
+	"role": exportRole,
+	"time": func(t time.Time) string { return t.UTC().Format("2006-01-02 15:04") },
+}).Parse(`<!DOCTYPE html>
+<html{{with .Language}} lang="{{.}}"{{end}}>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; line-height: 1.5; }
+.message { margin: 1.5rem 0; }
+.message h3 { font-size: 1rem; margin-bottom: .25rem; }
+.content, .summary { white-space: pre-wrap; }
+.sources { font-size: .9rem; color: #555; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<ul>
+{{- range .Videos}}
+<li>{{with .Label}}<strong>{{.}}</strong> {{end}}{{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}</li>
+{{- end}}
+</ul>
+<p><em>{{time .CreatedAt}}</em></p>
+{{- with .Summary}}
+<h2>Summary</h2>
+<div class="summary">{{.}}</div>
+{{- end}}
+{{- range .Videos}}{{if .Summary}}
+<h2>Summary: {{with .Label}}{{.}} {{end}}{{.Title}}</h2>
+<div class="summary">{{.Summary}}</div>
+{{- end}}{{end}}
+<h2>Conversation</h2>
+{{- range .Messages}}
+<div class="message {{.Role}}">
+<h3>{{role .Role}} · {{time .Time}}</h3>
+<div class="content">{{.Content}}</div>
+{{- if .Citations}}
+<ul class="sources">
+{{- range .Citations}}
+<li>[{{.Ref}}] {{.Timestamp}}{{with .VideoTitle}} ({{.}}){{end}}: “{{.Quote}}”</li>
+{{- end}}
+</ul>
+{{- end}}
+</div>
+{{- end}}
+</body>
+</html>
+`))
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func exportSession(videoIDs ...primitive.ObjectID) *models.AISession {
+	session := &models.AISession{
+		ID:        primitive.NewObjectID(),
+		VideoID:   videoIDs[0],
+		CreatedAt: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC),
+		Messages: []models.AIMessage{
+			{Role: "user", Content: "Что решили?"},
+			{Role: "assistant", Content: "Бюджет утвердили [S2]", Model: "test"},
+		},
+	}
+	if len(videoIDs) > 1 {
+		session.VideoIDs = videoIDs
+	}
+	for _, videoID := range videoIDs {
+		session.Messages[1].Citations = append(session.Messages[1].Citations,
+			models.AICitation{VideoID: videoID, SegmentID: 2, Start: 65, End: 70, Quote: "Бюджет утвердили"})
+	}
+	return session
+}
+
+func TestBuildSessionExportCitations(t *testing.T) {
+	first := &models.Video{ID: primitive.NewObjectID(), Title: "Планёрка", Summary: "Итоги"}
+	second := &models.Video{ID: primitive.NewObjectID(), Title: "Ретро", Summary: "Выводы"}
+	third := &models.Video{ID: primitive.NewObjectID(), Title: "Демо"}
+
+	tests := []struct {
+		name       string
+		session    *models.AISession
+		videos     []*models.Video
+		wantVideos []string
+		wantRefs   []string
+		wantTitles []string
+	}{
+		{
+			name:       "single video",
+			session:    exportSession(first.ID),
+			videos:     []*models.Video{first},
+			wantVideos: []string{""},
+			wantRefs:   []string{"S2"},
+			wantTitles: []string{"Планёрка"},
+		},
+		{
+			name:       "multi video",
+			session:    exportSession(first.ID, second.ID, third.ID),
+			videos:     []*models.Video{first, second, third},
+			wantVideos: []string{"V1", "V2", "V3"},
+			wantRefs:   []string{"V1 S2", "V2 S2", "V3 S2"},
+			wantTitles: []string{"Планёрка", "Ретро", "Демо"},
+		},
+		{
+			name:       "deleted video keeps its label",
+			session:    exportSession(first.ID, second.ID, third.ID),
+			videos:     []*models.Video{first, nil, third},
+			wantVideos: []string{"V1", "V3"},
+			wantRefs:   []string{"V1 S2", "V2 S2", "V3 S2"},
+			wantTitles: []string{"Планёрка", "", "Демо"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := BuildSessionExport(tt.session, tt.videos)
+
+			var labels []string
+			for _, video := range export.Videos {
+				labels = append(labels, video.Label)
+			}
+			if strings.Join(labels, ",") != strings.Join(tt.wantVideos, ",") {
+				t.Errorf("video labels = %q, want %q", labels, tt.wantVideos)
+			}
+
+			if export.Title != "Планёрка" {
+				t.Errorf("Title = %q, want the first video's title", export.Title)
+			}
+			if len(export.Messages) != 2 {
+				t.Fatalf("got %d messages, want 2", len(export.Messages))
+			}
+			citations := export.Messages[1].Citations
+			if len(citations) != len(tt.wantRefs) {
+				t.Fatalf("got %d citations, want %d", len(citations), len(tt.wantRefs))
+			}
+			for i, citation := range citations {
+				if citation.Ref != tt.wantRefs[i] || citation.VideoTitle != tt.wantTitles[i] {
+					t.Errorf("citation %d = %q (%q), want %q (%q)", i, citation.Ref, citation.VideoTitle, tt.wantRefs[i], tt.wantTitles[i])
+				}
+				if citation.Timestamp != "01:05–01:10" {
+					t.Errorf("citation %d timestamp = %q, want 01:05–01:10", i, citation.Timestamp)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionExportRender(t *testing.T) {
+	video := &models.Video{ID: primitive.NewObjectID(), Title: `<script>alert("x")</script>`, URL: "https://example.com/v?a=1&b=2"}
+	other := &models.Video{ID: primitive.NewObjectID(), Title: "Ретро"}
+	session := exportSession(video.ID, other.ID)
+	session.Messages[0].Content = "<b>жирный</b> & курсив"
+	export := BuildSessionExport(session, []*models.Video{video, other})
+
+	tests := []struct {
+		format      string
+		contentType string
+		want        []string
+		notWant     []string
+	}{
+		{
+			format:      ExportFormatMarkdown,
+			contentType: "text/markdown; charset=utf-8",
+			want:        []string{"- **V1** [<script>", "### You · 0001-01-01 00:00", "- [V2 S2] 01:05–01:10 (Ретро): \"Бюджет утвердили\""},
+		},
+		{
+			format:      ExportFormatHTML,
+			contentType: "text/html; charset=utf-8",
+			want: []string{
+				"&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;",
+				"&lt;b&gt;жирный&lt;/b&gt; &amp; курсив",
+				`href="https://example.com/v?a=1&amp;b=2"`,
+				"[V1 S2] 01:05–01:10",
+			},
+			notWant: []string{"<script>", "<b>жирный"},
+		},
+		{
+			format:      ExportFormatJSON,
+			contentType: "application/json; charset=utf-8",
+			want:        []string{`"ref": "V2 S2"`, `"label": "V1"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			body, contentType, err := export.Render(tt.format)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if contentType != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", contentType, tt.contentType)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("Render(%s) has no %q:\n%s", tt.format, want, body)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(body), notWant) {
+					t.Errorf("Render(%s) has unescaped %q", tt.format, notWant)
+				}
+			}
+		})
+	}
+
+	t.Run("json round trip", func(t *testing.T) {
+		body, _, _ := export.Render(ExportFormatJSON)
+		var decoded SessionExport
+		if err := json.Unmarshal(body, &decoded); err != nil || decoded.Videos[0].Title != video.Title {
+			t.Errorf("decoded title = %q, err = %v", decoded.Videos[0].Title, err)
+		}
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		if _, _, err := export.Render("pdf"); !errors.Is(err, models.ErrInvalidExportFormat) {
+			t.Errorf("Render(pdf) error = %v, want ErrInvalidExportFormat", err)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+// services/session_memory.go
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Память обновляется, когда за её пределами накопилось столько сообщений
+	memoryRefreshMessages = 20
+	// Последние сообщения не сворачиваются — они идут в контекст целиком
+	memoryKeepRecent = 10
+)
+
+// SessionMemoryService сворачивает ранние реплики длинных сессий в память сессии
+type SessionMemoryService interface {
+	Refresh(ctx context.Context, sessionID primitive.ObjectID) error
+}
+
+type sessionMemoryService struct {
+	aiService   AIService
+	sessionRepo repository.AISessionRepository
+}
+
+func NewSessionMemoryService(aiService AIService, sessionRepo repository.AISessionRepository) SessionMemoryService {
+	return &sessionMemoryService{
+		aiService:   aiService,
+		sessionRepo: sessionRepo,
+	}
+}
+
+// Refresh ничего не делает, пока несвёрнутых сообщений меньше memoryRefreshMessages.
+// Память строится по активной ветке; после перехода в другую ветку она собирается заново.
+func (s *sessionMemoryService) Refresh(ctx context.Context, sessionID primitive.ObjectID) error {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+
+	branch := session.Branch()
+	prevMemory, prevUpTo := session.BranchMemory(branch)
+	upTo := len(branch) - memoryKeepRecent
+	if len(branch)-prevUpTo < memoryRefreshMessages || upTo <= prevUpTo {
+		return nil
+	}
+
+	memory, err := s.aiService.CompressMemory(ctx, prevMemory, branch[prevUpTo:upTo])
+	if err != nil {
+		return err
+	}
+
+	memory = strings.TrimSpace(memory)
+	if memory == "" {
+		return nil
+	}
+
+	// Параллельное обновление уже свернуло эти сообщения
+	err = s.sessionRepo.UpdateMemory(ctx, sessionID, memory, upTo, branch[upTo-1].ID, session.MemoryUpTo)
+	if errors.Is(err, models.ErrSessionUpdateConflict) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type memoryUpdate struct {
+	memory   string
+	upTo     int
+	anchorID primitive.ObjectID
+	prevUpTo int
+}
+
+type fakeMemorySessions struct {
+	repository.AISessionRepository
+	session   *models.AISession
+	updateErr error
+	updates   []memoryUpdate
+}
+
+func (f *fakeMemorySessions) GetByID(ctx context.Context, id primitive.ObjectID) (*models.AISession, error) {
+	return f.session, nil
+}
+
+func (f *fakeMemorySessions) UpdateMemory(ctx context.Context, sessionID primitive.ObjectID, memory string, upTo int, anchorID primitive.ObjectID, prevUpTo int) error {
+	f.updates = append(f.updates, memoryUpdate{memory: memory, upTo: upTo, anchorID: anchorID, prevUpTo: prevUpTo})
+	return f.updateErr
+}
+
+type fakeMemoryAI struct {
+	AIService
+	reply    string
+	err      error
+	memory   string
+	messages []models.AIMessage
+	calls    int
+}
+
+func (f *fakeMemoryAI) CompressMemory(ctx context.Context, memory string, messages []models.AIMessage) (string, error) {
+	f.calls++
+	f.memory, f.messages = memory, messages
+	return f.reply, f.err
+}
+
+// appendChain дописывает в сессию n сообщений, продолжающих parent (nil — начало диалога)
+func appendChain(session *models.AISession, parent *primitive.ObjectID, n int) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, n)
+	for i := range ids {
+		msg := models.AIMessage{ID: primitive.NewObjectID(), ParentID: parent, Role: "user"}
+		if i%2 == 1 {
+			msg.Role = "assistant"
+		}
+		session.Messages = append(session.Messages, msg)
+		ids[i] = msg.ID
+		parent = &ids[i]
+	}
+	return ids
+}
+
+func memorySession(messages, memoryUpTo int) (*models.AISession, []primitive.ObjectID) {
+	session := &models.AISession{ID: primitive.NewObjectID()}
+	ids := appendChain(session, nil, messages)
+	if memoryUpTo > 0 {
+		session.Memory = "ранняя память"
+		session.MemoryUpTo = memoryUpTo
+		session.MemoryAnchorID = &ids[memoryUpTo-1]
+	}
+	return session, ids
+}
+
+func TestSessionMemoryRefreshThreshold(t *testing.T) {
+	tests := []struct {
+		name       string
+		messages   int
+		memoryUpTo int
+		wantCalls  int
+		wantMemory string
+		wantFrom   int
+		wantUpTo   int
+	}{
+		{name: "short session", messages: memoryRefreshMessages - 1},
+		{name: "threshold reached", messages: memoryRefreshMessages, wantCalls: 1, wantFrom: 0, wantUpTo: memoryRefreshMessages - memoryKeepRecent},
+		{name: "few new messages after memory", messages: 10 + memoryRefreshMessages - 1, memoryUpTo: 10},
+		{name: "extends memory", messages: 10 + memoryRefreshMessages, memoryUpTo: 10, wantCalls: 1, wantMemory: "ранняя память", wantFrom: 10, wantUpTo: memoryRefreshMessages},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, ids := memorySession(tt.messages, tt.memoryUpTo)
+			repo := &fakeMemorySessions{session: session}
+			ai := &fakeMemoryAI{reply: " новая память "}
+			s := &sessionMemoryService{aiService: ai, sessionRepo: repo}
+
+			if err := s.Refresh(context.Background(), session.ID); err != nil {
+				t.Fatalf("Refresh() error = %v", err)
+			}
+			if ai.calls != tt.wantCalls {
+				t.Fatalf("CompressMemory calls = %d, want %d", ai.calls, tt.wantCalls)
+			}
+			if tt.wantCalls == 0 {
+				if len(repo.updates) != 0 {
+					t.Errorf("memory updated without compression: %+v", repo.updates)
+				}
+				return
+			}
+
+			if ai.memory != tt.wantMemory {
+				t.Errorf("CompressMemory memory = %q, want %q", ai.memory, tt.wantMemory)
+			}
+			if len(ai.messages) != tt.wantUpTo-tt.wantFrom || ai.messages[0].ID != ids[tt.wantFrom] {
+				t.Errorf("CompressMemory got %d messages, want %d from #%d", len(ai.messages), tt.wantUpTo-tt.wantFrom, tt.wantFrom)
+			}
+
+			want := memoryUpdate{memory: "новая память", upTo: tt.wantUpTo, anchorID: ids[tt.wantUpTo-1], prevUpTo: tt.memoryUpTo}
+			if len(repo.updates) != 1 || repo.updates[0] != want {
+				t.Errorf("UpdateMemory = %+v, want %+v", repo.updates, want)
+			}
+		})
+	}
+}
+
+func TestSessionMemoryRefreshAfterBranchSwitch(t *testing.T) {
+	// Память свёрнута по основной ветке до 15-го сообщения, а активна ветка,
+	// отходящая от 5-го: память ей не подходит и собирается заново
+	session, main := memorySession(25, 15)
+	alt := appendChain(session, &main[4], 17)
+	session.ActiveLeafID = &alt[len(alt)-1]
+
+	repo := &fakeMemorySessions{session: session}
+	ai := &fakeMemoryAI{reply: "память ветки"}
+	s := &sessionMemoryService{aiService: ai, sessionRepo: repo}
+
+	if err := s.Refresh(context.Background(), session.ID); err != nil {
+		t.Fatalf("Refresh() error = %v", err)
+	}
+
+	branchLen := 5 + len(alt)
+	upTo := branchLen - memoryKeepRecent
+	if ai.memory != "" || len(ai.messages) != upTo || ai.messages[0].ID != main[0] {
+		t.Fatalf("CompressMemory(%q, %d messages), want a rebuild of the first %d", ai.memory, len(ai.messages), upTo)
+	}
+
+	// prevUpTo — сохранённое значение, чтобы параллельное обновление не затёрлось
+	want := memoryUpdate{memory: "память ветки", upTo: upTo, anchorID: alt[upTo-5-1], prevUpTo: 15}
+	if len(repo.updates) != 1 || repo.updates[0] != want {
+		t.Errorf("UpdateMemory = %+v, want %+v", repo.updates, want)
+	}
+}
+
+func TestSessionMemoryRefreshErrors(t *testing.T) {
+	aiErr := errors.New("provider down")
+	dbErr := errors.New("write failed")
+
+	tests := []struct {
+		name        string
+		reply       string
+		aiErr       error
+		updateErr   error
+		wantErr     error
+		wantUpdates int
+	}{
+		{name: "concurrent update", reply: "память", updateErr: models.ErrSessionUpdateConflict, wantUpdates: 1},
+		{name: "update failed", reply: "память", updateErr: dbErr, wantErr: dbErr, wantUpdates: 1},
+		{name: "empty memory", reply: "  "},
+		{name: "compression failed", aiErr: aiErr, wantErr: aiErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session, _ := memorySession(memoryRefreshMessages, 0)
+			repo := &fakeMemorySessions{session: session, updateErr: tt.updateErr}
+			s := &sessionMemoryService{aiService: &fakeMemoryAI{reply: tt.reply, err: tt.aiErr}, sessionRepo: repo}
+
+			err := s.Refresh(context.Background(), session.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Refresh() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(repo.updates) != tt.wantUpdates {
+				t.Errorf("UpdateMemory calls = %d, want %d", len(repo.updates), tt.wantUpdates)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+// services/share_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"github.com/code-zt/vidnotes/pkg/password"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	shareTokenBytes = 24
+	// Неверных паролей к одной ссылке с одного IP за окно, после чего ссылка
+	// для этого IP закрыта до конца окна
+	sharePasswordMaxFailures = 10
+	sharePasswordWindow      = 15 * time.Minute
+	// С какого размера таблица попыток чистится от истёкших окон
+	sharePasswordSweepSize = 10000
+)
+
+// ShareService управляет публичными ссылками. Ссылки на видео воркспейса создают
+// и видят редакторы; отозвать ссылку может её автор или владелец воркспейса.
+type ShareService interface {
+	CreateShare(ctx context.Context, userID, videoID primitive.ObjectID, req models.CreateShareRequest) (*models.Share, error)
+	ListShares(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.Share, error)
+	RevokeShare(ctx context.Context, userID, shareID primitive.ObjectID) error
+	// OpenShare открывает ссылку; clientIP ограничивает подбор пароля
+	OpenShare(ctx context.Context, token, sharePassword, clientIP string) (*models.SharedVideo, error)
+	DeleteShares(ctx context.Context, videoID primitive.ObjectID) error
+}
+
+type shareService struct {
+	shareRepo      repository.ShareRepository
+	videoRepo      repository.VideoRepository
+	summaryService SummaryService
+	attempts       *passwordAttempts
+}
+
+func NewShareService(shareRepo repository.ShareRepository, videoRepo repository.VideoRepository, summaryService SummaryService) ShareService {
+	return &shareService{
+		shareRepo:      shareRepo,
+		videoRepo:      videoRepo,
+		summaryService: summaryService,
+		attempts:       newPasswordAttempts(),
+	}
+}
+
+func (s *shareService) CreateShare(ctx context.Context, userID, videoID primitive.ObjectID, req models.CreateShareRequest) (*models.Share, error) {
+	if _, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor); err != nil {
+		return nil, err
+	}
+
+	ttl := time.Duration(req.ExpiresIn) * time.Hour
+	if req.ExpiresIn < 0 || ttl > models.MaxShareTTL {
+		return nil, models.ErrInvalidShareTTL
+	}
+
+	token, err := password.GenerateToken(shareTokenBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrShareCreateFailed, err)
+	}
+
+	share := &models.Share{
+		Token:             token,
+		TokenHash:         password.HashToken(token),
+		UserID:            userID,
+		VideoID:           videoID,
+		IncludeTranscript: req.IncludeTranscript,
+	}
+
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		share.ExpiresAt = &expiresAt
+	}
+
+	if req.Password != "" {
+		hash, err := password.HashPassword(req.Password)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", models.ErrShareCreateFailed, err)
+		}
+		share.PasswordHash = hash
+		share.HasPassword = true
+	}
+
+	if _, err := s.shareRepo.Create(ctx, share); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (s *shareService) ListShares(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.Share, error) {
+	if _, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor); err != nil {
+		return nil, err
+	}
+	return s.shareRepo.GetByVideo(ctx, videoID)
+}
+
+// RevokeShare отзывает ссылку автора; чужие ссылки на видео воркспейса — только
+// владелец воркспейса, в том числе ссылки вышедших участников
+func (s *shareService) RevokeShare(ctx context.Context, userID, shareID primitive.ObjectID) error {
+	share, err := s.shareRepo.GetByID(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if share.UserID != userID {
+		if _, err := s.summaryService.AuthorizeVideo(ctx, userID, share.VideoID, models.WorkspaceRoleOwner); err != nil {
+			// Существование чужой ссылки не раскрываем
+			return models.ErrShareNotFound
+		}
+	}
+	return s.shareRepo.Revoke(ctx, shareID)
+}
+
+// OpenShare проверяет ссылку и пароль и возвращает публичное представление видео.
+// Отозванные ссылки и ссылки на удалённые видео неотличимы от несуществующих.
+// Считаются только неверные пароли: запрос без пароля и открытия без пароля
+// не расходуют попытки, а блокировка касается одного IP.
+func (s *shareService) OpenShare(ctx context.Context, token, sharePassword, clientIP string) (*models.SharedVideo, error) {
+	share, err := s.shareRepo.GetByTokenHash(ctx, password.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if share.RevokedAt != nil {
+		return nil, models.ErrShareNotFound
+	}
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		return nil, models.ErrShareExpired
+	}
+
+	if share.HasPassword {
+		if sharePassword == "" {
+			return nil, models.ErrSharePasswordRequired
+		}
+		key := share.ID.Hex() + "|" + clientIP
+		if s.attempts.blocked(key) {
+			return nil, models.ErrTooManyShareAttempts
+		}
+		if !password.CheckPassword(sharePassword, share.PasswordHash) {
+			s.attempts.fail(key)
+			return nil, models.ErrInvalidSharePassword
+		}
+	}
+
+	video, err := s.videoRepo.GetByID(ctx, share.VideoID)
+	if err != nil {
+		return nil, models.ErrShareNotFound
+	}
+
+	if err := s.shareRepo.RecordView(ctx, share.ID); err != nil {
+		fmt.Printf("Failed to record view for share %s: %v\n", share.ID.Hex(), err)
+	}
+
+	shared := &models.SharedVideo{
+		Title:     video.Title,
+		Summary:   video.Summary,
+		Language:  video.Language,
+		CreatedAt: video.CreatedAt,
+		ExpiresAt: share.ExpiresAt,
+	}
+	if share.IncludeTranscript {
+		shared.Transcript = video.Transcript
+		shared.Segments = video.Segments
+	}
+
+	return shared, nil
+}
+
+// DeleteShares удаляет ссылки на видео вместе с самим видео
+func (s *shareService) DeleteShares(ctx context.Context, videoID primitive.ObjectID) error {
+	return s.shareRepo.DeleteByVideo(ctx, videoID)
+}
+
+// passwordAttempts считает неверные пароли в памяти процесса
+type passwordAttempts struct {
+	mu       sync.Mutex
+	failures map[string]passwordFailures
+}
+
+type passwordFailures struct {
+	count   int
+	resetAt time.Time
+}
+
+func newPasswordAttempts() *passwordAttempts {
+	return &passwordAttempts{failures: make(map[string]passwordFailures)}
+}
+
+func (a *passwordAttempts) blocked(key string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	f, ok := a.failures[key]
+	return ok && time.Now().Before(f.resetAt) && f.count >= sharePasswordMaxFailures
+}
+
+func (a *passwordAttempts) fail(key string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if len(a.failures) >= sharePasswordSweepSize {
+		for k, f := range a.failures {
+			if !now.Before(f.resetAt) {
+				delete(a.failures, k)
+			}
+		}
+	}
+
+	f, ok := a.failures[key]
+	if !ok || !now.Before(f.resetAt) {
+		f = passwordFailures{resetAt: now.Add(sharePasswordWindow)}
+	}
+	f.count++
+	a.failures[key] = f
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"github.com/code-zt/vidnotes/pkg/password"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeShareRepo struct {
+	repository.ShareRepository
+	shares map[primitive.ObjectID]*models.Share
+}
+
+func (f *fakeShareRepo) Create(ctx context.Context, share *models.Share) (primitive.ObjectID, error) {
+	share.ID = primitive.NewObjectID()
+	f.shares[share.ID] = share
+	return share.ID, nil
+}
+
+func (f *fakeShareRepo) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Share, error) {
+	share, ok := f.shares[id]
+	if !ok {
+		return nil, models.ErrShareNotFound
+	}
+	return share, nil
+}
+
+func (f *fakeShareRepo) GetByTokenHash(ctx context.Context, tokenHash string) (*models.Share, error) {
+	for _, share := range f.shares {
+		if share.TokenHash == tokenHash {
+			return share, nil
+		}
+	}
+	return nil, models.ErrShareNotFound
+}
+
+func (f *fakeShareRepo) RecordView(ctx context.Context, id primitive.ObjectID) error {
+	f.shares[id].ViewCount++
+	return nil
+}
+
+func (f *fakeShareRepo) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now()
+	f.shares[id].RevokedAt = &now
+	return nil
+}
+
+func TestShareServiceWorkspaceAccess(t *testing.T) {
+	workspaceID := primitive.NewObjectID()
+	video := &models.Video{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), WorkspaceID: &workspaceID}
+
+	tests := []struct {
+		name          string
+		role          string
+		wantCreateErr error
+		wantRevokeErr error
+	}{
+		{name: "owner", role: models.WorkspaceRoleOwner},
+		{name: "editor", role: models.WorkspaceRoleEditor, wantRevokeErr: models.ErrShareNotFound},
+		{name: "viewer", role: models.WorkspaceRoleViewer, wantCreateErr: models.ErrWorkspaceAccessDenied, wantRevokeErr: models.ErrShareNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeShareRepo{shares: map[primitive.ObjectID]*models.Share{}}
+			access := &fakeVideoAccess{video: video, role: tt.role}
+			s := &shareService{shareRepo: repo, summaryService: access}
+			ctx := context.Background()
+
+			_, err := s.CreateShare(ctx, primitive.NewObjectID(), video.ID, models.CreateShareRequest{})
+			if !errors.Is(err, tt.wantCreateErr) {
+				t.Errorf("CreateShare() error = %v, want %v", err, tt.wantCreateErr)
+			}
+
+			// Ссылка участника, которого уже нет в воркспейсе
+			memberShare := &models.Share{UserID: primitive.NewObjectID(), VideoID: video.ID}
+			repo.Create(ctx, memberShare)
+
+			err = s.RevokeShare(ctx, primitive.NewObjectID(), memberShare.ID)
+			if !errors.Is(err, tt.wantRevokeErr) {
+				t.Errorf("RevokeShare() of another member's link error = %v, want %v", err, tt.wantRevokeErr)
+			}
+			if revoked := memberShare.RevokedAt != nil; revoked != (tt.wantRevokeErr == nil) {
+				t.Errorf("link revoked = %v, want %v", revoked, tt.wantRevokeErr == nil)
+			}
+		})
+	}
+}
+
+func TestShareServiceRevokeOwnShare(t *testing.T) {
+	repo := &fakeShareRepo{shares: map[primitive.ObjectID]*models.Share{}}
+	// Автор ссылки отзывает её, даже если у него больше нет доступа к видео
+	s := &shareService{shareRepo: repo, summaryService: &fakeVideoAccess{}}
+	ctx := context.Background()
+
+	share := &models.Share{UserID: primitive.NewObjectID(), VideoID: primitive.NewObjectID()}
+	repo.Create(ctx, share)
+
+	if err := s.RevokeShare(ctx, share.UserID, share.ID); err != nil || share.RevokedAt == nil {
+		t.Errorf("RevokeShare() by author error = %v, revoked = %v", err, share.RevokedAt != nil)
+	}
+}
+
+type fakeSharedVideos struct {
+	repository.VideoRepository
+	video *models.Video
+}
+
+func (f *fakeSharedVideos) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Video, error) {
+	if f.video == nil || f.video.ID != id {
+		return nil, models.ErrVideoNotFound
+	}
+	return f.video, nil
+}
+
+func TestShareServiceTokenHash(t *testing.T) {
+	video := &models.Video{ID: primitive.NewObjectID(), UserID: primitive.NewObjectID(), Title: "Планёрка", Summary: "Итоги"}
+	repo := &fakeShareRepo{shares: map[primitive.ObjectID]*models.Share{}}
+	s := &shareService{
+		shareRepo:      repo,
+		videoRepo:      &fakeSharedVideos{video: video},
+		summaryService: &fakeVideoAccess{video: video, role: models.WorkspaceRoleOwner},
+	}
+	ctx := context.Background()
+
+	share, err := s.CreateShare(ctx, video.UserID, video.ID, models.CreateShareRequest{})
+	if err != nil {
+		t.Fatalf("CreateShare() error = %v", err)
+	}
+	if share.Token == "" || share.TokenHash != password.HashToken(share.Token) {
+		t.Fatalf("token hash = %q, want SHA-256 of the returned token", share.TokenHash)
+	}
+	if share.TokenHash == share.Token {
+		t.Fatal("token stored in plaintext")
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{name: "plain token", token: share.Token},
+		{name: "stored hash is not a token", token: share.TokenHash, wantErr: models.ErrShareNotFound},
+		{name: "unknown token", token: "unknown", wantErr: models.ErrShareNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shared, err := s.OpenShare(ctx, tt.token, "", "203.0.113.7")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("OpenShare() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && shared.Title != video.Title {
+				t.Errorf("OpenShare() title = %q, want %q", shared.Title, video.Title)
+			}
+		})
+	}
+}
+
+func TestShareServicePasswordAttempts(t *testing.T) {
+	video := &models.Video{ID: primitive.NewObjectID(), Title: "Планёрка"}
+	hash, err := password.HashPassword("верный")
+	if err != nil {
+		t.Fatal(err)
+	}
+	share := &models.Share{VideoID: video.ID, TokenHash: password.HashToken("token"), PasswordHash: hash, HasPassword: true}
+	repo := &fakeShareRepo{shares: map[primitive.ObjectID]*models.Share{}}
+	repo.Create(context.Background(), share)
+	s := &shareService{shareRepo: repo, videoRepo: &fakeSharedVideos{video: video}, attempts: newPasswordAttempts()}
+	ctx := context.Background()
+
+	// Открытия без пароля — обычный первый запрос браузера — попыток не расходуют
+	for i := 0; i < sharePasswordMaxFailures*2; i++ {
+		if _, err := s.OpenShare(ctx, "token", "", "203.0.113.7"); !errors.Is(err, models.ErrSharePasswordRequired) {
+			t.Fatalf("OpenShare() without password error = %v, want ErrSharePasswordRequired", err)
+		}
+	}
+	if _, err := s.OpenShare(ctx, "token", "верный", "203.0.113.7"); err != nil {
+		t.Fatalf("OpenShare() with the right password error = %v", err)
+	}
+
+	for i := 0; i < sharePasswordMaxFailures; i++ {
+		if _, err := s.OpenShare(ctx, "token", "неверный", "203.0.113.7"); !errors.Is(err, models.ErrInvalidSharePassword) {
+			t.Fatalf("attempt %d error = %v, want ErrInvalidSharePassword", i+1, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		ip       string
+		wantErr  error
+	}{
+		{name: "blocked IP, right password", password: "верный", ip: "203.0.113.7", wantErr: models.ErrTooManyShareAttempts},
+		{name: "blocked IP, no password", password: "", ip: "203.0.113.7", wantErr: models.ErrSharePasswordRequired},
+		{name: "other IP", password: "верный", ip: "198.51.100.2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.OpenShare(ctx, "token", tt.password, tt.ip); !errors.Is(err, tt.wantErr) {
+				t.Errorf("OpenShare() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
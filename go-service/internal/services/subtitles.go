@@ -0,0 +1,56 @@
+// services/subtitles.go
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+// Форматы субтитров
+const (
+	SubtitleFormatSRT = "srt"
+	SubtitleFormatVTT = "vtt"
+)
+
+// RenderSubtitles печатает сегменты субтитрами и возвращает их с Content-Type
+func RenderSubtitles(segments []models.TranscriptSegment, format string) ([]byte, string, error) {
+	var b strings.Builder
+	var contentType string
+	var separator string
+
+	switch format {
+	case SubtitleFormatSRT:
+		contentType, separator = "application/x-subrip; charset=utf-8", ","
+	case SubtitleFormatVTT:
+		contentType, separator = "text/vtt; charset=utf-8", "."
+		b.WriteString("WEBVTT\n\n")
+	default:
+		return nil, "", models.ErrInvalidSubtitleFormat
+	}
+
+	n := 0
+	for _, seg := range segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		n++
+		if format == SubtitleFormatSRT {
+			fmt.Fprintf(&b, "%d\n", n)
+		}
+		fmt.Fprintf(&b, "%s --> %s\n%s\n\n", subtitleTimestamp(seg.Start, separator), subtitleTimestamp(seg.End, separator), text)
+	}
+
+	return []byte(b.String()), contentType, nil
+}
+
+// subtitleTimestamp печатает время как 00:01:02,345 (SRT) или 00:01:02.345 (WebVTT)
+func subtitleTimestamp(seconds float64, separator string) string {
+	ms := int64(seconds*1000 + 0.5)
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, separator, ms%1000)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func TestSubtitleTimestamp(t *testing.T) {
+	tests := []struct {
+		seconds   float64
+		separator string
+		want      string
+	}{
+		{seconds: 0, separator: ",", want: "00:00:00,000"},
+		{seconds: 62.345, separator: ",", want: "00:01:02,345"},
+		{seconds: 62.345, separator: ".", want: "00:01:02.345"},
+		{seconds: 1.9996, separator: ",", want: "00:00:02,000"},
+		{seconds: 3723.004, separator: ".", want: "01:02:03.004"},
+		{seconds: -1, separator: ",", want: "00:00:00,000"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := subtitleTimestamp(tt.seconds, tt.separator); got != tt.want {
+				t.Errorf("subtitleTimestamp(%v, %q) = %q, want %q", tt.seconds, tt.separator, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSubtitles(t *testing.T) {
+	segments := []models.TranscriptSegment{
+		{ID: 1, Start: 0, End: 2.5, Text: " Добрый день. "},
+		{ID: 2, Start: 2.5, End: 3, Text: "  "},
+		{ID: 3, Start: 3, End: 61.25, Text: "Начнём с бюджета."},
+	}
+
+	tests := []struct {
+		name            string
+		segments        []models.TranscriptSegment
+		format          string
+		want            string
+		wantContentType string
+		wantErr         error
+	}{
+		{
+			name:            "srt",
+			segments:        segments,
+			format:          SubtitleFormatSRT,
+			want:            "1\n00:00:00,000 --> 00:00:02,500\nДобрый день.\n\n2\n00:00:03,000 --> 00:01:01,250\nНачнём с бюджета.\n\n",
+			wantContentType: "application/x-subrip; charset=utf-8",
+		},
+		{
+			name:            "vtt",
+			segments:        segments,
+			format:          SubtitleFormatVTT,
+			want:            "WEBVTT\n\n00:00:00.000 --> 00:00:02.500\nДобрый день.\n\n00:00:03.000 --> 00:01:01.250\nНачнём с бюджета.\n\n",
+			wantContentType: "text/vtt; charset=utf-8",
+		},
+		{
+			name:            "vtt without segments",
+			format:          SubtitleFormatVTT,
+			want:            "WEBVTT\n\n",
+			wantContentType: "text/vtt; charset=utf-8",
+		},
+		{name: "unknown format", segments: segments, format: "ass", wantErr: models.ErrInvalidSubtitleFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType, err := RenderSubtitles(tt.segments, tt.format)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if string(body) != tt.want {
+				t.Errorf("body = %q, want %q", body, tt.want)
+			}
+			if contentType != tt.wantContentType {
+				t.Errorf("content type = %q, want %q", contentType, tt.wantContentType)
+			}
+		})
+	}
+}
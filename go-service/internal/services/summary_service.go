@@ -0,0 +1,415 @@
+// services/summary_service.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SummaryService ведёт историю версий саммари. Любое изменение саммари —
+// после обработки, вручную или через AI — проходит через RecordVersion.
+type SummaryService interface {
+	RecordVersion(ctx context.Context, videoID primitive.ObjectID, content, source string, authorID *primitive.ObjectID) (*models.SummaryVersion, error)
+	UpdateSummary(ctx context.Context, userID, videoID primitive.ObjectID, content string) (*models.SummaryVersion, error)
+	ListVersions(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.SummaryVersion, error)
+	GetVersion(ctx context.Context, userID, videoID primitive.ObjectID, version int) (*models.SummaryVersion, error)
+	Diff(ctx context.Context, userID, videoID primitive.ObjectID, from, to int) (*models.SummaryDiff, error)
+	Restore(ctx context.Context, userID, videoID primitive.ObjectID, version int) (*models.SummaryVersion, error)
+	DeleteVersions(ctx context.Context, videoID primitive.ObjectID) error
+	// AI-доработка выполняется в фоне; результат — новая версия, статус — в Video.SummaryJob
+	ImproveSummary(ctx context.Context, userID, videoID primitive.ObjectID, focus []string) (*models.SummaryJob, error)
+	FixSummary(ctx context.Context, userID, videoID primitive.ObjectID) (*models.SummaryJob, error)
+	SummarizeSession(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.SummaryJob, error)
+	// AuthorizeVideo проверяет доступ к видео: к личному — владелец, к видео воркспейса —
+	// участник с ролью не ниже minRole, в том числе загрузивший его
+	AuthorizeVideo(ctx context.Context, userID, videoID primitive.ObjectID, minRole string) (*models.Video, error)
+}
+
+// Задачи дольше этого срока считаются зависшими (например, после рестарта)
+const summaryJobTimeout = 5 * time.Minute
+
+type summaryService struct {
+	versionRepo      repository.SummaryVersionRepository
+	videoRepo        repository.VideoRepository
+	sessionRepo      repository.AISessionRepository
+	aiService        AIService
+	embeddingService EmbeddingService
+	userService      UserService
+	workspaceService WorkspaceService
+}
+
+func NewSummaryService(
+	versionRepo repository.SummaryVersionRepository,
+	videoRepo repository.VideoRepository,
+	sessionRepo repository.AISessionRepository,
+	aiService AIService,
+	embeddingService EmbeddingService,
+	userService UserService,
+	workspaceService WorkspaceService,
+) SummaryService {
+	return &summaryService{
+		versionRepo:      versionRepo,
+		videoRepo:        videoRepo,
+		sessionRepo:      sessionRepo,
+		aiService:        aiService,
+		embeddingService: embeddingService,
+		userService:      userService,
+		workspaceService: workspaceService,
+	}
+}
+
+func (s *summaryService) RecordVersion(ctx context.Context, videoID primitive.ObjectID, content, source string, authorID *primitive.ObjectID) (*models.SummaryVersion, error) {
+	return s.record(ctx, videoID, &models.SummaryVersion{
+		VideoID:  videoID,
+		Content:  content,
+		Source:   source,
+		AuthorID: authorID,
+	})
+}
+
+func (s *summaryService) UpdateSummary(ctx context.Context, userID, videoID primitive.ObjectID, content string) (*models.SummaryVersion, error) {
+	content = strings.TrimSpace(content)
+	if content == "" || utf8.RuneCountInString(content) > models.MaxSummaryLength {
+		return nil, models.ErrInvalidSummary
+	}
+
+	video, err := s.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureBaseline(ctx, video); err != nil {
+		return nil, err
+	}
+
+	return s.record(ctx, videoID, &models.SummaryVersion{
+		VideoID:  videoID,
+		Content:  content,
+		Source:   models.SummarySourceManual,
+		AuthorID: &userID,
+	})
+}
+
+func (s *summaryService) ListVersions(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.SummaryVersion, error) {
+	video, err := s.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureBaseline(ctx, video); err != nil {
+		return nil, err
+	}
+	return s.versionRepo.GetByVideo(ctx, videoID)
+}
+
+func (s *summaryService) GetVersion(ctx context.Context, userID, videoID primitive.ObjectID, version int) (*models.SummaryVersion, error) {
+	video, err := s.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureBaseline(ctx, video); err != nil {
+		return nil, err
+	}
+	return s.versionRepo.GetVersion(ctx, videoID, version)
+}
+
+func (s *summaryService) Diff(ctx context.Context, userID, videoID primitive.ObjectID, from, to int) (*models.SummaryDiff, error) {
+	fromVersion, err := s.GetVersion(ctx, userID, videoID, from)
+	if err != nil {
+		return nil, err
+	}
+	toVersion, err := s.versionRepo.GetVersion(ctx, videoID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	ops := diffText(fromVersion.Content, toVersion.Content)
+	if ops == nil {
+		ops = []models.DiffOp{}
+	}
+	insertions, deletions := countDiff(ops)
+
+	return &models.SummaryDiff{
+		From:       from,
+		To:         to,
+		Ops:        ops,
+		Insertions: insertions,
+		Deletions:  deletions,
+	}, nil
+}
+
+// Restore создаёт новую версию с содержимым старой; история не переписывается
+func (s *summaryService) Restore(ctx context.Context, userID, videoID primitive.ObjectID, version int) (*models.SummaryVersion, error) {
+	if _, err := s.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor); err != nil {
+		return nil, err
+	}
+
+	source, err := s.versionRepo.GetVersion(ctx, videoID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.record(ctx, videoID, &models.SummaryVersion{
+		VideoID:      videoID,
+		Content:      source.Content,
+		Source:       models.SummarySourceRestore,
+		AuthorID:     &userID,
+		RestoredFrom: &version,
+	})
+}
+
+func (s *summaryService) DeleteVersions(ctx context.Context, videoID primitive.ObjectID) error {
+	return s.versionRepo.DeleteByVideo(ctx, videoID)
+}
+
+func (s *summaryService) ImproveSummary(ctx context.Context, userID, videoID primitive.ObjectID, focus []string) (*models.SummaryJob, error) {
+	focus, err := normalizeFocus(focus)
+	if err != nil {
+		return nil, err
+	}
+
+	video, err := s.authorizeRefinement(ctx, userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := video.Summary
+	return s.startJob(ctx, userID, video, models.SummarySourceImprove, nil, func(ctx context.Context) (string, error) {
+		return s.aiService.ImproveSummary(ctx, summary, focus)
+	})
+}
+
+func (s *summaryService) FixSummary(ctx context.Context, userID, videoID primitive.ObjectID) (*models.SummaryJob, error) {
+	video, err := s.authorizeRefinement(ctx, userID, videoID)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := video.Summary
+	return s.startJob(ctx, userID, video, models.SummarySourceFix, nil, func(ctx context.Context) (string, error) {
+		return s.aiService.FixSummaryErrors(ctx, summary)
+	})
+}
+
+// SummarizeSession строит саммари видео по диалогу сессии
+func (s *summaryService) SummarizeSession(ctx context.Context, userID, sessionID primitive.ObjectID) (*models.SummaryJob, error) {
+	session, err := s.sessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	// К чужим сессиям доступ только через воркспейс; роль проверяется по видео
+	if session.UserID != userID && session.WorkspaceID == nil {
+		return nil, models.ErrSessionNotFound
+	}
+
+	// Саммари по диалогу пишется в одно видео; в диалоге по нескольким видео неясно, в какое
+	if len(session.VideoIDs) > 1 {
+		return nil, models.ErrMultiVideoSession
+	}
+	// Саммари строится по активной ветке диалога
+	messages := session.Branch()
+	if !hasDialogue(messages) {
+		return nil, models.ErrEmptyDialogue
+	}
+
+	video, err := s.AuthorizeVideo(ctx, userID, session.VideoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.startJob(ctx, userID, video, models.SummarySourceDialogue, &session.ID, func(ctx context.Context) (string, error) {
+		return s.aiService.CreateSummaryFromDialogue(ctx, messages)
+	})
+}
+
+func (s *summaryService) AuthorizeVideo(ctx context.Context, userID, videoID primitive.ObjectID, minRole string) (*models.Video, error) {
+	video, err := s.videoRepo.GetByID(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	// Видео воркспейса — только по роли: загрузивший его участник после исключения
+	// или понижения теряет доступ наравне с остальными
+	if video.WorkspaceID == nil {
+		if video.UserID != userID {
+			return nil, models.ErrVideoNotFound
+		}
+		return video, nil
+	}
+	if _, err := s.workspaceService.Authorize(ctx, *video.WorkspaceID, userID, minRole); err != nil {
+		return nil, err
+	}
+	return video, nil
+}
+
+func (s *summaryService) authorizeRefinement(ctx context.Context, userID, videoID primitive.ObjectID) (*models.Video, error) {
+	video, err := s.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(video.Summary) == "" {
+		return nil, models.ErrSummaryNotReady
+	}
+	return video, nil
+}
+
+// startJob проверяет квоту, ставит задачу на видео и запускает её в фоне.
+// Доработки расходуют ту же квоту анализов, что и загрузка видео, а токены
+// модели — из месячного лимита запустившего их пользователя.
+func (s *summaryService) startJob(ctx context.Context, userID primitive.ObjectID, video *models.Video, source string, sessionID *primitive.ObjectID, run func(ctx context.Context) (string, error)) (*models.SummaryJob, error) {
+	if video.WorkspaceID != nil {
+		if err := s.workspaceService.CanPerformAnalysis(ctx, *video.WorkspaceID); err != nil {
+			return nil, err
+		}
+	} else if err := s.userService.CanPerformAnalysis(ctx, userID); err != nil {
+		return nil, err
+	}
+	if err := s.userService.CheckAIQuota(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureBaseline(ctx, video); err != nil {
+		return nil, err
+	}
+
+	job := &models.SummaryJob{
+		ID:        primitive.NewObjectID(),
+		VideoID:   video.ID,
+		Source:    source,
+		Status:    models.SummaryJobProcessing,
+		SessionID: sessionID,
+		StartedAt: time.Now(),
+	}
+	if err := s.videoRepo.StartSummaryJob(ctx, video.ID, job, time.Now().Add(-summaryJobTimeout)); err != nil {
+		return nil, err
+	}
+
+	// Квоту списываем атомарно: проверка выше не защищает от параллельных запусков
+	var err error
+	if video.WorkspaceID != nil {
+		err = s.workspaceService.RecordAnalysis(ctx, *video.WorkspaceID)
+	} else {
+		err = s.userService.RecordAnalysis(ctx, userID)
+	}
+	if err != nil {
+		if err := s.videoRepo.FinishSummaryJob(ctx, video.ID, job.ID, models.SummaryJobFailed, 0, err.Error()); err != nil {
+			fmt.Printf("Failed to update summary job for video %s: %v\n", video.ID.Hex(), err)
+		}
+		return nil, err
+	}
+
+	go s.runJob(userID, job, video, run)
+
+	return job, nil
+}
+
+func (s *summaryService) runJob(userID primitive.ObjectID, job *models.SummaryJob, video *models.Video, run func(ctx context.Context) (string, error)) {
+	ctx, cancel := context.WithTimeout(context.Background(), summaryJobTimeout)
+	defer cancel()
+	ctx = WithAIWorkspace(WithAIUsageScope(prompts.WithLocale(ctx, video.Language), userID, job.SessionID), video.WorkspaceID)
+
+	var version *models.SummaryVersion
+	content, err := run(ctx)
+	if err == nil {
+		content = strings.TrimSpace(content)
+		if content == "" || utf8.RuneCountInString(content) > models.MaxSummaryLength {
+			err = models.ErrInvalidSummary
+		}
+	}
+	if err == nil {
+		version, err = s.record(ctx, job.VideoID, &models.SummaryVersion{
+			VideoID:   job.VideoID,
+			Content:   content,
+			Source:    job.Source,
+			AuthorID:  &userID,
+			SessionID: job.SessionID,
+		})
+	}
+
+	if err != nil {
+		fmt.Printf("Summary job %s for video %s failed: %v\n", job.Source, job.VideoID.Hex(), err)
+		if err := s.videoRepo.FinishSummaryJob(ctx, job.VideoID, job.ID, models.SummaryJobFailed, 0, err.Error()); err != nil {
+			fmt.Printf("Failed to update summary job for video %s: %v\n", job.VideoID.Hex(), err)
+		}
+		return
+	}
+
+	if err := s.videoRepo.FinishSummaryJob(ctx, job.VideoID, job.ID, models.SummaryJobCompleted, version.Version, ""); err != nil {
+		fmt.Printf("Failed to update summary job for video %s: %v\n", job.VideoID.Hex(), err)
+	}
+}
+
+// record сохраняет версию, делает её текущей и переиндексирует видео для семантического поиска
+func (s *summaryService) record(ctx context.Context, videoID primitive.ObjectID, version *models.SummaryVersion) (*models.SummaryVersion, error) {
+	if err := s.versionRepo.Create(ctx, version); err != nil {
+		return nil, err
+	}
+
+	if err := s.videoRepo.UpdateSummary(ctx, videoID, version.Content); err != nil {
+		return nil, err
+	}
+
+	// После обработки видео индексируется отдельно, вместе с транскриптом
+	if version.Source != models.SummarySourceProcessing {
+		if video, err := s.videoRepo.GetByID(ctx, videoID); err == nil {
+			if err := s.embeddingService.IndexVideo(ctx, video); err != nil {
+				fmt.Printf("Failed to reindex video %s after summary change: %v\n", videoID.Hex(), err)
+			}
+		}
+	}
+
+	return version, nil
+}
+
+// ensureBaseline сохраняет исходное саммари видео, обработанных до появления версий.
+// Подсчёт только избавляет от лишней вставки: базовая версия всегда получает номер 1,
+// поэтому параллельные запросы не создают её дважды.
+func (s *summaryService) ensureBaseline(ctx context.Context, video *models.Video) error {
+	if video.Summary == "" {
+		return nil
+	}
+
+	count, err := s.versionRepo.CountByVideo(ctx, video.ID)
+	if err != nil || count > 0 {
+		return err
+	}
+
+	return s.versionRepo.CreateBaseline(ctx, &models.SummaryVersion{
+		VideoID: video.ID,
+		Content: video.Summary,
+		Source:  models.SummarySourceProcessing,
+	})
+}
+
+// normalizeFocus убирает пустые акценты и ограничивает их число и длину
+func normalizeFocus(focus []string) ([]string, error) {
+	result := make([]string, 0, len(focus))
+	for _, item := range focus {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if utf8.RuneCountInString(item) > models.MaxImproveFocusLength {
+			return nil, models.ErrInvalidImproveFocus
+		}
+		result = append(result, item)
+	}
+	if len(result) > models.MaxImproveFocusItems {
+		return nil, models.ErrInvalidImproveFocus
+	}
+	return result, nil
+}
+
+func hasDialogue(messages []models.AIMessage) bool {
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeMembership — роли участников воркспейса; кого нет в roles, тот не участник
+type fakeMembership struct {
+	WorkspaceService
+	roles map[primitive.ObjectID]string
+}
+
+func (f *fakeMembership) Authorize(ctx context.Context, workspaceID, userID primitive.ObjectID, minRole string) (*models.WorkspaceMember, error) {
+	role, ok := f.roles[userID]
+	if !ok {
+		return nil, models.ErrWorkspaceNotFound
+	}
+	if workspaceRoleRank[role] < workspaceRoleRank[minRole] {
+		return nil, models.ErrWorkspaceAccessDenied
+	}
+	return &models.WorkspaceMember{UserID: userID, Role: role}, nil
+}
+
+func TestSummaryServiceAuthorizeVideo(t *testing.T) {
+	uploader := primitive.NewObjectID()
+	workspaceID := primitive.NewObjectID()
+
+	tests := []struct {
+		name         string
+		workspace    *primitive.ObjectID
+		uploaderRole string // пусто — загрузивший не участник
+		user         primitive.ObjectID
+		minRole      string
+		wantErr      error
+	}{
+		{name: "personal video owner", user: uploader, minRole: models.WorkspaceRoleOwner},
+		{name: "personal video stranger", user: primitive.NewObjectID(), minRole: models.WorkspaceRoleViewer, wantErr: models.ErrVideoNotFound},
+		{name: "uploader still editor", workspace: &workspaceID, uploaderRole: models.WorkspaceRoleEditor, user: uploader, minRole: models.WorkspaceRoleEditor},
+		{name: "uploader demoted to viewer", workspace: &workspaceID, uploaderRole: models.WorkspaceRoleViewer, user: uploader, minRole: models.WorkspaceRoleEditor, wantErr: models.ErrWorkspaceAccessDenied},
+		{name: "uploader removed", workspace: &workspaceID, user: uploader, minRole: models.WorkspaceRoleViewer, wantErr: models.ErrWorkspaceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			video := &models.Video{ID: primitive.NewObjectID(), UserID: uploader, WorkspaceID: tt.workspace}
+			members := &fakeMembership{roles: map[primitive.ObjectID]string{}}
+			if tt.uploaderRole != "" {
+				members.roles[uploader] = tt.uploaderRole
+			}
+			s := &summaryService{videoRepo: &fakeSharedVideos{video: video}, workspaceService: members}
+
+			got, err := s.AuthorizeVideo(context.Background(), tt.user, video.ID, tt.minRole)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AuthorizeVideo() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && got != video {
+				t.Errorf("AuthorizeVideo() = %v, want the video", got)
+			}
+		})
+	}
+}
+
+// fakeVersionRepo хранит версии по номеру, как уникальный индекс {video_id, version};
+// staleCount имитирует подсчёт, сделанный до вставки параллельного запроса
+type fakeVersionRepo struct {
+	repository.SummaryVersionRepository
+	versions   map[int]*models.SummaryVersion
+	staleCount bool
+}
+
+func (f *fakeVersionRepo) CountByVideo(ctx context.Context, videoID primitive.ObjectID) (int64, error) {
+	if f.staleCount {
+		return 0, nil
+	}
+	return int64(len(f.versions)), nil
+}
+
+func (f *fakeVersionRepo) CreateBaseline(ctx context.Context, version *models.SummaryVersion) error {
+	if _, ok := f.versions[1]; !ok {
+		version.Version = 1
+		f.versions[1] = version
+	}
+	return nil
+}
+
+func TestSummaryServiceEnsureBaselineOnce(t *testing.T) {
+	repo := &fakeVersionRepo{versions: map[int]*models.SummaryVersion{}, staleCount: true}
+	s := &summaryService{versionRepo: repo}
+	video := &models.Video{ID: primitive.NewObjectID(), Summary: "Исходное саммари"}
+
+	for i := 0; i < 2; i++ {
+		if err := s.ensureBaseline(context.Background(), video); err != nil {
+			t.Fatalf("ensureBaseline() error = %v", err)
+		}
+	}
+	if len(repo.versions) != 1 || repo.versions[1].Content != video.Summary {
+		t.Errorf("versions after two baselines = %d, want one version 1", len(repo.versions))
+	}
+}
@@ -0,0 +1,286 @@
+// services/translation_service.go
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/prompts"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Перевод длинного транскрипта идёт десятками запросов к модели
+const translationJobTimeout = 30 * time.Minute
+
+// Код языка: ISO 639-1/639-3 и необязательный регион или письменность (pt-br, zh-hans)
+var languagePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+
+// Названия языков для промпта; остальные коды модель понимает и так
+var languageNames = map[string]string{
+	"ar": "Arabic", "de": "German", "en": "English", "es": "Spanish", "fr": "French",
+	"hi": "Hindi", "it": "Italian", "ja": "Japanese", "kk": "Kazakh", "ko": "Korean",
+	"pl": "Polish", "pt": "Portuguese", "pt-br": "Brazilian Portuguese", "ru": "Russian",
+	"tr": "Turkish", "uk": "Ukrainian", "zh": "Chinese", "zh-hans": "Simplified Chinese",
+	"zh-hant": "Traditional Chinese",
+}
+
+// TranslationService переводит саммари и транскрипт видео на другие языки.
+// Перевод идёт в фоне, хранится по одному на язык и переиспользуется, пока
+// оригинал не изменился.
+type TranslationService interface {
+	// Translate возвращает готовый актуальный перевод или запускает перевод;
+	// started сообщает, что перевод ещё идёт
+	Translate(ctx context.Context, userID, videoID primitive.ObjectID, language string) (translation *models.Translation, started bool, err error)
+	List(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.Translation, error)
+	Get(ctx context.Context, userID, videoID primitive.ObjectID, language string) (*models.Translation, error)
+	// Subtitles печатает субтитры в SRT или WebVTT; пустой language — язык оригинала
+	Subtitles(ctx context.Context, userID, videoID primitive.ObjectID, language, format string) ([]byte, string, error)
+	// Completed возвращает готовый перевод для экспорта или nil, если его нет.
+	// Доступ к видео проверяет вызывающий.
+	Completed(ctx context.Context, videoID primitive.ObjectID, language string) (*models.Translation, error)
+	DeleteTranslations(ctx context.Context, videoID primitive.ObjectID) error
+}
+
+type translationService struct {
+	translationRepo repository.TranslationRepository
+	summaryService  SummaryService
+	userService     UserService
+	aiService       AIService
+}
+
+func NewTranslationService(translationRepo repository.TranslationRepository, summaryService SummaryService, userService UserService, aiService AIService) TranslationService {
+	return &translationService{
+		translationRepo: translationRepo,
+		summaryService:  summaryService,
+		userService:     userService,
+		aiService:       aiService,
+	}
+}
+
+// NormalizeLanguage приводит код языка к виду pt-br
+func NormalizeLanguage(language string) (string, error) {
+	language = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+	if !languagePattern.MatchString(language) {
+		return "", models.ErrInvalidTranslationLanguage
+	}
+	return language, nil
+}
+
+func languageName(language string) string {
+	if name, ok := languageNames[language]; ok {
+		return name
+	}
+	return language
+}
+
+// Перевод читает видео, поэтому достаточно роли наблюдателя; токены списываются с запросившего
+func (s *translationService) Translate(ctx context.Context, userID, videoID primitive.ObjectID, language string) (*models.Translation, bool, error) {
+	language, err := NormalizeLanguage(language)
+	if err != nil {
+		return nil, false, err
+	}
+
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, false, err
+	}
+	if video.Language != "" && strings.EqualFold(video.Language, language) {
+		return nil, false, models.ErrInvalidTranslationLanguage
+	}
+	if strings.TrimSpace(video.Summary) == "" && strings.TrimSpace(video.Transcript) == "" {
+		return nil, false, models.ErrNoVideoContent
+	}
+
+	hash := sourceHash(video)
+	existing, err := s.translationRepo.Get(ctx, videoID, language)
+	if err != nil && !errors.Is(err, models.ErrTranslationNotFound) {
+		return nil, false, err
+	}
+	if existing != nil && existing.Status == models.TranslationCompleted && existing.SourceHash == hash {
+		return existing, false, nil
+	}
+
+	if err := s.userService.CheckAIQuota(ctx, userID); err != nil {
+		return nil, false, err
+	}
+
+	translation := &models.Translation{
+		VideoID:        videoID,
+		Language:       language,
+		SourceLanguage: video.Language,
+		SourceHash:     hash,
+		RequestedBy:    userID,
+		StartedAt:      time.Now(),
+	}
+	if err := s.translationRepo.Start(ctx, translation, time.Now().Add(-translationJobTimeout)); err != nil {
+		if errors.Is(err, models.ErrTranslationInProgress) {
+			current, err := s.translationRepo.Get(ctx, videoID, language)
+			if err != nil {
+				return nil, false, err
+			}
+			return current, true, nil
+		}
+		return nil, false, err
+	}
+
+	go s.run(userID, translation, video)
+
+	return translation, true, nil
+}
+
+func (s *translationService) run(userID primitive.ObjectID, translation *models.Translation, video *models.Video) {
+	ctx, cancel := context.WithTimeout(context.Background(), translationJobTimeout)
+	defer cancel()
+	ctx = WithAIWorkspace(WithAIUsageScope(prompts.WithLocale(ctx, video.Language), userID, nil), video.WorkspaceID)
+
+	summary, transcript, segments, err := s.translate(ctx, video, languageName(translation.Language))
+	if err != nil {
+		fmt.Printf("Translation to %s for video %s failed: %v\n", translation.Language, video.ID.Hex(), err)
+		if err := s.translationRepo.Fail(ctx, translation.ID, err.Error()); err != nil {
+			fmt.Printf("Failed to update translation for video %s: %v\n", video.ID.Hex(), err)
+		}
+		return
+	}
+
+	if err := s.translationRepo.Complete(ctx, translation.ID, summary, transcript, segments); err != nil {
+		fmt.Printf("Failed to update translation for video %s: %v\n", video.ID.Hex(), err)
+	}
+}
+
+// translate переводит саммари и транскрипт. Транскрипт с сегментами переводится
+// по сегментам, чтобы сохранить таймкоды, и собирается из них заново.
+func (s *translationService) translate(ctx context.Context, video *models.Video, language string) (string, string, []models.TranscriptSegment, error) {
+	var summary, transcript string
+	var segments []models.TranscriptSegment
+	var err error
+
+	if strings.TrimSpace(video.Summary) != "" {
+		if summary, err = s.aiService.TranslateText(ctx, video.Summary, language); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	switch {
+	case len(video.Segments) > 0:
+		if segments, err = s.aiService.TranslateSegments(ctx, video.Segments, language); err != nil {
+			return "", "", nil, err
+		}
+		texts := make([]string, len(segments))
+		for i, seg := range segments {
+			texts[i] = seg.Text
+		}
+		transcript = strings.Join(texts, " ")
+	case strings.TrimSpace(video.Transcript) != "":
+		if transcript, err = s.aiService.TranslateText(ctx, video.Transcript, language); err != nil {
+			return "", "", nil, err
+		}
+	}
+
+	return summary, transcript, segments, nil
+}
+
+// sourceHash — отпечаток саммари и сегментов, по которому видно, что перевод устарел
+func sourceHash(video *models.Video) string {
+	h := sha256.New()
+	h.Write([]byte(video.Summary))
+	h.Write([]byte{0})
+	if len(video.Segments) == 0 {
+		h.Write([]byte(video.Transcript))
+	}
+	for _, seg := range video.Segments {
+		h.Write([]byte(strconv.Itoa(seg.ID)))
+		h.Write([]byte{0})
+		h.Write([]byte(seg.Text))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *translationService) List(ctx context.Context, userID, videoID primitive.ObjectID) ([]*models.Translation, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	translations, err := s.translationRepo.ListByVideo(ctx, videoID)
+	if err != nil {
+		return nil, err
+	}
+	hash := sourceHash(video)
+	for _, translation := range translations {
+		translation.Outdated = translation.SourceHash != hash
+	}
+	return translations, nil
+}
+
+func (s *translationService) Get(ctx context.Context, userID, videoID primitive.ObjectID, language string) (*models.Translation, error) {
+	language, err := NormalizeLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+	translation, err := s.translationRepo.Get(ctx, videoID, language)
+	if err != nil {
+		return nil, err
+	}
+	translation.Outdated = translation.SourceHash != sourceHash(video)
+	return translation, nil
+}
+
+func (s *translationService) Subtitles(ctx context.Context, userID, videoID primitive.ObjectID, language, format string) ([]byte, string, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, "", err
+	}
+
+	segments := video.Segments
+	if language != "" {
+		if language, err = NormalizeLanguage(language); err != nil {
+			return nil, "", err
+		}
+		if !strings.EqualFold(language, video.Language) {
+			translation, err := s.translationRepo.Get(ctx, videoID, language)
+			if err != nil {
+				return nil, "", err
+			}
+			if translation.Status != models.TranslationCompleted {
+				return nil, "", models.ErrTranslationNotReady
+			}
+			segments = translation.Segments
+		}
+	}
+	if len(segments) == 0 {
+		return nil, "", models.ErrNoTranscriptSegments
+	}
+
+	return RenderSubtitles(segments, format)
+}
+
+func (s *translationService) Completed(ctx context.Context, videoID primitive.ObjectID, language string) (*models.Translation, error) {
+	translation, err := s.translationRepo.Get(ctx, videoID, language)
+	if err != nil {
+		if errors.Is(err, models.ErrTranslationNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if translation.Status != models.TranslationCompleted {
+		return nil, nil
+	}
+	return translation, nil
+}
+
+func (s *translationService) DeleteTranslations(ctx context.Context, videoID primitive.ObjectID) error {
+	return s.translationRepo.DeleteByVideo(ctx, videoID)
+}
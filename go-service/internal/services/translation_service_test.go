@@ -0,0 +1,65 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+)
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+		wantErr  error
+	}{
+		{language: "en", want: "en"},
+		{language: " RU ", want: "ru"},
+		{language: "pt_BR", want: "pt-br"},
+		{language: "zh-Hans", want: "zh-hans"},
+		{language: "fil", want: "fil"},
+		{language: "", wantErr: models.ErrInvalidTranslationLanguage},
+		{language: "english", wantErr: models.ErrInvalidTranslationLanguage},
+		{language: "en-", wantErr: models.ErrInvalidTranslationLanguage},
+		{language: "../etc", wantErr: models.ErrInvalidTranslationLanguage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			got, err := NormalizeLanguage(tt.language)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("NormalizeLanguage(%q) = (%q, %v), want (%q, %v)", tt.language, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSourceHash(t *testing.T) {
+	base := &models.Video{
+		Summary:    "Итоги",
+		Transcript: "текст",
+		Segments:   []models.TranscriptSegment{{ID: 1, Start: 0, End: 2, Text: "текст"}},
+	}
+	hash := sourceHash(base)
+
+	tests := []struct {
+		name     string
+		video    *models.Video
+		wantSame bool
+	}{
+		{name: "same content", video: &models.Video{Summary: "Итоги", Transcript: "текст", Segments: []models.TranscriptSegment{{ID: 1, Start: 0, End: 2, Text: "текст"}}}, wantSame: true},
+		{name: "timing ignored", video: &models.Video{Summary: "Итоги", Transcript: "текст", Segments: []models.TranscriptSegment{{ID: 1, Start: 5, End: 9, Text: "текст"}}}, wantSame: true},
+		{name: "summary edited", video: &models.Video{Summary: "Итоги!", Transcript: "текст", Segments: base.Segments}},
+		{name: "segment edited", video: &models.Video{Summary: "Итоги", Transcript: "текст", Segments: []models.TranscriptSegment{{ID: 1, Text: "другой"}}}},
+		{name: "segment renumbered", video: &models.Video{Summary: "Итоги", Transcript: "текст", Segments: []models.TranscriptSegment{{ID: 2, Text: "текст"}}}},
+		{name: "summary moved into transcript", video: &models.Video{Summary: "", Transcript: "Итоги" + "текст"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if same := sourceHash(tt.video) == hash; same != tt.wantSame {
+				t.Errorf("hash unchanged = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
@@ -24,15 +24,19 @@ type UserService interface {
 	ChangeSubscription(ctx context.Context, userID primitive.ObjectID, subscription string) error
 	GetSubscriptionLimits(subscription string) models.SubscriptionConfig
 	IncrementAnalysesCount(ctx context.Context, userID primitive.ObjectID) error
+	CheckAIQuota(ctx context.Context, userID primitive.ObjectID) error
+	RecordAIUsage(ctx context.Context, record *models.AIUsageRecord) error
 }
 
 type userService struct {
-	userRepo repository.UserRepository
+	userRepo  repository.UserRepository
+	usageRepo repository.AIUsageRepository
 }
 
-func NewUserService(userRepo repository.UserRepository) UserService {
+func NewUserService(userRepo repository.UserRepository, usageRepo repository.AIUsageRepository) UserService {
 	return &userService{
-		userRepo: userRepo,
+		userRepo:  userRepo,
+		usageRepo: usageRepo,
 	}
 }
 
@@ -147,6 +151,15 @@ func (s *userService) checkAndResetMonthlyLimits(user *models.User) bool {
 	return false
 }
 
+// currentMonthUsage — анализы за текущий месяц; счётчик прошлого месяца не считается
+func currentMonthUsage(used, month, year int) int {
+	now := time.Now()
+	if month != int(now.Month()) || year != now.Year() {
+		return 0
+	}
+	return used
+}
+
 func (s *userService) CanPerformAnalysis(ctx context.Context, userID primitive.ObjectID) error {
 	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
@@ -167,16 +180,14 @@ func (s *userService) CanPerformAnalysis(ctx context.Context, userID primitive.O
 	return nil
 }
 
+// RecordAnalysis списывает анализ с месячной квоты; при гонке лимит не превышается
 func (s *userService) RecordAnalysis(ctx context.Context, userID primitive.ObjectID) error {
 	user, err := s.userRepo.GetUserByID(ctx, userID)
 	if err != nil {
 		return err
 	}
-	user.MonthlyAnalysesUsed++
-	user.AnalysesCount++
-	user.LastAnalysisDate = time.Now()
 
-	return s.userRepo.UpdateUser(ctx, user)
+	return s.userRepo.RecordAnalysis(ctx, userID, subscriptionLimits(user.Subscription).MonthlyAnalyses)
 }
 
 func (s *userService) GetAnalyticsInfo(ctx context.Context, userID primitive.ObjectID) (*models.AnalyticsInfo, error) {
@@ -197,6 +208,11 @@ func (s *userService) GetAnalyticsInfo(ctx context.Context, userID primitive.Obj
 		limits = models.SubscriptionLimits["free"]
 	}
 
+	aiUsage, err := s.monthlyAIUsage(ctx, userID, limits)
+	if err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	nextReset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
 	usagePercentage := float64(user.MonthlyAnalysesUsed) / float64(limits.MonthlyAnalyses) * 100
@@ -211,6 +227,7 @@ func (s *userService) GetAnalyticsInfo(ctx context.Context, userID primitive.Obj
 		NextReset:          nextReset,
 		UsagePercentage:    usagePercentage,
 		CurrentMonth:       now.Format("January 2006"),
+		AIUsage:            aiUsage,
 	}, nil
 }
 
@@ -229,6 +246,10 @@ func (s *userService) ChangeSubscription(ctx context.Context, userID primitive.O
 }
 
 func (s *userService) GetSubscriptionLimits(subscription string) models.SubscriptionConfig {
+	return subscriptionLimits(subscription)
+}
+
+func subscriptionLimits(subscription string) models.SubscriptionConfig {
 	limits, exists := models.SubscriptionLimits[subscription]
 	if !exists {
 		return models.SubscriptionLimits["free"]
@@ -245,3 +266,48 @@ func (s *userService) IncrementAnalysesCount(ctx context.Context, userID primiti
 	user.AnalysesCount++
 	return s.userRepo.UpdateUser(ctx, user)
 }
+
+func (s *userService) CheckAIQuota(ctx context.Context, userID primitive.ObjectID) error {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.monthlyAIUsage(ctx, userID, s.GetSubscriptionLimits(user.Subscription))
+	if err != nil {
+		return err
+	}
+
+	if usage.MonthlyTokensUsed >= usage.MonthlyTokenLimit {
+		return models.ErrMonthlyAITokensExceeded
+	}
+	return nil
+}
+
+func (s *userService) RecordAIUsage(ctx context.Context, record *models.AIUsageRecord) error {
+	return s.usageRepo.Create(ctx, record)
+}
+
+// monthlyAIUsage собирает расход токенов LLM с начала текущего месяца
+func (s *userService) monthlyAIUsage(ctx context.Context, userID primitive.ObjectID, limits models.SubscriptionConfig) (*models.AIUsageInfo, error) {
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+
+	byOperation, err := s.usageRepo.TotalsByOperation(ctx, userID, monthStart)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.AIUsageInfo{
+		MonthlyTokenLimit: limits.MonthlyAITokens,
+		ByOperation:       byOperation,
+	}
+	for _, totals := range byOperation {
+		usage.MonthlyTokensUsed += totals.TotalTokens
+		usage.MonthlyCost += totals.Cost
+		usage.Requests += totals.Requests
+	}
+	usage.MonthlyTokensRemaining = max(0, usage.MonthlyTokenLimit-usage.MonthlyTokensUsed)
+
+	return usage, nil
+}
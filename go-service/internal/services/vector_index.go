@@ -0,0 +1,113 @@
+// services/vector_index.go
+package services
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// VectorIndex — in-memory индекс эмбеддингов, разбитый по библиотекам
+// (личным и воркспейсов, см. models.LibraryID).
+// Векторы нормализованы, поэтому косинусная близость считается скалярным произведением.
+type VectorIndex struct {
+	mu        sync.RWMutex
+	libraries map[primitive.ObjectID][]*models.ContentChunk
+	loaded    map[primitive.ObjectID]bool
+	// Счётчик изменений по библиотеке: защищает от загрузки устаревшего
+	// снимка, если видео переиндексировали во время чтения из хранилища
+	versions map[primitive.ObjectID]uint64
+}
+
+type scoredChunk struct {
+	chunk *models.ContentChunk
+	score float64
+}
+
+func NewVectorIndex() *VectorIndex {
+	return &VectorIndex{
+		libraries: make(map[primitive.ObjectID][]*models.ContentChunk),
+		loaded:    make(map[primitive.ObjectID]bool),
+		versions:  make(map[primitive.ObjectID]uint64),
+	}
+}
+
+// State сообщает, загружен ли индекс библиотеки, и текущую версию её данных
+func (idx *VectorIndex) State(libraryID primitive.ObjectID) (loaded bool, version uint64) {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+	return idx.loaded[libraryID], idx.versions[libraryID]
+}
+
+// Load заменяет чанки библиотеки загруженными из хранилища.
+// Возвращает false, если с момента получения version данные менялись.
+func (idx *VectorIndex) Load(libraryID primitive.ObjectID, chunks []*models.ContentChunk, version uint64) bool {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	if idx.versions[libraryID] != version {
+		return false
+	}
+	idx.libraries[libraryID] = chunks
+	idx.loaded[libraryID] = true
+	return true
+}
+
+// ReplaceVideo обновляет чанки видео, если индекс библиотеки уже загружен.
+// Незагруженный индекс подтянет актуальные данные из хранилища при первом поиске.
+func (idx *VectorIndex) ReplaceVideo(libraryID, videoID primitive.ObjectID, chunks []*models.ContentChunk) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	idx.versions[libraryID]++
+	if !idx.loaded[libraryID] {
+		return
+	}
+
+	kept := make([]*models.ContentChunk, 0, len(idx.libraries[libraryID])+len(chunks))
+	for _, c := range idx.libraries[libraryID] {
+		if c.VideoID != videoID {
+			kept = append(kept, c)
+		}
+	}
+	idx.libraries[libraryID] = append(kept, chunks...)
+}
+
+func (idx *VectorIndex) RemoveVideo(libraryID, videoID primitive.ObjectID) {
+	idx.ReplaceVideo(libraryID, videoID, nil)
+}
+
+// Search возвращает k ближайших чанков библиотеки по косинусной близости.
+// filter позволяет ограничить выдачу (например, набором видео); nil — без ограничений.
+func (idx *VectorIndex) Search(libraryID primitive.ObjectID, query []float32, k int, filter func(*models.ContentChunk) bool) []scoredChunk {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var results []scoredChunk
+	for _, c := range idx.libraries[libraryID] {
+		if len(c.Vector) != len(query) {
+			continue
+		}
+		if filter != nil && !filter(c) {
+			continue
+		}
+		results = append(results, scoredChunk{chunk: c, score: dot(c.Vector, query)})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].score > results[j].score
+	})
+
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
+
+func dot(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		sum += float64(a[i]) * float64(b[i])
+	}
+	return sum
+}
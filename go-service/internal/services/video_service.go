@@ -4,6 +4,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"time"
 
 	pb "github.com/code-zt/vidnotes/api/proto"
@@ -14,34 +15,63 @@ import (
 )
 
 type VideoService interface {
-	UploadVideo(ctx context.Context, userID primitive.ObjectID, file []byte, filename string) (*models.Video, error)
-	GetVideoStatus(ctx context.Context, videoID primitive.ObjectID) (*models.Video, error)
-	GetUserVideos(ctx context.Context, userID primitive.ObjectID) ([]*models.Video, error)
-	GetVideoResult(ctx context.Context, videoID primitive.ObjectID) (string, error)
-	DeleteVideo(ctx context.Context, videoID primitive.ObjectID) error
+	UploadVideo(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, file []byte, filename string) (*models.Video, error)
+	GetVideoStatus(ctx context.Context, userID, videoID primitive.ObjectID) (*models.Video, error)
+	GetUserVideos(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter, page models.PageOptions) (*models.VideoPage, error)
+	GetVideoResult(ctx context.Context, userID, videoID primitive.ObjectID) (string, error)
+	// DeleteVideo удаляет видео автора; видео воркспейса — также редактор или владелец
+	DeleteVideo(ctx context.Context, userID, videoID primitive.ObjectID) error
 }
 
 type videoService struct {
-	videoRepo   repository.VideoRepository
-	userService UserService
-	grpcClient  pb.VideoProcessorClient
+	videoRepo          repository.VideoRepository
+	userService        UserService
+	embeddingService   EmbeddingService
+	libraryService     LibraryService
+	workspaceService   WorkspaceService
+	summaryService     SummaryService
+	quizService        QuizService
+	insightsService    InsightsService
+	translationService TranslationService
+	shareService       ShareService
+	grpcClient         pb.VideoProcessorClient
 }
 
 func NewVideoService(
 	videoRepo repository.VideoRepository,
 	userService UserService,
+	embeddingService EmbeddingService,
+	libraryService LibraryService,
+	workspaceService WorkspaceService,
+	summaryService SummaryService,
+	quizService QuizService,
+	insightsService InsightsService,
+	translationService TranslationService,
+	shareService ShareService,
 	grpcConn *grpc.ClientConn,
 ) VideoService {
 	return &videoService{
-		videoRepo:   videoRepo,
-		userService: userService,
-		grpcClient:  pb.NewVideoProcessorClient(grpcConn),
+		videoRepo:          videoRepo,
+		userService:        userService,
+		embeddingService:   embeddingService,
+		libraryService:     libraryService,
+		workspaceService:   workspaceService,
+		summaryService:     summaryService,
+		quizService:        quizService,
+		insightsService:    insightsService,
+		translationService: translationService,
+		shareService:       shareService,
+		grpcClient:         pb.NewVideoProcessorClient(grpcConn),
 	}
 }
 
-func (s *videoService) UploadVideo(ctx context.Context, userID primitive.ObjectID, file []byte, filename string) (*models.Video, error) {
-	// Проверяем лимиты пользователя
-	if err := s.userService.CanPerformAnalysis(ctx, userID); err != nil {
+func (s *videoService) UploadVideo(ctx context.Context, userID primitive.ObjectID, workspaceID *primitive.ObjectID, file []byte, filename string) (*models.Video, error) {
+	// Проверяем лимиты: видео воркспейса расходуют общую квоту команды
+	if workspaceID != nil {
+		if err := s.workspaceService.CanPerformAnalysis(ctx, *workspaceID); err != nil {
+			return nil, err
+		}
+	} else if err := s.userService.CanPerformAnalysis(ctx, userID); err != nil {
 		return nil, err
 	}
 
@@ -52,12 +82,13 @@ func (s *videoService) UploadVideo(ctx context.Context, userID primitive.ObjectI
 
 	// Создаем запись видео в базе
 	video := &models.Video{
-		UserID:    userID,
-		Title:     filename,
-		URL:       fmt.Sprintf("/videos/%s", primitive.NewObjectID().Hex()),
-		Status:    "uploaded",
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		UserID:      userID,
+		WorkspaceID: workspaceID,
+		Title:       filename,
+		URL:         fmt.Sprintf("/videos/%s", primitive.NewObjectID().Hex()),
+		Status:      "uploaded",
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
 	}
 
 	videoID, err := s.videoRepo.Create(ctx, video)
@@ -65,14 +96,24 @@ func (s *videoService) UploadVideo(ctx context.Context, userID primitive.ObjectI
 		return nil, err
 	}
 
+	// Анализ списывается только после создания записи, чтобы сбой вставки его не расходовал.
+	// Проверка выше не защищает от параллельных загрузок: если квоту уже исчерпали,
+	// запись удаляется и обработка не запускается.
+	if workspaceID != nil {
+		err = s.workspaceService.RecordAnalysis(ctx, *workspaceID)
+	} else {
+		err = s.userService.RecordAnalysis(ctx, userID)
+	}
+	if err != nil {
+		if delErr := s.videoRepo.Delete(ctx, videoID); delErr != nil {
+			log.Printf("Failed to delete video %s after quota check: %v", videoID.Hex(), delErr)
+		}
+		return nil, err
+	}
+
 	// Запускаем обработку в фоне
 	go s.processVideo(context.Background(), videoID, file, filename)
 
-	// Увеличиваем счетчик анализов
-	if err := s.userService.RecordAnalysis(ctx, userID); err != nil {
-		fmt.Printf("Failed to record analysis: %v\n", err)
-	}
-
 	return video, nil
 }
 
@@ -105,7 +146,7 @@ func (s *videoService) processVideo(ctx context.Context, videoID primitive.Objec
 		return fmt.Errorf("failed to send metadata: %w", err)
 	}
 
-	fmt.Printf("Sending video data: %d bytes in chunks\n", len(file))
+	log.Printf("Sending video data: %d bytes in chunks", len(file))
 
 	// Отправляем данные файла чанками
 	chunkSize := 64 * 1024 // 64KB chunks для лучшей производительности
@@ -130,11 +171,11 @@ func (s *videoService) processVideo(ctx context.Context, videoID primitive.Objec
 
 		// Логируем прогресс каждые 1MB
 		if sentBytes%(1024*1024) == 0 {
-			fmt.Printf("Sent %d/%d bytes (%.1f%%)\n", sentBytes, len(file), float64(sentBytes)/float64(len(file))*100)
+			log.Printf("Sent %d/%d bytes (%.1f%%)", sentBytes, len(file), float64(sentBytes)/float64(len(file))*100)
 		}
 	}
 
-	fmt.Printf("All data sent: %d bytes\n", sentBytes)
+	log.Printf("All data sent: %d bytes", sentBytes)
 
 	// Закрываем поток и получаем ответ
 	resp, err := stream.CloseAndRecv()
@@ -149,50 +190,102 @@ func (s *videoService) processVideo(ctx context.Context, videoID primitive.Objec
 		return fmt.Errorf("processing failed: %s", resp.Error)
 	}
 
-	// Сохраняем summary в видео
-	if err := s.videoRepo.UpdateSummary(ctx, videoID, resp.Summary); err != nil {
+	// Сохраняем summary в видео первой версией
+	if _, err := s.summaryService.RecordVersion(ctx, videoID, resp.Summary, models.SummarySourceProcessing, nil); err != nil {
 		s.videoRepo.UpdateStatus(ctx, videoID, "failed")
 		return fmt.Errorf("failed to save video summary: %w", err)
 	}
 
+	// Сохраняем транскрипт с таймкодами для поиска
+	segments := make([]models.TranscriptSegment, 0, len(resp.Segments))
+	for _, seg := range resp.Segments {
+		segments = append(segments, models.TranscriptSegment{
+			ID:    int(seg.Id),
+			Start: seg.Start,
+			End:   seg.End,
+			Text:  seg.Text,
+		})
+	}
+	if err := s.videoRepo.UpdateTranscript(ctx, videoID, resp.Transcript, segments, resp.Language); err != nil {
+		log.Printf("Failed to save transcript for video %s: %v", videoID.Hex(), err)
+	}
+
 	// Обновляем статус видео на "completed"
 	if err := s.videoRepo.UpdateStatus(ctx, videoID, "completed"); err != nil {
 		return fmt.Errorf("failed to update video status: %w", err)
 	}
 
-	fmt.Printf("Video %s processed successfully. Summary length: %d\n", videoID.Hex(), len(resp.Summary))
+	log.Printf("Video %s processed successfully. Summary length: %d", videoID.Hex(), len(resp.Summary))
+
+	// Индексируем для семантического поиска; ошибка не влияет на статус видео
+	if video, err := s.videoRepo.GetByID(ctx, videoID); err == nil {
+		if err := s.embeddingService.IndexVideo(ctx, video); err != nil {
+			log.Printf("Failed to index video %s for semantic search: %v", videoID.Hex(), err)
+		}
+
+		// Предлагаем теги по саммари; пользователь сам решает, какие применить
+		if _, err := s.libraryService.SuggestTags(ctx, video.UserID, videoID); err != nil {
+			log.Printf("Failed to suggest tags for video %s: %v", videoID.Hex(), err)
+		}
+
+		// Тезисы, задачи и решения — для записей встреч, где прозы саммари мало
+		if _, err := s.insightsService.Extract(ctx, video); err != nil {
+			log.Printf("Failed to extract insights for video %s: %v", videoID.Hex(), err)
+		}
+	}
+
 	return nil
 }
 
-func (s *videoService) GetVideoStatus(ctx context.Context, videoID primitive.ObjectID) (*models.Video, error) {
-	video, err := s.videoRepo.GetByID(ctx, videoID)
-	if err != nil {
-		return nil, err
-	}
-	return video, nil
+func (s *videoService) GetVideoStatus(ctx context.Context, userID, videoID primitive.ObjectID) (*models.Video, error) {
+	return s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
 }
 
-func (s *videoService) GetUserVideos(ctx context.Context, userID primitive.ObjectID) ([]*models.Video, error) {
-	videos, err := s.videoRepo.GetByUser(ctx, userID)
+func (s *videoService) GetUserVideos(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter, page models.PageOptions) (*models.VideoPage, error) {
+	videos, err := s.videoRepo.List(ctx, userID, filter, page)
 	if err != nil {
 		return nil, err
 	}
 	return videos, nil
 }
 
-func (s *videoService) GetVideoResult(ctx context.Context, videoID primitive.ObjectID) (string, error) {
-	video, err := s.videoRepo.GetByID(ctx, videoID)
+func (s *videoService) GetVideoResult(ctx context.Context, userID, videoID primitive.ObjectID) (string, error) {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleViewer)
 	if err != nil {
 		return "", err
 	}
 
 	if video.Status != "completed" {
-		return "", fmt.Errorf("video processing not completed")
+		return "", fmt.Errorf("%w: video processing not completed", models.ErrVideoResultNotFound)
 	}
 
 	return video.Summary, nil
 }
 
-func (s *videoService) DeleteVideo(ctx context.Context, videoID primitive.ObjectID) error {
-	return s.videoRepo.Delete(ctx, videoID)
+func (s *videoService) DeleteVideo(ctx context.Context, userID, videoID primitive.ObjectID) error {
+	video, err := s.summaryService.AuthorizeVideo(ctx, userID, videoID, models.WorkspaceRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	if err := s.videoRepo.Delete(ctx, videoID); err != nil {
+		return err
+	}
+
+	if err := s.embeddingService.RemoveVideo(ctx, models.LibraryID(video.UserID, video.WorkspaceID), videoID); err != nil {
+		log.Printf("Failed to remove embeddings for video %s: %v", videoID.Hex(), err)
+	}
+	if err := s.summaryService.DeleteVersions(ctx, videoID); err != nil {
+		log.Printf("Failed to remove summary versions for video %s: %v", videoID.Hex(), err)
+	}
+	if err := s.quizService.DeleteQuizzes(ctx, videoID); err != nil {
+		log.Printf("Failed to remove quizzes for video %s: %v", videoID.Hex(), err)
+	}
+	if err := s.translationService.DeleteTranslations(ctx, videoID); err != nil {
+		log.Printf("Failed to remove translations for video %s: %v", videoID.Hex(), err)
+	}
+	if err := s.shareService.DeleteShares(ctx, videoID); err != nil {
+		log.Printf("Failed to remove share links for video %s: %v", videoID.Hex(), err)
+	}
+	return nil
 }
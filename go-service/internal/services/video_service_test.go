@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeQuotaUsers struct {
+	UserService
+	recordErr error
+	recorded  int
+}
+
+func (f *fakeQuotaUsers) CanPerformAnalysis(ctx context.Context, userID primitive.ObjectID) error {
+	return nil
+}
+
+func (f *fakeQuotaUsers) RecordAnalysis(ctx context.Context, userID primitive.ObjectID) error {
+	if f.recordErr != nil {
+		return f.recordErr
+	}
+	f.recorded++
+	return nil
+}
+
+type fakeUploadRepo struct {
+	repository.VideoRepository
+	createErr error
+	videos    map[primitive.ObjectID]*models.Video
+}
+
+func (f *fakeUploadRepo) Create(ctx context.Context, video *models.Video) (primitive.ObjectID, error) {
+	if f.createErr != nil {
+		return primitive.NilObjectID, f.createErr
+	}
+	video.ID = primitive.NewObjectID()
+	f.videos[video.ID] = video
+	return video.ID, nil
+}
+
+func (f *fakeUploadRepo) Delete(ctx context.Context, videoID primitive.ObjectID) error {
+	delete(f.videos, videoID)
+	return nil
+}
+
+// Успешная загрузка запускает обработку через gRPC, поэтому здесь только пути с ошибкой
+func TestVideoServiceUploadQuota(t *testing.T) {
+	tests := []struct {
+		name         string
+		createErr    error
+		recordErr    error
+		wantErr      error
+		wantRecorded int
+	}{
+		{name: "insert failed", createErr: models.ErrVideoCreateFailed, wantErr: models.ErrVideoCreateFailed},
+		{name: "quota taken by a parallel upload", recordErr: models.ErrMonthlyAnalysesLimitExceeded, wantErr: models.ErrMonthlyAnalysesLimitExceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeQuotaUsers{recordErr: tt.recordErr}
+			repo := &fakeUploadRepo{createErr: tt.createErr, videos: map[primitive.ObjectID]*models.Video{}}
+			s := &videoService{videoRepo: repo, userService: users}
+
+			_, err := s.UploadVideo(context.Background(), primitive.NewObjectID(), nil, []byte("video"), "meeting.mp4")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UploadVideo() error = %v, want %v", err, tt.wantErr)
+			}
+			if users.recorded != tt.wantRecorded {
+				t.Errorf("analyses recorded = %d, want %d", users.recorded, tt.wantRecorded)
+			}
+			if len(repo.videos) != 0 {
+				t.Errorf("%d videos left after a failed upload", len(repo.videos))
+			}
+		})
+	}
+}
@@ -0,0 +1,486 @@
+// services/workspace_service.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"github.com/code-zt/vidnotes/pkg/password"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var workspaceRoleRank = map[string]int{
+	models.WorkspaceRoleViewer: 1,
+	models.WorkspaceRoleEditor: 2,
+	models.WorkspaceRoleOwner:  3,
+}
+
+type WorkspaceService interface {
+	CreateWorkspace(ctx context.Context, userID primitive.ObjectID, req models.CreateWorkspaceRequest) (*models.WorkspaceSummary, error)
+	ListWorkspaces(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceSummary, error)
+	GetWorkspace(ctx context.Context, userID, workspaceID primitive.ObjectID) (*models.WorkspaceSummary, error)
+	UpdateWorkspace(ctx context.Context, userID, workspaceID primitive.ObjectID, req models.UpdateWorkspaceRequest) (*models.WorkspaceSummary, error)
+	DeleteWorkspace(ctx context.Context, userID, workspaceID primitive.ObjectID) error
+
+	ListMembers(ctx context.Context, userID, workspaceID primitive.ObjectID) ([]*models.WorkspaceMemberInfo, error)
+	UpdateMemberRole(ctx context.Context, userID, workspaceID, memberID primitive.ObjectID, role string) error
+	RemoveMember(ctx context.Context, userID, workspaceID, memberID primitive.ObjectID) error
+
+	InviteMember(ctx context.Context, userID, workspaceID primitive.ObjectID, req models.InviteMemberRequest) (*models.WorkspaceInvite, error)
+	ListInvites(ctx context.Context, userID, workspaceID primitive.ObjectID) ([]*models.WorkspaceInvite, error)
+	RevokeInvite(ctx context.Context, userID, workspaceID, inviteID primitive.ObjectID) error
+	ListMyInvites(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceInvite, error)
+	AcceptInvite(ctx context.Context, userID primitive.ObjectID, token string) (*models.WorkspaceSummary, error)
+
+	// Authorize проверяет, что пользователь состоит в воркспейсе с ролью не ниже minRole
+	Authorize(ctx context.Context, workspaceID, userID primitive.ObjectID, minRole string) (*models.WorkspaceMember, error)
+
+	CanPerformAnalysis(ctx context.Context, workspaceID primitive.ObjectID) error
+	RecordAnalysis(ctx context.Context, workspaceID primitive.ObjectID) error
+	GetAnalyticsInfo(ctx context.Context, userID, workspaceID primitive.ObjectID) (*models.AnalyticsInfo, error)
+	ChangeSubscription(ctx context.Context, workspaceID primitive.ObjectID, subscription string) error
+}
+
+const inviteTokenBytes = 32
+
+type workspaceService struct {
+	workspaceRepo repository.WorkspaceRepository
+	userRepo      repository.UserRepository
+	videoRepo     repository.VideoRepository
+	sessionRepo   repository.AISessionRepository
+	emailService  EmailService
+	// Кэширует настройку RedactPII; сбрасывается при её изменении
+	redactor *PIIRedactor
+}
+
+func NewWorkspaceService(workspaceRepo repository.WorkspaceRepository, userRepo repository.UserRepository, videoRepo repository.VideoRepository, sessionRepo repository.AISessionRepository, emailService EmailService, redactor *PIIRedactor) WorkspaceService {
+	return &workspaceService{
+		workspaceRepo: workspaceRepo,
+		userRepo:      userRepo,
+		videoRepo:     videoRepo,
+		sessionRepo:   sessionRepo,
+		emailService:  emailService,
+		redactor:      redactor,
+	}
+}
+
+func (s *workspaceService) CreateWorkspace(ctx context.Context, userID primitive.ObjectID, req models.CreateWorkspaceRequest) (*models.WorkspaceSummary, error) {
+	name, err := normalizeWorkspaceName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace := &models.Workspace{
+		Name:         name,
+		OwnerID:      userID,
+		Subscription: "free",
+	}
+
+	if _, err := s.workspaceRepo.Create(ctx, workspace); err != nil {
+		return nil, err
+	}
+
+	member := &models.WorkspaceMember{
+		WorkspaceID: workspace.ID,
+		UserID:      userID,
+		Role:        models.WorkspaceRoleOwner,
+	}
+	if err := s.workspaceRepo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceSummary{Workspace: workspace, Role: member.Role}, nil
+}
+
+func (s *workspaceService) ListWorkspaces(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceSummary, error) {
+	memberships, err := s.workspaceRepo.ListMemberships(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles := make(map[primitive.ObjectID]string, len(memberships))
+	ids := make([]primitive.ObjectID, 0, len(memberships))
+	for _, m := range memberships {
+		roles[m.WorkspaceID] = m.Role
+		ids = append(ids, m.WorkspaceID)
+	}
+
+	workspaces, err := s.workspaceRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	summaries := make([]*models.WorkspaceSummary, 0, len(workspaces))
+	for _, w := range workspaces {
+		summaries = append(summaries, &models.WorkspaceSummary{Workspace: w, Role: roles[w.ID]})
+	}
+	return summaries, nil
+}
+
+func (s *workspaceService) GetWorkspace(ctx context.Context, userID, workspaceID primitive.ObjectID) (*models.WorkspaceSummary, error) {
+	member, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceSummary{Workspace: workspace, Role: member.Role}, nil
+}
+
+func (s *workspaceService) UpdateWorkspace(ctx context.Context, userID, workspaceID primitive.ObjectID, req models.UpdateWorkspaceRequest) (*models.WorkspaceSummary, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != "" {
+		if workspace.Name, err = normalizeWorkspaceName(req.Name); err != nil {
+			return nil, err
+		}
+	}
+	if req.RedactPII != nil {
+		workspace.RedactPII = req.RedactPII
+	}
+
+	if err := s.workspaceRepo.Update(ctx, workspace); err != nil {
+		return nil, err
+	}
+	if req.RedactPII != nil {
+		s.redactor.ForgetWorkspace(workspaceID)
+	}
+
+	return &models.WorkspaceSummary{Workspace: workspace, Role: models.WorkspaceRoleOwner}, nil
+}
+
+// DeleteWorkspace удаляет только пустой воркспейс, чтобы не потерять видео команды.
+// AI-сессии воркспейса удаляются вместе с ним (их видео уже удалены), участники и
+// приглашения — в репозитории. Коллекции личные и воркспейсу не принадлежат.
+func (s *workspaceService) DeleteWorkspace(ctx context.Context, userID, workspaceID primitive.ObjectID) error {
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	videoIDs, err := s.videoRepo.ListIDs(ctx, userID, models.VideoFilter{WorkspaceID: &workspaceID})
+	if err != nil {
+		return err
+	}
+	if len(videoIDs) > 0 {
+		return models.ErrWorkspaceNotEmpty
+	}
+
+	if err := s.sessionRepo.DeleteByWorkspace(ctx, workspaceID); err != nil {
+		return err
+	}
+	return s.workspaceRepo.Delete(ctx, workspaceID)
+}
+
+func (s *workspaceService) ListMembers(ctx context.Context, userID, workspaceID primitive.ObjectID) ([]*models.WorkspaceMemberInfo, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+
+	members, err := s.workspaceRepo.ListMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]*models.WorkspaceMemberInfo, 0, len(members))
+	for _, m := range members {
+		info := &models.WorkspaceMemberInfo{
+			UserID:   m.UserID,
+			Role:     m.Role,
+			JoinedAt: m.JoinedAt,
+		}
+		if user, err := s.userRepo.GetUserByID(ctx, m.UserID); err == nil {
+			info.Name = user.Name
+			info.Email = user.Email
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (s *workspaceService) UpdateMemberRole(ctx context.Context, userID, workspaceID, memberID primitive.ObjectID, role string) error {
+	if role != models.WorkspaceRoleEditor && role != models.WorkspaceRoleViewer {
+		return models.ErrInvalidWorkspaceRole
+	}
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+	if memberID == userID {
+		return models.ErrOwnerCannotLeave
+	}
+
+	return s.workspaceRepo.UpdateMemberRole(ctx, workspaceID, memberID, role)
+}
+
+// RemoveMember: владелец удаляет любого участника, остальные могут только выйти сами
+func (s *workspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID primitive.ObjectID) error {
+	member, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	if memberID == userID {
+		if member.Role == models.WorkspaceRoleOwner {
+			return models.ErrOwnerCannotLeave
+		}
+	} else if member.Role != models.WorkspaceRoleOwner {
+		return models.ErrWorkspaceAccessDenied
+	}
+
+	return s.workspaceRepo.RemoveMember(ctx, workspaceID, memberID)
+}
+
+func (s *workspaceService) InviteMember(ctx context.Context, userID, workspaceID primitive.ObjectID, req models.InviteMemberRequest) (*models.WorkspaceInvite, error) {
+	if req.Role != models.WorkspaceRoleEditor && req.Role != models.WorkspaceRoleViewer {
+		return nil, models.ErrInvalidWorkspaceRole
+	}
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	email := normalizeEmail(req.Email)
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, models.ErrInvalidInviteEmail
+	}
+
+	// Уже состоящего в воркспейсе пользователя приглашать не нужно
+	if user, err := s.userRepo.GetUserByEmail(ctx, email); err == nil && user != nil {
+		if _, err := s.workspaceRepo.GetMember(ctx, workspaceID, user.ID); err == nil {
+			return nil, models.ErrAlreadyMember
+		}
+	}
+
+	token, err := password.GenerateToken(inviteTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	invite := &models.WorkspaceInvite{
+		WorkspaceID: workspaceID,
+		Email:       email,
+		TokenHash:   password.HashToken(token),
+		Role:        req.Role,
+		InvitedBy:   userID,
+		ExpiresAt:   time.Now().Add(models.WorkspaceInviteTTL),
+	}
+	if _, err := s.workspaceRepo.CreateInvite(ctx, invite); err != nil {
+		return nil, err
+	}
+
+	// Приглашение без письма принять нельзя, поэтому не оставляем его
+	if err := s.emailService.SendWorkspaceInviteEmail(ctx, email, workspace.Name, token); err != nil {
+		_ = s.workspaceRepo.DeleteInvite(ctx, invite.ID)
+		return nil, fmt.Errorf("%w: %v", models.ErrInviteSendFailed, err)
+	}
+
+	return invite, nil
+}
+
+func (s *workspaceService) ListInvites(ctx context.Context, userID, workspaceID primitive.ObjectID) ([]*models.WorkspaceInvite, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return nil, err
+	}
+	return s.workspaceRepo.ListInvites(ctx, workspaceID)
+}
+
+func (s *workspaceService) RevokeInvite(ctx context.Context, userID, workspaceID, inviteID primitive.ObjectID) error {
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleOwner); err != nil {
+		return err
+	}
+
+	invite, err := s.workspaceRepo.GetInvite(ctx, inviteID)
+	if err != nil {
+		return err
+	}
+	if invite.WorkspaceID != workspaceID {
+		return models.ErrInviteNotFound
+	}
+
+	return s.workspaceRepo.DeleteInvite(ctx, inviteID)
+}
+
+func (s *workspaceService) ListMyInvites(ctx context.Context, userID primitive.ObjectID) ([]*models.WorkspaceInvite, error) {
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invites, err := s.workspaceRepo.ListInvitesByEmail(ctx, normalizeEmail(user.Email))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, invite := range invites {
+		if workspace, err := s.workspaceRepo.GetByID(ctx, invite.WorkspaceID); err == nil {
+			invite.WorkspaceName = workspace.Name
+		}
+	}
+	return invites, nil
+}
+
+// AcceptInvite принимает приглашение по токену из письма: токен подтверждает
+// владение адресом, поэтому одного совпадения email аккаунта недостаточно
+func (s *workspaceService) AcceptInvite(ctx context.Context, userID primitive.ObjectID, token string) (*models.WorkspaceSummary, error) {
+	if token == "" {
+		return nil, models.ErrInviteNotFound
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invite, err := s.workspaceRepo.GetInviteByTokenHash(ctx, password.HashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	// Чужое приглашение не раскрываем
+	if invite.Email != normalizeEmail(user.Email) || invite.AcceptedAt != nil {
+		return nil, models.ErrInviteNotFound
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return nil, models.ErrInviteExpired
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, invite.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Сначала гасим токен: при параллельных запросах приглашение сработает один раз
+	if err := s.workspaceRepo.MarkInviteAccepted(ctx, invite.ID); err != nil {
+		return nil, err
+	}
+
+	member := &models.WorkspaceMember{
+		WorkspaceID: invite.WorkspaceID,
+		UserID:      userID,
+		Role:        invite.Role,
+	}
+	if err := s.workspaceRepo.AddMember(ctx, member); err != nil {
+		return nil, err
+	}
+
+	return &models.WorkspaceSummary{Workspace: workspace, Role: member.Role}, nil
+}
+
+func (s *workspaceService) Authorize(ctx context.Context, workspaceID, userID primitive.ObjectID, minRole string) (*models.WorkspaceMember, error) {
+	member, err := s.workspaceRepo.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		// Не участникам не сообщаем, существует ли воркспейс
+		if errors.Is(err, models.ErrMemberNotFound) {
+			return nil, models.ErrWorkspaceNotFound
+		}
+		return nil, err
+	}
+
+	if workspaceRoleRank[member.Role] < workspaceRoleRank[minRole] {
+		return nil, models.ErrWorkspaceAccessDenied
+	}
+	return member, nil
+}
+
+// CanPerformAnalysis проверяет общую квоту воркспейса
+func (s *workspaceService) CanPerformAnalysis(ctx context.Context, workspaceID primitive.ObjectID) error {
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	if workspaceAnalysesUsed(workspace) >= subscriptionLimits(workspace.Subscription).MonthlyAnalyses {
+		return models.ErrMonthlyAnalysesLimitExceeded
+	}
+	return nil
+}
+
+// RecordAnalysis списывает анализ с общей квоты; при гонке лимит не превышается
+func (s *workspaceService) RecordAnalysis(ctx context.Context, workspaceID primitive.ObjectID) error {
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+
+	return s.workspaceRepo.RecordAnalysis(ctx, workspaceID, subscriptionLimits(workspace.Subscription).MonthlyAnalyses)
+}
+
+func (s *workspaceService) GetAnalyticsInfo(ctx context.Context, userID, workspaceID primitive.ObjectID) (*models.AnalyticsInfo, error) {
+	if _, err := s.Authorize(ctx, workspaceID, userID, models.WorkspaceRoleViewer); err != nil {
+		return nil, err
+	}
+
+	workspace, err := s.workspaceRepo.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := subscriptionLimits(workspace.Subscription)
+	used := workspaceAnalysesUsed(workspace)
+	now := time.Now()
+	nextReset := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, now.Location())
+
+	return &models.AnalyticsInfo{
+		Subscription:       workspacePlan(workspace),
+		MonthlyLimit:       limits.MonthlyAnalyses,
+		MonthlyUsed:        used,
+		MonthlyRemaining:   limits.MonthlyAnalyses - used,
+		TotalAnalyses:      workspace.AnalysesCount,
+		CanPerformAnalysis: used < limits.MonthlyAnalyses,
+		NextReset:          nextReset,
+		UsagePercentage:    float64(used) / float64(limits.MonthlyAnalyses) * 100,
+		CurrentMonth:       now.Format("January 2006"),
+	}, nil
+}
+
+// ChangeSubscription меняет тариф воркспейса; вызывается биллингом, не участниками
+func (s *workspaceService) ChangeSubscription(ctx context.Context, workspaceID primitive.ObjectID, subscription string) error {
+	if _, exists := models.SubscriptionLimits[subscription]; !exists {
+		return models.ErrInvalidSubscription
+	}
+	return s.workspaceRepo.UpdateSubscription(ctx, workspaceID, subscription)
+}
+
+// workspaceAnalysesUsed — анализы воркспейса за текущий месяц
+func workspaceAnalysesUsed(workspace *models.Workspace) int {
+	return currentMonthUsage(workspace.MonthlyAnalysesUsed, workspace.LastResetMonth, workspace.LastResetYear)
+}
+
+// workspacePlan — тариф воркспейса; у созданных до общих квот тариф не записан
+func workspacePlan(workspace *models.Workspace) string {
+	if _, ok := models.SubscriptionLimits[workspace.Subscription]; !ok {
+		return "free"
+	}
+	return workspace.Subscription
+}
+
+func normalizeWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > 100 {
+		return "", models.ErrInvalidWorkspaceName
+	}
+	return name, nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/code-zt/vidnotes/config"
+	"github.com/code-zt/vidnotes/internal/models"
+	"github.com/code-zt/vidnotes/internal/repository"
+	"github.com/code-zt/vidnotes/pkg/password"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type fakeWorkspaceRepo struct {
+	repository.WorkspaceRepository
+	workspaces map[primitive.ObjectID]*models.Workspace
+	members    map[primitive.ObjectID]string
+	invites    map[primitive.ObjectID]*models.WorkspaceInvite
+	// Лимит, переданный в последний RecordAnalysis
+	recordedLimit int
+}
+
+func (f *fakeWorkspaceRepo) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Workspace, error) {
+	workspace, ok := f.workspaces[id]
+	if !ok {
+		return nil, models.ErrWorkspaceNotFound
+	}
+	return workspace, nil
+}
+
+func (f *fakeWorkspaceRepo) Update(ctx context.Context, workspace *models.Workspace) error {
+	f.workspaces[workspace.ID] = workspace
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) GetMember(ctx context.Context, workspaceID, userID primitive.ObjectID) (*models.WorkspaceMember, error) {
+	role, ok := f.members[userID]
+	if !ok || f.workspaces[workspaceID] == nil {
+		return nil, models.ErrMemberNotFound
+	}
+	return &models.WorkspaceMember{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
+
+func (f *fakeWorkspaceRepo) UpdateSubscription(ctx context.Context, id primitive.ObjectID, subscription string) error {
+	f.workspaces[id].Subscription = subscription
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) RecordAnalysis(ctx context.Context, id primitive.ObjectID, monthlyLimit int) error {
+	f.recordedLimit = monthlyLimit
+	workspace := f.workspaces[id]
+	if workspaceAnalysesUsed(workspace) >= monthlyLimit {
+		return models.ErrMonthlyAnalysesLimitExceeded
+	}
+	workspace.MonthlyAnalysesUsed++
+	workspace.AnalysesCount++
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) AddMember(ctx context.Context, member *models.WorkspaceMember) error {
+	if _, ok := f.members[member.UserID]; ok {
+		return models.ErrAlreadyMember
+	}
+	f.members[member.UserID] = member.Role
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) CreateInvite(ctx context.Context, invite *models.WorkspaceInvite) (primitive.ObjectID, error) {
+	invite.ID = primitive.NewObjectID()
+	f.invites[invite.ID] = invite
+	return invite.ID, nil
+}
+
+func (f *fakeWorkspaceRepo) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*models.WorkspaceInvite, error) {
+	for _, invite := range f.invites {
+		if invite.TokenHash == tokenHash {
+			return invite, nil
+		}
+	}
+	return nil, models.ErrInviteNotFound
+}
+
+func (f *fakeWorkspaceRepo) MarkInviteAccepted(ctx context.Context, id primitive.ObjectID) error {
+	invite := f.invites[id]
+	if invite.AcceptedAt != nil {
+		return models.ErrInviteNotFound
+	}
+	now := time.Now()
+	invite.AcceptedAt = &now
+	return nil
+}
+
+func (f *fakeWorkspaceRepo) DeleteInvite(ctx context.Context, id primitive.ObjectID) error {
+	delete(f.invites, id)
+	return nil
+}
+
+type fakeUserRepo struct {
+	repository.UserRepository
+	users map[primitive.ObjectID]*models.User
+}
+
+func (f *fakeUserRepo) GetUserByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	user, ok := f.users[id]
+	if !ok {
+		return nil, models.ErrUserNotFound
+	}
+	return user, nil
+}
+
+func (f *fakeUserRepo) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	for _, user := range f.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, models.ErrUserNotFound
+}
+
+type fakeEmailService struct {
+	EmailService
+	err   error
+	to    string
+	token string
+}
+
+func (f *fakeEmailService) SendWorkspaceInviteEmail(ctx context.Context, email, workspaceName, token string) error {
+	f.to, f.token = email, token
+	return f.err
+}
+
+// newTestWorkspace — воркспейс с владельцем, редактором и зрителем
+func newTestWorkspace(subscription string, used int) (*fakeWorkspaceRepo, *models.Workspace, map[string]primitive.ObjectID) {
+	now := time.Now()
+	workspace := &models.Workspace{
+		ID:                  primitive.NewObjectID(),
+		OwnerID:             primitive.NewObjectID(),
+		Subscription:        subscription,
+		MonthlyAnalysesUsed: used,
+		LastResetMonth:      int(now.Month()),
+		LastResetYear:       now.Year(),
+	}
+	users := map[string]primitive.ObjectID{
+		models.WorkspaceRoleOwner:  workspace.OwnerID,
+		models.WorkspaceRoleEditor: primitive.NewObjectID(),
+		models.WorkspaceRoleViewer: primitive.NewObjectID(),
+	}
+	repo := &fakeWorkspaceRepo{
+		workspaces: map[primitive.ObjectID]*models.Workspace{workspace.ID: workspace},
+		members:    map[primitive.ObjectID]string{},
+		invites:    map[primitive.ObjectID]*models.WorkspaceInvite{},
+	}
+	for role, id := range users {
+		repo.members[id] = role
+	}
+	return repo, workspace, users
+}
+
+func TestWorkspaceServiceAuthorize(t *testing.T) {
+	repo, workspace, users := newTestWorkspace("free", 0)
+	s := &workspaceService{workspaceRepo: repo}
+
+	tests := []struct {
+		name    string
+		userID  primitive.ObjectID
+		minRole string
+		wantErr error
+	}{
+		{name: "owner as owner", userID: users[models.WorkspaceRoleOwner], minRole: models.WorkspaceRoleOwner},
+		{name: "editor as viewer", userID: users[models.WorkspaceRoleEditor], minRole: models.WorkspaceRoleViewer},
+		{name: "viewer as editor", userID: users[models.WorkspaceRoleViewer], minRole: models.WorkspaceRoleEditor, wantErr: models.ErrWorkspaceAccessDenied},
+		{name: "editor as owner", userID: users[models.WorkspaceRoleEditor], minRole: models.WorkspaceRoleOwner, wantErr: models.ErrWorkspaceAccessDenied},
+		{name: "stranger", userID: primitive.NewObjectID(), minRole: models.WorkspaceRoleViewer, wantErr: models.ErrWorkspaceNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Authorize(context.Background(), workspace.ID, tt.userID, tt.minRole)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Authorize() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWorkspaceServiceQuota(t *testing.T) {
+	free := models.SubscriptionLimits["free"].MonthlyAnalyses
+	premium := models.SubscriptionLimits["premium"].MonthlyAnalyses
+
+	tests := []struct {
+		name         string
+		subscription string
+		used         int
+		lastMonth    bool
+		wantErr      error
+		wantLimit    int
+	}{
+		{name: "free with room", subscription: "free", used: free - 1, wantLimit: free},
+		{name: "free exhausted", subscription: "free", used: free, wantErr: models.ErrMonthlyAnalysesLimitExceeded},
+		{name: "premium beyond free limit", subscription: "premium", used: free, wantLimit: premium},
+		{name: "last month counter ignored", subscription: "free", used: free, lastMonth: true, wantLimit: free},
+		{name: "plan not recorded", subscription: "", used: free - 1, wantLimit: free},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, workspace, _ := newTestWorkspace(tt.subscription, tt.used)
+			if tt.lastMonth {
+				workspace.LastResetYear--
+			}
+			s := &workspaceService{workspaceRepo: repo}
+			ctx := context.Background()
+
+			if err := s.CanPerformAnalysis(ctx, workspace.ID); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CanPerformAnalysis() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if err := s.RecordAnalysis(ctx, workspace.ID); err != nil {
+				t.Fatalf("RecordAnalysis() error = %v", err)
+			}
+			if repo.recordedLimit != tt.wantLimit {
+				t.Errorf("RecordAnalysis() limit = %d, want %d", repo.recordedLimit, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestWorkspaceServiceAnalyticsInfo(t *testing.T) {
+	repo, workspace, users := newTestWorkspace("premium", 10)
+	s := &workspaceService{workspaceRepo: repo}
+	ctx := context.Background()
+
+	if _, err := s.GetAnalyticsInfo(ctx, primitive.NewObjectID(), workspace.ID); !errors.Is(err, models.ErrWorkspaceNotFound) {
+		t.Errorf("GetAnalyticsInfo() for stranger error = %v, want %v", err, models.ErrWorkspaceNotFound)
+	}
+
+	info, err := s.GetAnalyticsInfo(ctx, users[models.WorkspaceRoleViewer], workspace.ID)
+	if err != nil {
+		t.Fatalf("GetAnalyticsInfo() error = %v", err)
+	}
+	if info.Subscription != "premium" || info.MonthlyUsed != 10 || info.MonthlyLimit != models.SubscriptionLimits["premium"].MonthlyAnalyses {
+		t.Errorf("GetAnalyticsInfo() = %s %d/%d, want premium 10/%d",
+			info.Subscription, info.MonthlyUsed, info.MonthlyLimit, models.SubscriptionLimits["premium"].MonthlyAnalyses)
+	}
+}
+
+func TestWorkspaceServiceChangeSubscription(t *testing.T) {
+	repo, workspace, _ := newTestWorkspace("free", 0)
+	s := &workspaceService{workspaceRepo: repo}
+	ctx := context.Background()
+
+	if err := s.ChangeSubscription(ctx, workspace.ID, "enterprise"); !errors.Is(err, models.ErrInvalidSubscription) {
+		t.Errorf("ChangeSubscription(enterprise) error = %v, want %v", err, models.ErrInvalidSubscription)
+	}
+	if err := s.ChangeSubscription(ctx, workspace.ID, "premium"); err != nil || workspace.Subscription != "premium" {
+		t.Errorf("ChangeSubscription(premium) = %v, subscription %q", err, workspace.Subscription)
+	}
+}
+
+func TestWorkspaceServiceUpdateRedactPII(t *testing.T) {
+	repo, workspace, users := newTestWorkspace("free", 0)
+	redactor, err := NewPIIRedactor(&config.RedactionConfig{Enabled: true}, repo)
+	if err != nil {
+		t.Fatalf("NewPIIRedactor: %v", err)
+	}
+	s := &workspaceService{workspaceRepo: repo, redactor: redactor}
+	ctx := WithAIWorkspace(context.Background(), &workspace.ID)
+
+	if !redactor.enabledFor(ctx) {
+		t.Fatal("enabledFor() = false before update, want config default true")
+	}
+
+	off := false
+	if _, err := s.UpdateWorkspace(ctx, users[models.WorkspaceRoleOwner], workspace.ID, models.UpdateWorkspaceRequest{RedactPII: &off}); err != nil {
+		t.Fatalf("UpdateWorkspace() error = %v", err)
+	}
+	if redactor.enabledFor(ctx) {
+		t.Error("enabledFor() = true after RedactPII=false, want cached policy dropped")
+	}
+}
+
+func TestWorkspaceServiceInviteMember(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string
+		sendErr    error
+		wantErr    error
+		wantInvite bool
+	}{
+		{name: "owner", role: models.WorkspaceRoleOwner, wantInvite: true},
+		{name: "editor", role: models.WorkspaceRoleEditor, wantErr: models.ErrWorkspaceAccessDenied},
+		{name: "email not sent", role: models.WorkspaceRoleOwner, sendErr: errors.New("smtp down"), wantErr: models.ErrInviteSendFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, workspace, users := newTestWorkspace("free", 0)
+			email := &fakeEmailService{err: tt.sendErr}
+			s := &workspaceService{workspaceRepo: repo, userRepo: &fakeUserRepo{}, emailService: email}
+
+			req := models.InviteMemberRequest{Email: " New@Example.com ", Role: models.WorkspaceRoleEditor}
+			invite, err := s.InviteMember(context.Background(), users[tt.role], workspace.ID, req)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("InviteMember() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := len(repo.invites) > 0; got != tt.wantInvite {
+				t.Fatalf("invite stored = %v, want %v", got, tt.wantInvite)
+			}
+			if !tt.wantInvite {
+				return
+			}
+			if email.to != "new@example.com" || email.token == "" {
+				t.Errorf("email sent to %q with token %q", email.to, email.token)
+			}
+			if invite.TokenHash != password.HashToken(email.token) || invite.TokenHash == email.token {
+				t.Error("invite must store the token hash, not the token")
+			}
+		})
+	}
+}
+
+func TestWorkspaceServiceAcceptInvite(t *testing.T) {
+	invitee := &models.User{ID: primitive.NewObjectID(), Email: "new@example.com"}
+	other := &models.User{ID: primitive.NewObjectID(), Email: "other@example.com"}
+
+	tests := []struct {
+		name    string
+		user    *models.User
+		token   func(sent string) string
+		expired bool
+		wantErr error
+	}{
+		{name: "valid token", user: invitee, token: func(sent string) string { return sent }},
+		{name: "no token", user: invitee, token: func(string) string { return "" }, wantErr: models.ErrInviteNotFound},
+		{name: "wrong token", user: invitee, token: func(sent string) string { return sent + "x" }, wantErr: models.ErrInviteNotFound},
+		{name: "other account", user: other, token: func(sent string) string { return sent }, wantErr: models.ErrInviteNotFound},
+		{name: "expired", user: invitee, token: func(sent string) string { return sent }, expired: true, wantErr: models.ErrInviteExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, workspace, users := newTestWorkspace("free", 0)
+			email := &fakeEmailService{}
+			userRepo := &fakeUserRepo{users: map[primitive.ObjectID]*models.User{invitee.ID: invitee, other.ID: other}}
+			s := &workspaceService{workspaceRepo: repo, userRepo: userRepo, emailService: email}
+			ctx := context.Background()
+
+			req := models.InviteMemberRequest{Email: invitee.Email, Role: models.WorkspaceRoleViewer}
+			invite, err := s.InviteMember(ctx, users[models.WorkspaceRoleOwner], workspace.ID, req)
+			if err != nil {
+				t.Fatalf("InviteMember() error = %v", err)
+			}
+			if tt.expired {
+				invite.ExpiresAt = time.Now().Add(-time.Minute)
+			}
+
+			_, err = s.AcceptInvite(ctx, tt.user.ID, tt.token(email.token))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AcceptInvite() error = %v, want %v", err, tt.wantErr)
+			}
+			if joined := repo.members[tt.user.ID] == models.WorkspaceRoleViewer; joined != (tt.wantErr == nil) {
+				t.Errorf("member added = %v, want %v", joined, tt.wantErr == nil)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			// Токен одноразовый
+			if _, err := s.AcceptInvite(ctx, tt.user.ID, email.token); !errors.Is(err, models.ErrInviteNotFound) {
+				t.Errorf("second AcceptInvite() error = %v, want %v", err, models.ErrInviteNotFound)
+			}
+		})
+	}
+}
+
+func (f *fakeWorkspaceRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	delete(f.workspaces, id)
+	for inviteID, invite := range f.invites {
+		if invite.WorkspaceID == id {
+			delete(f.invites, inviteID)
+		}
+	}
+	return nil
+}
+
+type fakeWorkspaceVideos struct {
+	repository.VideoRepository
+	ids []primitive.ObjectID
+}
+
+func (f *fakeWorkspaceVideos) ListIDs(ctx context.Context, userID primitive.ObjectID, filter models.VideoFilter) ([]primitive.ObjectID, error) {
+	return f.ids, nil
+}
+
+type fakeWorkspaceSessions struct {
+	repository.AISessionRepository
+	deleted []primitive.ObjectID
+}
+
+func (f *fakeWorkspaceSessions) DeleteByWorkspace(ctx context.Context, workspaceID primitive.ObjectID) error {
+	f.deleted = append(f.deleted, workspaceID)
+	return nil
+}
+
+func TestWorkspaceServiceDeleteWorkspace(t *testing.T) {
+	tests := []struct {
+		name    string
+		role    string
+		videos  []primitive.ObjectID
+		wantErr error
+	}{
+		{name: "empty", role: models.WorkspaceRoleOwner},
+		{name: "has videos", role: models.WorkspaceRoleOwner, videos: []primitive.ObjectID{primitive.NewObjectID()}, wantErr: models.ErrWorkspaceNotEmpty},
+		{name: "editor", role: models.WorkspaceRoleEditor, wantErr: models.ErrWorkspaceAccessDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, workspace, users := newTestWorkspace("free", 0)
+			repo.invites[primitive.NewObjectID()] = &models.WorkspaceInvite{WorkspaceID: workspace.ID}
+			sessions := &fakeWorkspaceSessions{}
+			s := &workspaceService{workspaceRepo: repo, videoRepo: &fakeWorkspaceVideos{ids: tt.videos}, sessionRepo: sessions}
+
+			err := s.DeleteWorkspace(context.Background(), users[tt.role], workspace.ID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DeleteWorkspace() error = %v, want %v", err, tt.wantErr)
+			}
+
+			deleted := tt.wantErr == nil
+			if got := repo.workspaces[workspace.ID] == nil; got != deleted {
+				t.Errorf("workspace deleted = %v, want %v", got, deleted)
+			}
+			if got := len(sessions.deleted) == 1 && sessions.deleted[0] == workspace.ID; got != deleted {
+				t.Errorf("sessions deleted = %v, want %v", got, deleted)
+			}
+			if got := len(repo.invites) == 0; got != deleted {
+				t.Errorf("invites deleted = %v, want %v", got, deleted)
+			}
+		})
+	}
+}
@@ -11,7 +11,7 @@ import re
 import torch
 from concurrent import futures
 import traceback
-from typing import List, Dict, Any
+from typing import List, Dict, Any, Tuple
 import logging
 
 # Настраиваем логирование
@@ -128,7 +128,7 @@ class VideoProcessor(videoproc_pb2_grpc.VideoProcessorServicer):
 
             logger.info("Starting audio and video processing...")
             
-            audio_text = self._process_audio(tmp_video_path)
+            audio_text, segments, language = self._process_audio(tmp_video_path)
             frames_text = self._process_video_frames(tmp_video_path)
             
             summary = self._summarize_content(audio_text, frames_text, filename)
@@ -142,7 +142,10 @@ class VideoProcessor(videoproc_pb2_grpc.VideoProcessorServicer):
                 video_id=video_id or "",
                 summary=summary,
                 error="",
-                status="completed"
+                status="completed",
+                transcript=audio_text,
+                segments=[videoproc_pb2.TranscriptSegment(**seg) for seg in segments],
+                language=language
             )
             
         except Exception as e:
@@ -241,7 +244,8 @@ class VideoProcessor(videoproc_pb2_grpc.VideoProcessorServicer):
             logger.exception("Error getting video duration")
             return 0
 
-    def _process_audio(self, video_path: str) -> str:
+    def _process_audio(self, video_path: str) -> Tuple[str, List[Dict[str, Any]], str]:
+        """Возвращает текст транскрипции, сегменты с таймкодами и язык"""
         logger.info("Checking for audio stream...")
         
         try:
@@ -262,7 +266,7 @@ class VideoProcessor(videoproc_pb2_grpc.VideoProcessorServicer):
             
             if not has_audio:
                 logger.info("No audio stream found, skipping audio processing")
-                return ""
+                return "", [], ""
             
             logger.info("Audio stream found, extracting...")
             tmp_audio = tempfile.NamedTemporaryFile(
@@ -291,7 +295,7 @@ class VideoProcessor(videoproc_pb2_grpc.VideoProcessorServicer):
             
             if not os.path.exists(tmp_audio_path) or os.path.getsize(tmp_audio_path) == 0:
                 logger.error("Audio extraction failed: empty output file")
-                return ""
+                return "", [], ""
             
             logger.info("Transcribing audio with Whisper...")
             transcription = self.whisper_model.transcribe(
@@ -302,19 +306,30 @@ class VideoProcessor(videoproc_pb2_grpc.VideoProcessorServicer):
             )
             
             audio_text = transcription["text"].strip()
-            logger.info(f"Audio transcription completed: {len(audio_text)} characters")
+            segments = [
+                {
+                    "id": int(seg.get("id", i)),
+                    "start": float(seg.get("start", 0.0)),
+                    "end": float(seg.get("end", 0.0)),
+                    "text": seg.get("text", "").strip(),
+                }
+                for i, seg in enumerate(transcription.get("segments", []))
+                if seg.get("text", "").strip()
+            ]
+            language = transcription.get("language") or "ru"
+            logger.info(f"Audio transcription completed: {len(audio_text)} characters, {len(segments)} segments")
             
-            return audio_text
+            return audio_text, segments, language
             
         except subprocess.CalledProcessError as e:
             logger.error(f"FFmpeg audio extraction failed: {e.stderr}")
-            return ""
+            return "", [], ""
         except subprocess.TimeoutExpired:
             logger.error("Audio processing timeout")
-            return ""
+            return "", [], ""
         except Exception as e:
             logger.exception("Error during audio processing")
-            return ""
+            return "", [], ""
         finally:
             if 'tmp_audio_path' in locals() and os.path.exists(tmp_audio_path):
                 try:
@@ -24,7 +24,7 @@ _sym_db = _symbol_database.Default()
 
 
 
-DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0fvideoproc.proto\x12\tvideoproc\">\n\nVideoChunk\x12\x10\n\x08\x66ilename\x18\x01 \x01(\t\x12\x0c\n\x04\x64\x61ta\x18\x02 \x01(\x0c\x12\x10\n\x08video_id\x18\x03 \x01(\t\"S\n\x0fProcessResponse\x12\x10\n\x08video_id\x18\x01 \x01(\t\x12\x0f\n\x07summary\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12\x0e\n\x06status\x18\x04 \x01(\t2U\n\x0eVideoProcessor\x12\x43\n\x0cProcessVideo\x12\x15.videoproc.VideoChunk\x1a\x1a.videoproc.ProcessResponse(\x01\x42\x1bZ\x19proto/videoproc;videoprocb\x06proto3')
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x0fvideoproc.proto\x12\tvideoproc\">\n\nVideoChunk\x12\x10\n\x08\x66ilename\x18\x01 \x01(\t\x12\x0c\n\x04\x64\x61ta\x18\x02 \x01(\x0c\x12\x10\n\x08video_id\x18\x03 \x01(\t\"I\n\x11TranscriptSegment\x12\n\n\x02id\x18\x01 \x01(\x05\x12\r\n\x05start\x18\x02 \x01(\x01\x12\x0b\n\x03\x65nd\x18\x03 \x01(\x01\x12\x0c\n\x04text\x18\x04 \x01(\t\"\xa9\x01\n\x0fProcessResponse\x12\x10\n\x08video_id\x18\x01 \x01(\t\x12\x0f\n\x07summary\x18\x02 \x01(\t\x12\r\n\x05\x65rror\x18\x03 \x01(\t\x12\x0e\n\x06status\x18\x04 \x01(\t\x12\x12\n\ntranscript\x18\x05 \x01(\t\x12.\n\x08segments\x18\x06 \x03(\x0b\x32\x1c.videoproc.TranscriptSegment\x12\x10\n\x08language\x18\x07 \x01(\t2U\n\x0eVideoProcessor\x12\x43\n\x0cProcessVideo\x12\x15.videoproc.VideoChunk\x1a\x1a.videoproc.ProcessResponse(\x01\x42\x1bZ\x19proto/videoproc;videoprocb\x06proto3')
 
 _globals = globals()
 _builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
@@ -34,8 +34,10 @@ if not _descriptor._USE_C_DESCRIPTORS:
   _globals['DESCRIPTOR']._serialized_options = b'Z\031proto/videoproc;videoproc'
   _globals['_VIDEOCHUNK']._serialized_start=30
   _globals['_VIDEOCHUNK']._serialized_end=92
-  _globals['_PROCESSRESPONSE']._serialized_start=94
-  _globals['_PROCESSRESPONSE']._serialized_end=177
-  _globals['_VIDEOPROCESSOR']._serialized_start=179
-  _globals['_VIDEOPROCESSOR']._serialized_end=264
+  _globals['_TRANSCRIPTSEGMENT']._serialized_start=94
+  _globals['_TRANSCRIPTSEGMENT']._serialized_end=167
+  _globals['_PROCESSRESPONSE']._serialized_start=170
+  _globals['_PROCESSRESPONSE']._serialized_end=339
+  _globals['_VIDEOPROCESSOR']._serialized_start=341
+  _globals['_VIDEOPROCESSOR']._serialized_end=426
 # @@protoc_insertion_point(module_scope)
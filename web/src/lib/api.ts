@@ -39,6 +39,24 @@ async function fetchJson<T>(input: string, init: RequestInit = {}): Promise<T> {
 	return parseDataEnvelope<T>(res);
 }
 
+type Page<T> = { items: T[]; next_cursor?: string; total?: number };
+
+const PAGE_LIMIT = 100;
+
+// List endpoints are cursor-paginated; follow next_cursor until the last page
+async function fetchAllPages<T>(url: string): Promise<T[]> {
+	const items: T[] = [];
+	let cursor: string | undefined;
+	do {
+		const params = new URLSearchParams({ limit: String(PAGE_LIMIT) });
+		if (cursor) params.set('cursor', cursor);
+		const page = await fetchJson<Page<T>>(`${url}?${params}`);
+		items.push(...(page.items ?? []));
+		cursor = page.next_cursor;
+	} while (cursor);
+	return items;
+}
+
 async function parseDataEnvelope<T>(res: Response): Promise<T> {
 	const raw = await res.json();
 	return (raw && typeof raw === 'object' && 'data' in raw) ? (raw.data as T) : (raw as T);
@@ -147,7 +165,7 @@ export async function apiUploadVideo(file: File): Promise<any> {
 }
 
 export async function apiGetUserVideos(): Promise<any> {
-	return fetchJson<any>(`${API_URL}/videos/`);
+	return fetchAllPages<any>(`${API_URL}/videos/`);
 }
 
 export async function apiGetVideoStatus(id: string): Promise<any> {
@@ -168,7 +186,7 @@ export async function apiCreateSession(input: { title?: string; video_id: string
 }
 
 export async function apiGetSessions(): Promise<any[]> {
-	return fetchJson<any[]>(`${API_URL}/ai/sessions`);
+	return fetchAllPages<any>(`${API_URL}/ai/sessions`);
 }
 
 export async function apiGetSession(id: string): Promise<any> {